@@ -0,0 +1,222 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Agent reacts, on behalf of a node, to the migrations coordinated under a root prefix of a KeyLayout,
+// see Coordinator. The roles of the node are defined by the callbacks it is given:
+//
+//   - gateway nodes reload when a ReloadGatewayCommand includes their index, see WithAgentReloadGateway
+//   - source router nodes reload on every ReloadSrcRouterCommand, see WithAgentReloadSrcRouter
+//   - processor nodes acknowledge the migrations moving their partitions, move the partitions of the jobs
+//     they are the source of and mark them as moved, then take over the partitions of the moved jobs they are
+//     the target of and mark them as completed, see WithAgentPartitionsMovedOut and WithAgentPartitionsMovedIn
+//
+// Every ack is written exactly once under AckKey(nodeName), and every job status is changed exactly once,
+// even across restarts: callbacks are not invoked again once their ack is written. A callback may be invoked again
+// if the node stopped after the callback returned but before its ack was written, so callbacks must be idempotent.
+type Agent struct {
+	store     Store
+	root      string
+	nodeIndex int
+	nodeName  string
+
+	reloadGateway      func(ctx context.Context, cmd *ReloadGatewayCommand) error
+	reloadSrcRouter    func(ctx context.Context, cmd *ReloadSrcRouterCommand) error
+	partitionsMovedOut func(ctx context.Context, job *PartitionMigrationJob) error
+	partitionsMovedIn  func(ctx context.Context, job *PartitionMigrationJob) error
+	eventHandled       func(event WatchEvent)
+}
+
+// NewAgent returns an Agent for the node with the given index and name, following the migrations under root.
+func NewAgent(store Store, root string, nodeIndex int, nodeName string, opt ...func(a *Agent)) (*Agent, error) {
+	if err := validateKeyRoot(root); err != nil {
+		return nil, err
+	}
+	if err := validateKeySegment("node name", nodeName); err != nil {
+		return nil, err
+	}
+	a := &Agent{
+		store:     store,
+		root:      root,
+		nodeIndex: nodeIndex,
+		nodeName:  nodeName,
+	}
+	for _, o := range opt {
+		o(a)
+	}
+	return a, nil
+}
+
+// WithAgentReloadGateway makes the node a gateway node, reloading with the given callback.
+func WithAgentReloadGateway(reload func(ctx context.Context, cmd *ReloadGatewayCommand) error) func(a *Agent) {
+	return func(a *Agent) {
+		a.reloadGateway = reload
+	}
+}
+
+// WithAgentReloadSrcRouter makes the node a source router node, reloading with the given callback.
+func WithAgentReloadSrcRouter(reload func(ctx context.Context, cmd *ReloadSrcRouterCommand) error) func(a *Agent) {
+	return func(a *Agent) {
+		a.reloadSrcRouter = reload
+	}
+}
+
+// WithAgentPartitionsMovedOut makes the node a processor node, moving the partitions of the jobs
+// it is the source of with the given callback.
+func WithAgentPartitionsMovedOut(move func(ctx context.Context, job *PartitionMigrationJob) error) func(a *Agent) {
+	return func(a *Agent) {
+		a.partitionsMovedOut = move
+	}
+}
+
+// WithAgentPartitionsMovedIn makes the node a processor node, taking over the partitions of the jobs
+// it is the target of with the given callback.
+func WithAgentPartitionsMovedIn(move func(ctx context.Context, job *PartitionMigrationJob) error) func(a *Agent) {
+	return func(a *Agent) {
+		a.partitionsMovedIn = move
+	}
+}
+
+// WithAgentEventHandled calls handled every time the agent is done with an event of its watch, before it reads
+// the next one, e.g. to synchronise with the agent in simulations.
+func WithAgentEventHandled(handled func(event WatchEvent)) func(a *Agent) {
+	return func(a *Agent) {
+		a.eventHandled = handled
+	}
+}
+
+// Run handles the migration documents already in the store, then the ones modified later, until the context is done
+// or a callback fails. A watch closed by the store while the context is live is an error too. After a failure, the
+// agent can be run again.
+func (a *Agent) Run(ctx context.Context) error {
+	prefix := a.root + "/"
+	if a.root == "/" {
+		prefix = a.root
+	}
+	kvs, revision, err := a.store.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	for _, kv := range kvs {
+		if err := a.handle(ctx, kv); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := a.store.Watch(ctx, prefix, revision+1)
+	if err != nil {
+		return err
+	}
+	for event := range events {
+		if event.Type == WatchEventTypePut {
+			if err := a.handle(ctx, event.KeyValue); err != nil {
+				return err
+			}
+		}
+		if a.eventHandled != nil {
+			a.eventHandled(event)
+		}
+	}
+	if ctx.Err() == nil {
+		return fmt.Errorf("watching %s: watch closed", prefix)
+	}
+	return ctx.Err()
+}
+
+// handle handles a document, ignoring the ones which are not addressed to the roles of the node.
+func (a *Agent) handle(ctx context.Context, kv KeyValue) error {
+	key, err := ParseKey(a.root, kv.Key)
+	if err != nil {
+		return nil // not a document of the key layout
+	}
+	switch key.Kind {
+	case KeyKindMigration:
+		if a.partitionsMovedOut == nil && a.partitionsMovedIn == nil {
+			return nil
+		}
+		pm, err := unmarshalDocument[PartitionMigration](kv)
+		if err != nil || pm.Status != PartitionMigrationStatusNew {
+			return err
+		}
+		if !slices.Contains(pm.SourceNodes(), a.nodeIndex) && !slices.Contains(pm.TargetNodes(), a.nodeIndex) {
+			return nil
+		}
+		return a.ack(ctx, pm.AckKey(a.nodeName), pm.Ack(a.nodeIndex, a.nodeName), nil)
+	case KeyKindReloadGateway:
+		if a.reloadGateway == nil {
+			return nil
+		}
+		cmd, err := unmarshalDocument[ReloadGatewayCommand](kv)
+		if err != nil || !slices.Contains(cmd.Nodes, a.nodeIndex) {
+			return err
+		}
+		return a.ack(ctx, cmd.AckKey(a.nodeName), cmd.Ack(a.nodeIndex, a.nodeName), func() error {
+			return a.reloadGateway(ctx, cmd)
+		})
+	case KeyKindReloadSrcRouter:
+		if a.reloadSrcRouter == nil {
+			return nil
+		}
+		cmd, err := unmarshalDocument[ReloadSrcRouterCommand](kv)
+		if err != nil {
+			return err
+		}
+		return a.ack(ctx, cmd.AckKey(a.nodeName), cmd.Ack(a.nodeName), func() error {
+			return a.reloadSrcRouter(ctx, cmd)
+		})
+	case KeyKindJob:
+		job, err := unmarshalDocument[PartitionMigrationJob](kv)
+		if err != nil {
+			return err
+		}
+		switch {
+		case a.partitionsMovedOut != nil && job.Status == PartitionMigrationJobStatusNew && job.SourceNode == a.nodeIndex:
+			return a.advanceJob(ctx, kv, job, PartitionMigrationJobStatusMoved, a.partitionsMovedOut)
+		case a.partitionsMovedIn != nil && job.Status == PartitionMigrationJobStatusMoved && job.TargetNode == a.nodeIndex:
+			return a.advanceJob(ctx, kv, job, PartitionMigrationJobStatusCompleted, a.partitionsMovedIn)
+		}
+	}
+	return nil
+}
+
+// ack invokes the callback, if any, and writes the ack, unless it was already written.
+func (a *Agent) ack(ctx context.Context, key string, ack any, callback func() error) error {
+	if _, err := a.store.Get(ctx, key); err == nil || !errors.Is(err, ErrKeyNotFound) {
+		return err // already acknowledged
+	}
+	if callback != nil {
+		if err := callback(); err != nil {
+			return fmt.Errorf("node %q acknowledging %s: %w", a.nodeName, key, err)
+		}
+	}
+	if _, err := CompareAndSwapJSON(ctx, a.store, key, 0, ack); err != nil && !errors.Is(err, ErrRevisionConflict) {
+		return err
+	}
+	return nil
+}
+
+// advanceJob invokes the callback and changes the status of the job, unless it was changed in the meantime.
+func (a *Agent) advanceJob(ctx context.Context, kv KeyValue, job *PartitionMigrationJob, status PartitionMigrationJobStatus, callback func(ctx context.Context, job *PartitionMigrationJob) error) error {
+	current, err := a.store.Get(ctx, kv.Key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil
+	}
+	if err != nil || current.Revision != kv.Revision {
+		return err // already changed, the change will be handled next
+	}
+	if err := callback(ctx, job.Clone()); err != nil {
+		return fmt.Errorf("node %q moving job %q to %s: %w", a.nodeName, job.JobID, status, err)
+	}
+	job.Status = status
+	if _, err := CompareAndSwapJSON(ctx, a.store, kv.Key, kv.Revision, job); err != nil && !errors.Is(err, ErrRevisionConflict) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package cluster_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-schemas/go/cluster"
+	"github.com/rudderlabs/rudder-schemas/go/schemastest"
+)
+
+func TestAgent(t *testing.T) {
+	const root = "/migrations"
+	clock := func() time.Time { return schemastest.FixtureTime }
+	newCoordinator := func(store cluster.Store, layout *cluster.KeyLayout) *cluster.Coordinator {
+		return cluster.NewCoordinator(store, layout,
+			cluster.WithCoordinatorGatewayNodes(0, 1),
+			cluster.WithCoordinatorSrcRouterNodes("srcrouter-0"),
+			cluster.WithCoordinatorClock(clock),
+		)
+	}
+	newLayout := func(t *testing.T) (*cluster.PartitionMigration, *cluster.KeyLayout) {
+		pm := schemastest.NewPartitionMigration()
+		layout, err := cluster.NewKeyLayout(root, pm.ID)
+		require.NoError(t, err)
+		return pm, layout
+	}
+	// newAgents returns the agents of the nodes 0 to 2 of the fixture migration, all of them being gateway and
+	// processor nodes, and of a source router node, recording their callbacks.
+	newAgents := func(t *testing.T, store cluster.Store, calls *agentCalls) []*cluster.Agent {
+		var agents []*cluster.Agent
+		for node := range 3 {
+			name := fmt.Sprintf("node-%d", node)
+			agent, err := cluster.NewAgent(store, root, node, name,
+				cluster.WithAgentReloadGateway(func(ctx context.Context, cmd *cluster.ReloadGatewayCommand) error {
+					return calls.record(name, "reload-gw")
+				}),
+				cluster.WithAgentPartitionsMovedOut(func(ctx context.Context, job *cluster.PartitionMigrationJob) error {
+					return calls.record(name, "moved-out "+job.JobID)
+				}),
+				cluster.WithAgentPartitionsMovedIn(func(ctx context.Context, job *cluster.PartitionMigrationJob) error {
+					return calls.record(name, "moved-in "+job.JobID)
+				}),
+			)
+			require.NoError(t, err)
+			agents = append(agents, agent)
+		}
+		agent, err := cluster.NewAgent(store, root, 0, "srcrouter-0",
+			cluster.WithAgentReloadSrcRouter(func(ctx context.Context, cmd *cluster.ReloadSrcRouterCommand) error {
+				return calls.record("srcrouter-0", "reload-srcrouter")
+			}),
+		)
+		require.NoError(t, err)
+		return append(agents, agent)
+	}
+	expectedCalls := map[string]int{
+		"node-0 reload-gw":             1,
+		"node-1 reload-gw":             1,
+		"node-0 moved-out job-1":       1,
+		"node-0 moved-out job-2":       1,
+		"node-0 moved-out job-3":       1,
+		"node-1 moved-in job-1":        1,
+		"node-2 moved-in job-2":        1,
+		"node-2 moved-in job-3":        1,
+		"srcrouter-0 reload-srcrouter": 1,
+	}
+
+	t.Run("full lifecycle", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		pm, layout := newLayout(t)
+		store := cluster.NewMemoryStore()
+		calls := &agentCalls{}
+		agents := newAgents(t, store, calls)
+		done := runAgents(ctx, agents)
+
+		info, err := newCoordinator(store, layout).Run(ctx, pm)
+		require.NoError(t, err)
+		require.Equal(t, cluster.PartitionMigrationStatusCompleted, info.Status)
+		for _, job := range info.Jobs {
+			require.Equal(t, cluster.PartitionMigrationJobStatusCompleted, job.Status)
+		}
+		cancel()
+		for range agents {
+			require.ErrorIs(t, <-done, context.Canceled)
+		}
+		require.Equal(t, expectedCalls, calls.counts(), "node 2 is not asked to reload its gateway")
+
+		acks, _, err := store.List(context.Background(), layout.MigrationAckKeyPrefix()+"/")
+		require.NoError(t, err)
+		require.Len(t, acks, 3)
+		for i, kv := range acks {
+			ack, _, err := cluster.GetJSON[cluster.PartitionMigrationAck](context.Background(), store, kv.Key)
+			require.NoError(t, err)
+			require.Equal(t, pm.Ack(i, fmt.Sprintf("node-%d", i)), ack)
+		}
+		gw, _, err := cluster.GetJSON[cluster.ReloadGatewayAck](context.Background(), store, layout.ReloadGatewayCommand(nil).AckKey("node-1"))
+		require.NoError(t, err)
+		require.Equal(t, layout.ReloadGatewayCommand(nil).Ack(1, "node-1"), gw)
+		_, err = store.Get(context.Background(), layout.ReloadGatewayCommand(nil).AckKey("node-2"))
+		require.ErrorIs(t, err, cluster.ErrKeyNotFound)
+
+		t.Run("restarted agents don't act again", func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			revision := store.Revision()
+			calls := &agentCalls{}
+			agents := newAgents(t, store, calls)
+			done := runAgents(ctx, agents)
+			for range agents {
+				require.ErrorIs(t, <-done, context.DeadlineExceeded)
+			}
+			require.Empty(t, calls.counts())
+			require.Equal(t, revision, store.Revision(), "nothing is written")
+		})
+	})
+
+	t.Run("failing callbacks are retried by the next run", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		pm, layout := newLayout(t)
+		store := cluster.NewMemoryStore()
+		calls := &agentCalls{fail: map[string]error{"node-1 moved-in job-1": errors.New("disk full")}}
+		agents := newAgents(t, store, calls)
+
+		coordinator := make(chan error, 1)
+		go func() {
+			_, err := newCoordinator(store, layout).Run(ctx, pm)
+			coordinator <- err
+		}()
+		done := runAgents(ctx, agents[1:2])
+		others := runAgents(ctx, append([]*cluster.Agent{agents[0]}, agents[2:]...))
+		err := <-done
+		require.ErrorContains(t, err, `node "node-1" moving job "job-1" to completed: disk full`)
+		job, _, err := cluster.GetJSON[cluster.PartitionMigrationJob](ctx, store, layout.JobsPrefix()+"job-1")
+		require.NoError(t, err)
+		require.Equal(t, cluster.PartitionMigrationJobStatusMoved, job.Status, "the job is not completed")
+
+		calls.succeed()
+		done = runAgents(ctx, agents[1:2])
+		require.NoError(t, <-coordinator)
+		cancel()
+		require.ErrorIs(t, <-done, context.Canceled)
+		for range len(agents) - 1 {
+			require.ErrorIs(t, <-others, context.Canceled)
+		}
+		expected := map[string]int{"node-1 moved-in job-1": 2}
+		for call, n := range expectedCalls {
+			if _, ok := expected[call]; !ok {
+				expected[call] = n
+			}
+		}
+		require.Equal(t, expected, calls.counts())
+	})
+
+	t.Run("closed watch", func(t *testing.T) {
+		agent, err := cluster.NewAgent(closedWatchStore{cluster.NewMemoryStore()}, root, 0, "node-0")
+		require.NoError(t, err)
+		require.EqualError(t, agent.Run(context.Background()), "watching /migrations/: watch closed")
+	})
+
+	t.Run("handled events", func(t *testing.T) {
+		events := make(chan cluster.WatchEvent)
+		handled := make(chan cluster.WatchEvent)
+		agent, err := cluster.NewAgent(watchStore{Store: cluster.NewMemoryStore(), events: events}, root, 0, "node-0",
+			cluster.WithAgentEventHandled(func(event cluster.WatchEvent) { handled <- event }),
+		)
+		require.NoError(t, err)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := runAgents(ctx, []*cluster.Agent{agent})
+
+		for _, event := range []cluster.WatchEvent{
+			{Type: cluster.WatchEventTypePut, KeyValue: cluster.KeyValue{Key: "/migrations/other", Value: []byte(`{}`), Revision: 1}},
+			{Type: cluster.WatchEventTypeDelete, KeyValue: cluster.KeyValue{Key: "/migrations/other", Revision: 2}},
+		} {
+			events <- event
+			require.Equal(t, event, <-handled, "every event is handled, even the ignored ones")
+		}
+		cancel()
+		close(events)
+		require.ErrorIs(t, <-done, context.Canceled)
+	})
+
+	t.Run("invalid agents", func(t *testing.T) {
+		for _, name := range []string{"", ".", "..", "node/1", "a..b"} {
+			_, err := cluster.NewAgent(cluster.NewMemoryStore(), root, 0, name)
+			require.ErrorIs(t, err, cluster.ErrInvalidKey, name)
+		}
+		_, err := cluster.NewAgent(cluster.NewMemoryStore(), "/migrations/", 0, "node-0")
+		require.ErrorIs(t, err, cluster.ErrInvalidKey)
+	})
+}
+
+// closedWatchStore closes its watches right away, like a store losing its connection.
+type closedWatchStore struct {
+	cluster.Store
+}
+
+func (closedWatchStore) Watch(context.Context, string, int64) (<-chan cluster.WatchEvent, error) {
+	events := make(chan cluster.WatchEvent)
+	close(events)
+	return events, nil
+}
+
+// watchStore returns the same events to every watch.
+type watchStore struct {
+	cluster.Store
+	events chan cluster.WatchEvent
+}
+
+func (s watchStore) Watch(context.Context, string, int64) (<-chan cluster.WatchEvent, error) {
+	return s.events, nil
+}
+
+// agentCalls records the callbacks invoked by agents, failing the ones configured to fail.
+type agentCalls struct {
+	mu    sync.Mutex
+	calls map[string]int
+	fail  map[string]error
+}
+
+func (c *agentCalls) record(node, call string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.calls == nil {
+		c.calls = make(map[string]int)
+	}
+	call = node + " " + call
+	c.calls[call]++
+	return c.fail[call]
+}
+
+func (c *agentCalls) succeed() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fail = nil
+}
+
+func (c *agentCalls) counts() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts := make(map[string]int, len(c.calls))
+	for call, n := range c.calls {
+		counts[call] = n
+	}
+	return counts
+}
+
+// runAgents runs the agents in the background, returning the results of their runs.
+func runAgents(ctx context.Context, agents []*cluster.Agent) <-chan error {
+	done := make(chan error, len(agents))
+	for _, agent := range agents {
+		go func() {
+			done <- agent.Run(ctx)
+		}()
+	}
+	return done
+}
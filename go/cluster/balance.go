@@ -0,0 +1,196 @@
+package cluster
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+)
+
+// PartitionLoad is the load of a partition, as measured by its nodes.
+type PartitionLoad struct {
+	EventsPerSecond float64 // rate of events received by the partition
+	BacklogBytes    int64   // size of the events of the partition not processed yet
+}
+
+// PartitionBalancer proposes assignments of partitions to nodes, balancing their load according to the capacities
+// of the nodes, while moving as few partitions as possible.
+type PartitionBalancer struct {
+	tolerance float64
+	weight    func(load PartitionLoad) float64
+}
+
+// NewPartitionBalancer returns a PartitionBalancer with a tolerance of 10%, weighting partitions by their events per second.
+func NewPartitionBalancer(opt ...func(b *PartitionBalancer)) *PartitionBalancer {
+	b := &PartitionBalancer{
+		tolerance: 0.1,
+		weight:    func(load PartitionLoad) float64 { return load.EventsPerSecond },
+	}
+	for _, o := range opt {
+		o(b)
+	}
+	return b
+}
+
+// WithBalancerTolerance sets the relative deviation of the load of a node from its fair share, proportional
+// to its capacity, within which it is balanced.
+func WithBalancerTolerance(tolerance float64) func(b *PartitionBalancer) {
+	return func(b *PartitionBalancer) {
+		b.tolerance = tolerance
+	}
+}
+
+// WithBalancerWeight sets the weight of a partition given its load, e.g. combining its events per second and backlog.
+func WithBalancerWeight(weight func(load PartitionLoad) float64) func(b *PartitionBalancer) {
+	return func(b *PartitionBalancer) {
+		b.weight = weight
+	}
+}
+
+// PartitionBalance is an assignment of partitions proposed by a PartitionBalancer.
+type PartitionBalance struct {
+	Assignment map[string]int  // node of every partition
+	NodeLoads  map[int]float64 // weight of the partitions of every node
+	Balanced   bool            // whether the load of every node is within the tolerance
+
+	current map[string]int
+	loads   map[string]PartitionLoad
+}
+
+// Balance proposes an assignment of the partitions of the current assignment to the nodes with a capacity.
+// Partitions of nodes without capacity are moved to the least loaded nodes first, then partitions are moved,
+// one at a time, from the most to the least loaded node relative to their capacities, until the load of every node
+// is within the tolerance, or no move can reduce the imbalance. Partitions without a load weigh nothing, loads of
+// partitions missing from the current assignment are ignored.
+func (b *PartitionBalancer) Balance(current map[string]int, loads map[string]PartitionLoad, capacities map[int]float64) (*PartitionBalance, error) {
+	nodes := slices.Sorted(maps.Keys(capacities))
+	nodes = slices.DeleteFunc(nodes, func(node int) bool { return capacities[node] == 0 })
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("balancing partitions: no node has a capacity")
+	}
+	weights := make(map[string]float64, len(current))
+	for partition := range current {
+		weights[partition] = b.weight(loads[partition])
+		if weights[partition] < 0 || math.IsNaN(weights[partition]) {
+			return nil, fmt.Errorf("balancing partitions: invalid weight %v of partition %q", weights[partition], partition)
+		}
+	}
+	var totalCapacity float64
+	for _, node := range nodes {
+		if capacities[node] < 0 || math.IsNaN(capacities[node]) {
+			return nil, fmt.Errorf("balancing partitions: invalid capacity %v of node %d", capacities[node], node)
+		}
+		totalCapacity += capacities[node]
+	}
+
+	pb := &PartitionBalance{
+		Assignment: make(map[string]int, len(current)),
+		NodeLoads:  make(map[int]float64, len(nodes)),
+		current:    current,
+		loads:      loads,
+	}
+	partitions := make(map[int][]string) // partitions of every node, sorted
+	var orphans []string
+	for _, partition := range slices.Sorted(maps.Keys(current)) {
+		node := current[partition]
+		if capacities[node] <= 0 {
+			orphans = append(orphans, partition)
+			continue
+		}
+		pb.Assignment[partition] = node
+		pb.NodeLoads[node] += weights[partition]
+		partitions[node] = append(partitions[node], partition)
+	}
+	relativeLoad := func(node int) float64 { return pb.NodeLoads[node] / capacities[node] }
+	move := func(partition string, from, to int) {
+		partitions[from] = slices.DeleteFunc(partitions[from], func(p string) bool { return p == partition })
+		i, _ := slices.BinarySearch(partitions[to], partition)
+		partitions[to] = slices.Insert(partitions[to], i, partition)
+		pb.Assignment[partition] = to
+		pb.NodeLoads[from] -= weights[partition]
+		pb.NodeLoads[to] += weights[partition]
+	}
+
+	// the heaviest orphans first, to the least loaded node
+	slices.SortStableFunc(orphans, func(a, b string) int { return cmp.Compare(weights[b], weights[a]) })
+	for _, partition := range orphans {
+		to := slices.MinFunc(nodes, func(a, b int) int { return cmp.Compare(relativeLoad(a), relativeLoad(b)) })
+		pb.Assignment[partition] = to
+		pb.NodeLoads[to] += weights[partition]
+		i, _ := slices.BinarySearch(partitions[to], partition)
+		partitions[to] = slices.Insert(partitions[to], i, partition)
+	}
+
+	var totalWeight float64
+	for _, w := range weights {
+		totalWeight += w
+	}
+	withinTolerance := func(node int) bool {
+		fairShare := totalWeight * capacities[node] / totalCapacity
+		return math.Abs(pb.NodeLoads[node]-fairShare) <= b.tolerance*fairShare
+	}
+	for {
+		if pb.Balanced = totalWeight == 0 || !slices.ContainsFunc(nodes, func(node int) bool { return !withinTolerance(node) }); pb.Balanced {
+			break
+		}
+		from := slices.MaxFunc(nodes, func(a, b int) int { return cmp.Compare(relativeLoad(a), relativeLoad(b)) })
+		to := slices.MinFunc(nodes, func(a, b int) int { return cmp.Compare(relativeLoad(a), relativeLoad(b)) })
+		// moving a weight w reduces the sum of the squared loads relative to capacities if 0 < w < 2*ideal,
+		// the most when w is ideal, so that moves can't cycle
+		ideal := (relativeLoad(from) - relativeLoad(to)) / (1/capacities[from] + 1/capacities[to])
+		candidates := slices.DeleteFunc(slices.Clone(partitions[from]), func(p string) bool {
+			return weights[p] <= 0 || weights[p] >= 2*ideal
+		})
+		if len(candidates) == 0 {
+			break
+		}
+		partition := slices.MinFunc(candidates, func(a, b string) int {
+			return cmp.Compare(math.Abs(weights[a]-ideal), math.Abs(weights[b]-ideal))
+		})
+		move(partition, from, to)
+	}
+	return pb, nil
+}
+
+// Moved returns the partitions assigned to another node than their current one, sorted.
+func (pb *PartitionBalance) Moved() []string {
+	var moved []string
+	for _, partition := range slices.Sorted(maps.Keys(pb.Assignment)) {
+		if pb.Assignment[partition] != pb.current[partition] {
+			moved = append(moved, partition)
+		}
+	}
+	return moved
+}
+
+// Jobs returns the migration jobs moving the partitions to their proposed nodes, one for every pair of source
+// and target nodes, with IDs made of the prefix and the indexes of the nodes.
+func (pb *PartitionBalance) Jobs(prefix string) []*PartitionMigrationJobHeader {
+	type route struct{ source, target int }
+	jobs := make(map[route]*PartitionMigrationJobHeader)
+	for _, partition := range pb.Moved() {
+		r := route{source: pb.current[partition], target: pb.Assignment[partition]}
+		if _, ok := jobs[r]; !ok {
+			jobs[r] = &PartitionMigrationJobHeader{
+				JobID:      fmt.Sprintf("%s-%d-%d", prefix, r.source, r.target),
+				SourceNode: r.source,
+				TargetNode: r.target,
+			}
+		}
+		jobs[r].Partitions = append(jobs[r].Partitions, partition)
+	}
+	return slices.SortedFunc(maps.Values(jobs), func(a, b *PartitionMigrationJobHeader) int {
+		return cmp.Or(cmp.Compare(a.SourceNode, b.SourceNode), cmp.Compare(a.TargetNode, b.TargetNode))
+	})
+}
+
+// Plan returns the plan of the migration to the proposed assignment, see NewPartitionMigrationPlan,
+// the sizes of the partitions being their backlogs.
+func (pb *PartitionBalance) Plan(id string, limits PartitionMigrationLimits) (*PartitionMigrationPlan, error) {
+	sizes := make(map[string]int64, len(pb.loads))
+	for partition, load := range pb.loads {
+		sizes[partition] = load.BacklogBytes
+	}
+	return NewPartitionMigrationPlan(id, pb.Jobs(id), limits, sizes)
+}
@@ -0,0 +1,154 @@
+package cluster_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-schemas/go/cluster"
+	"github.com/rudderlabs/rudder-schemas/go/schemastest"
+)
+
+func TestPartitionBalancer(t *testing.T) {
+	eps := func(eventsPerSecond ...float64) map[string]cluster.PartitionLoad {
+		loads := make(map[string]cluster.PartitionLoad)
+		for i, e := range eventsPerSecond {
+			loads[fmt.Sprintf("p-%d", i)] = cluster.PartitionLoad{EventsPerSecond: e, BacklogBytes: int64(i) * 100}
+		}
+		return loads
+	}
+	equal := map[int]float64{0: 1, 1: 1}
+
+	t.Run("balanced assignments are kept", func(t *testing.T) {
+		current := map[string]int{"p-0": 0, "p-1": 1, "p-2": 0, "p-3": 1}
+		balance, err := cluster.NewPartitionBalancer().Balance(current, eps(10, 9, 1, 3), equal)
+		require.NoError(t, err)
+		require.True(t, balance.Balanced)
+		require.Equal(t, current, balance.Assignment)
+		require.Empty(t, balance.Moved())
+		require.Empty(t, balance.Jobs("rebalance"))
+		require.Equal(t, map[int]float64{0: 11, 1: 12}, balance.NodeLoads)
+	})
+
+	t.Run("hot partitions are spread with few moves", func(t *testing.T) {
+		current := map[string]int{"p-0": 0, "p-1": 0, "p-2": 0, "p-3": 0, "p-4": 1, "p-5": 1}
+		balance, err := cluster.NewPartitionBalancer().Balance(current, eps(10, 10, 10, 10, 1, 1), equal)
+		require.NoError(t, err)
+		require.True(t, balance.Balanced)
+		require.Equal(t, []string{"p-0", "p-1"}, balance.Moved())
+		require.Equal(t, map[int]float64{0: 20, 1: 22}, balance.NodeLoads)
+		require.Equal(t, []*cluster.PartitionMigrationJobHeader{
+			{JobID: "rebalance-0-1", SourceNode: 0, TargetNode: 1, Partitions: []string{"p-0", "p-1"}},
+		}, balance.Jobs("rebalance"))
+	})
+
+	t.Run("loads are proportional to capacities", func(t *testing.T) {
+		current := map[string]int{"p-0": 0, "p-1": 0, "p-2": 0, "p-3": 0}
+		balance, err := cluster.NewPartitionBalancer().Balance(current, eps(5, 5, 5, 5), map[int]float64{0: 1, 1: 3})
+		require.NoError(t, err)
+		require.True(t, balance.Balanced)
+		require.Equal(t, map[int]float64{0: 5, 1: 15}, balance.NodeLoads)
+	})
+
+	t.Run("partitions of removed nodes are moved", func(t *testing.T) {
+		current := map[string]int{"p-0": 0, "p-1": 1, "p-2": 2, "p-3": 2}
+		balance, err := cluster.NewPartitionBalancer().Balance(current, eps(4, 4, 4, 4), map[int]float64{0: 1, 1: 1, 2: 0})
+		require.NoError(t, err)
+		require.True(t, balance.Balanced)
+		require.Equal(t, []string{"p-2", "p-3"}, balance.Moved())
+		require.Equal(t, map[int]float64{0: 8, 1: 8}, balance.NodeLoads)
+		jobs := balance.Jobs("scale-down")
+		require.Len(t, jobs, 2)
+		for _, job := range jobs {
+			require.Equal(t, 2, job.SourceNode)
+		}
+	})
+
+	t.Run("weights", func(t *testing.T) {
+		current := map[string]int{"p-0": 0, "p-1": 0, "p-2": 0, "p-3": 0}
+		byBacklog := cluster.WithBalancerWeight(func(load cluster.PartitionLoad) float64 { return float64(load.BacklogBytes) })
+		balance, err := cluster.NewPartitionBalancer(byBacklog).Balance(current, eps(1, 1, 1, 1), equal)
+		require.NoError(t, err)
+		require.True(t, balance.Balanced)
+		require.Equal(t, []string{"p-3"}, balance.Moved(), "backlogs are 0, 100, 200 and 300")
+	})
+
+	t.Run("tolerance", func(t *testing.T) {
+		current := map[string]int{"p-0": 0, "p-1": 0, "p-2": 1}
+		loads := eps(6, 5, 10)
+		balance, err := cluster.NewPartitionBalancer(cluster.WithBalancerTolerance(0.1)).Balance(current, loads, equal)
+		require.NoError(t, err)
+		require.True(t, balance.Balanced)
+		require.Empty(t, balance.Moved(), "11 and 10 are within 10% of 10.5")
+
+		balance, err = cluster.NewPartitionBalancer(cluster.WithBalancerTolerance(0.01)).Balance(current, loads, equal)
+		require.NoError(t, err)
+		require.False(t, balance.Balanced, "no move can balance 11 and 10 within 1%")
+		require.Empty(t, balance.Moved())
+	})
+
+	t.Run("plan", func(t *testing.T) {
+		current := map[string]int{"p-0": 0, "p-1": 0, "p-2": 0, "p-3": 0, "p-4": 1, "p-5": 1}
+		balance, err := cluster.NewPartitionBalancer().Balance(current, eps(10, 10, 10, 10, 1, 1), map[int]float64{0: 1, 1: 1, 2: 1})
+		require.NoError(t, err)
+		p, err := balance.Plan("rebalance", cluster.PartitionMigrationLimits{MaxJobsPerNode: 1})
+		require.NoError(t, err)
+		require.NoError(t, p.Validate())
+		for partition, node := range p.Assignment() {
+			require.Equal(t, balance.Assignment[partition], node)
+		}
+		require.Len(t, p.Assignment(), len(balance.Moved()))
+		require.Len(t, p.Waves, 2, "node 0 is the source of two jobs")
+		var bytes int64
+		for _, wave := range p.Waves {
+			bytes += wave.Bytes
+		}
+		require.Positive(t, bytes, "sizes are the backlogs")
+	})
+
+	t.Run("random loads", func(t *testing.T) {
+		for seed := range int64(100) {
+			r := schemastest.NewRand(seed)
+			capacities := make(map[int]float64)
+			for node := range 1 + r.Intn(6) {
+				capacities[node] = float64(r.Intn(4)) // some nodes are removed
+			}
+			capacities[0] = 1
+			current := make(map[string]int)
+			loads := make(map[string]cluster.PartitionLoad)
+			for i := range r.Intn(200) {
+				partition := fmt.Sprintf("p-%d", i)
+				current[partition] = r.Intn(len(capacities) + 1)
+				loads[partition] = cluster.PartitionLoad{EventsPerSecond: math.Floor(r.ExpFloat64() * 100)}
+			}
+			balance, err := cluster.NewPartitionBalancer().Balance(current, loads, capacities)
+			require.NoError(t, err, "seed %d", seed)
+			require.Len(t, balance.Assignment, len(current))
+			nodeLoads := make(map[int]float64)
+			for partition, node := range balance.Assignment {
+				require.Positive(t, capacities[node], "seed %d: partition %s is assigned to node %d", seed, partition, node)
+				nodeLoads[node] += loads[partition].EventsPerSecond
+			}
+			for node, load := range nodeLoads {
+				require.InDelta(t, load, balance.NodeLoads[node], 1e-6, "seed %d", seed)
+			}
+			moved := 0
+			for _, job := range balance.Jobs("j") {
+				moved += len(job.Partitions)
+			}
+			require.Len(t, balance.Moved(), moved)
+		}
+	})
+
+	t.Run("invalid inputs", func(t *testing.T) {
+		current := map[string]int{"p-0": 0}
+		_, err := cluster.NewPartitionBalancer().Balance(current, nil, map[int]float64{0: 0})
+		require.ErrorContains(t, err, "no node has a capacity")
+		_, err = cluster.NewPartitionBalancer().Balance(current, nil, map[int]float64{0: -1})
+		require.ErrorContains(t, err, "invalid capacity -1 of node 0")
+		_, err = cluster.NewPartitionBalancer().Balance(current, eps(-1), equal)
+		require.ErrorContains(t, err, `invalid weight -1 of partition "p-0"`)
+	})
+}
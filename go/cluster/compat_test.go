@@ -0,0 +1,207 @@
+package cluster_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-schemas/go/cluster"
+	"github.com/rudderlabs/rudder-schemas/go/internal/compattest"
+)
+
+// The corpus lives in testdata/compat, one file per version that changed the JSON documents of the migration types,
+// containing one document per type. Released files must never be changed: if the JSON output of a type changes,
+// add the new documents to head.json, which is renamed after the upcoming version once it is released.
+var compatTypes = map[string]func() any{
+	"PartitionMigration":     func() any { return &cluster.PartitionMigration{} },
+	"PartitionMigrationJob":  func() any { return &cluster.PartitionMigrationJob{} },
+	"PartitionMigrationInfo": func() any { return &cluster.PartitionMigrationInfo{} },
+	"PartitionMigrationAck":  func() any { return &cluster.PartitionMigrationAck{} },
+	"ReloadGatewayCommand":   func() any { return &cluster.ReloadGatewayCommand{} },
+	"ReloadGatewayAck":       func() any { return &cluster.ReloadGatewayAck{} },
+	"ReloadSrcRouterCommand": func() any { return &cluster.ReloadSrcRouterCommand{} },
+	"ReloadSrcRouterAck":     func() any { return &cluster.ReloadSrcRouterAck{} },
+}
+
+// previousMinorTypes are frozen copies of the migration types in v0.11, along with the status values known to it.
+// Update them when a new minor version is released.
+var previousMinorTypes = map[string]func() any{
+	"PartitionMigration":     func() any { return &previousPartitionMigration{} },
+	"PartitionMigrationJob":  func() any { return &previousPartitionMigrationJob{} },
+	"PartitionMigrationInfo": func() any { return &previousPartitionMigrationInfo{} },
+	"PartitionMigrationAck":  func() any { return &previousAck{} },
+	"ReloadGatewayCommand":   func() any { return &previousReloadGatewayCommand{} },
+	"ReloadGatewayAck":       func() any { return &previousAck{} },
+	"ReloadSrcRouterCommand": func() any { return &previousReloadSrcRouterCommand{} },
+	"ReloadSrcRouterAck":     func() any { return &previousReloadSrcRouterAck{} },
+}
+
+var (
+	previousMigrationStatuses = []string{"", "new", "reloading-gw", "reloading-srcrouter", "migrating", "completed"}
+	previousJobStatuses       = []string{"", "new", "moved", "completed"}
+)
+
+type previousPartitionMigrationJobHeader struct {
+	JobID      string   `json:"jobId"`
+	SourceNode int      `json:"sourceNode"`
+	TargetNode int      `json:"targetNode"`
+	Partitions []string `json:"partitions"`
+}
+
+type previousPartitionMigration struct {
+	ID             string                                 `json:"id"`
+	Status         string                                 `json:"status"`
+	PreviousStatus string                                 `json:"previousStatus"`
+	Jobs           []*previousPartitionMigrationJobHeader `json:"jobs"`
+	StartTime      time.Time                              `json:"startTime"`
+	AckKeyPrefix   string                                 `json:"ackKeyPrefix"`
+}
+
+type previousPartitionMigrationJob struct {
+	previousPartitionMigrationJobHeader
+	MigrationID string    `json:"migrationId"`
+	Status      string    `json:"status"`
+	StartTime   time.Time `json:"startTime"`
+}
+
+type previousPartitionMigrationInfo struct {
+	ID             string                           `json:"id"`
+	Status         string                           `json:"status"`
+	PreviousStatus string                           `json:"previousStatus"`
+	Jobs           []*previousPartitionMigrationJob `json:"jobs"`
+	StartTime      time.Time                        `json:"startTime"`
+	AckKeyPrefix   string                           `json:"ackKeyPrefix"`
+}
+
+type previousAck struct {
+	NodeIndex int    `json:"nodeIndex"`
+	NodeName  string `json:"nodeName"`
+}
+
+type previousReloadGatewayCommand struct {
+	Nodes        []int  `json:"nodes"`
+	AckKeyPrefix string `json:"ackKeyPrefix"`
+}
+
+type previousReloadSrcRouterCommand struct {
+	AckKeyPrefix string `json:"ackKeyPrefix"`
+}
+
+type previousReloadSrcRouterAck struct {
+	NodeName string `json:"nodeName"`
+}
+
+func TestCompatibility(t *testing.T) {
+	corpus := loadCompatCorpus(t)
+	latest := corpus[len(corpus)-1]
+
+	t.Run("released documents decode", func(t *testing.T) {
+		for _, c := range corpus {
+			for name, doc := range c.Content {
+				t.Run(c.Version+"/"+name, func(t *testing.T) {
+					newType, ok := compatTypes[name]
+					require.True(t, ok, "unknown type %q", name)
+
+					// unknown fields are rejected, so that renamed or removed fields are detected
+					v := newType()
+					dec := json.NewDecoder(bytes.NewReader(doc))
+					dec.DisallowUnknownFields()
+					require.NoError(t, dec.Decode(v))
+
+					// released fields keep their values when encoded again
+					data, err := json.Marshal(v)
+					require.NoError(t, err)
+					requireJSONSubset(t, doc, data)
+				})
+			}
+		}
+	})
+
+	t.Run("current output is in the corpus", func(t *testing.T) {
+		require.Len(t, latest.Content, len(compatTypes), "%s must contain a document for every type", latest.Version)
+		for name, doc := range latest.Content {
+			t.Run(name, func(t *testing.T) {
+				v := compatTypes[name]()
+				require.NoError(t, json.Unmarshal(doc, v))
+				data, err := json.Marshal(v)
+				require.NoError(t, err)
+				require.JSONEq(t, string(doc), string(data),
+					"the JSON output of %s changed, add the new document to %s.json, released files must not change", name, compattest.HeadVersion)
+			})
+		}
+	})
+
+	t.Run("current output is readable by the previous minor version", func(t *testing.T) {
+		for name, doc := range latest.Content {
+			t.Run(name, func(t *testing.T) {
+				v := compatTypes[name]()
+				require.NoError(t, json.Unmarshal(doc, v))
+				data, err := json.Marshal(v)
+				require.NoError(t, err)
+
+				previous := previousMinorTypes[name]()
+				require.NoError(t, json.Unmarshal(data, previous))
+
+				switch p := previous.(type) {
+				case *previousPartitionMigration:
+					require.Contains(t, previousMigrationStatuses, p.Status)
+					require.Contains(t, previousMigrationStatuses, p.PreviousStatus)
+				case *previousPartitionMigrationInfo:
+					require.Contains(t, previousMigrationStatuses, p.Status)
+					require.Contains(t, previousMigrationStatuses, p.PreviousStatus)
+					for _, job := range p.Jobs {
+						require.Contains(t, previousJobStatuses, job.Status)
+					}
+				case *previousPartitionMigrationJob:
+					require.Contains(t, previousJobStatuses, p.Status)
+				}
+
+				// every field known to the previous minor version is preserved
+				previousData, err := json.Marshal(previous)
+				require.NoError(t, err)
+				requireJSONSubset(t, previousData, data)
+			})
+		}
+	})
+}
+
+func loadCompatCorpus(t testing.TB) []compattest.Version[map[string]json.RawMessage] {
+	t.Helper()
+	return compattest.Load[map[string]json.RawMessage](t, filepath.Join("testdata", "compat"))
+}
+
+// requireJSONSubset requires every object field of expected to be present in actual with the same value.
+func requireJSONSubset(t *testing.T, expected, actual []byte) {
+	t.Helper()
+	var e, a any
+	require.NoError(t, json.Unmarshal(expected, &e))
+	require.NoError(t, json.Unmarshal(actual, &a))
+	requireSubset(t, "$", e, a)
+}
+
+func requireSubset(t *testing.T, path string, expected, actual any) {
+	t.Helper()
+	switch e := expected.(type) {
+	case map[string]any:
+		a, ok := actual.(map[string]any)
+		require.True(t, ok, "%s: expected an object, got %v", path, actual)
+		for k, v := range e {
+			require.Contains(t, a, k, "%s: field %q is missing", path, k)
+			requireSubset(t, path+"."+k, v, a[k])
+		}
+	case []any:
+		a, ok := actual.([]any)
+		require.True(t, ok, "%s: expected an array, got %v", path, actual)
+		require.Len(t, a, len(e), "%s: array length differs", path)
+		for i := range e {
+			requireSubset(t, path+"["+strconv.Itoa(i)+"]", e[i], a[i])
+		}
+	default:
+		require.Equal(t, expected, actual, "%s: value differs", path)
+	}
+}
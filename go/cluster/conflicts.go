@@ -0,0 +1,125 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+var ErrMigrationConflict = errors.New("migration conflict")
+
+// MigrationScope is what a migration touches: the partitions and nodes of its jobs, and the nodes it reloads.
+type MigrationScope struct {
+	Migration      *PartitionMigration
+	GatewayNodes   []int    // indexes of the gateway nodes reloaded by the migration
+	SrcRouterNodes []string // names of the source router nodes reloaded by the migration
+}
+
+type MigrationConflictKind string
+
+const (
+	MigrationConflictKindPartition       MigrationConflictKind = "partition"        // both migrations move the same partitions
+	MigrationConflictKindNodeRole        MigrationConflictKind = "node-role"        // nodes are sources in one migration and targets in the other
+	MigrationConflictKindGatewayReload   MigrationConflictKind = "gateway-reload"   // both migrations reload the same gateway nodes
+	MigrationConflictKindSrcRouterReload MigrationConflictKind = "srcrouter-reload" // both migrations reload the same source router nodes
+)
+
+// MigrationConflict is a conflict between a proposed migration and an active one.
+type MigrationConflict struct {
+	Kind        MigrationConflictKind
+	MigrationID string   // ID of the active migration
+	Partitions  []string // partitions moved by both migrations
+	Nodes       []int    // nodes with opposite roles, or gateway nodes reloaded by both migrations
+	NodeNames   []string // source router nodes reloaded by both migrations
+}
+
+func (c MigrationConflict) String() string {
+	switch c.Kind {
+	case MigrationConflictKindPartition:
+		return fmt.Sprintf("partitions %v are moved by migration %q", c.Partitions, c.MigrationID)
+	case MigrationConflictKindNodeRole:
+		return fmt.Sprintf("nodes %v are sources in one migration and targets in the other, migration %q", c.Nodes, c.MigrationID)
+	case MigrationConflictKindGatewayReload:
+		return fmt.Sprintf("gateway nodes %v are reloaded by migration %q", c.Nodes, c.MigrationID)
+	case MigrationConflictKindSrcRouterReload:
+		return fmt.Sprintf("source router nodes %v are reloaded by migration %q", c.NodeNames, c.MigrationID)
+	}
+	return fmt.Sprintf("%s conflict with migration %q", c.Kind, c.MigrationID)
+}
+
+// DetectMigrationConflicts returns the conflicts of a proposed migration with the active ones, in the order of the
+// active migrations. Completed migrations, and the proposed migration itself, are not considered active.
+func DetectMigrationConflicts(active []MigrationScope, proposed MigrationScope) []MigrationConflict {
+	partitions := migrationPartitions(proposed.Migration)
+	sources, targets := proposed.Migration.SourceNodes(), proposed.Migration.TargetNodes()
+
+	var conflicts []MigrationConflict
+	for _, a := range activeMigrations(active, proposed) {
+		if overlap := sorted(lo.Intersect(partitions, migrationPartitions(a.Migration))); len(overlap) > 0 {
+			conflicts = append(conflicts, MigrationConflict{Kind: MigrationConflictKindPartition, MigrationID: a.Migration.ID, Partitions: overlap})
+		}
+		opposite := append(lo.Intersect(sources, a.Migration.TargetNodes()), lo.Intersect(targets, a.Migration.SourceNodes())...)
+		if opposite = sorted(lo.Uniq(opposite)); len(opposite) > 0 {
+			conflicts = append(conflicts, MigrationConflict{Kind: MigrationConflictKindNodeRole, MigrationID: a.Migration.ID, Nodes: opposite})
+		}
+		if overlap := sorted(lo.Intersect(proposed.GatewayNodes, a.GatewayNodes)); len(overlap) > 0 {
+			conflicts = append(conflicts, MigrationConflict{Kind: MigrationConflictKindGatewayReload, MigrationID: a.Migration.ID, Nodes: overlap})
+		}
+		if overlap := sorted(lo.Intersect(proposed.SrcRouterNodes, a.SrcRouterNodes)); len(overlap) > 0 {
+			conflicts = append(conflicts, MigrationConflict{Kind: MigrationConflictKindSrcRouterReload, MigrationID: a.Migration.ID, NodeNames: overlap})
+		}
+	}
+	return conflicts
+}
+
+// MigrationPolicy decides whether a proposed migration may start while other migrations are active.
+// The zero value runs one migration at a time.
+type MigrationPolicy struct {
+	Parallel         bool                    // whether migrations may run in parallel, as long as they don't conflict
+	MaxParallel      int                     // maximum number of migrations running in parallel, 0 for no limit
+	AllowedConflicts []MigrationConflictKind // kinds of conflicts tolerated between parallel migrations, e.g. reloads
+}
+
+// Check returns an error wrapping ErrMigrationConflict if the proposed migration may not start
+// while the active ones are running, see DetectMigrationConflicts.
+func (p MigrationPolicy) Check(active []MigrationScope, proposed MigrationScope) error {
+	running := activeMigrations(active, proposed)
+	if len(running) == 0 {
+		return nil
+	}
+	if !p.Parallel {
+		return fmt.Errorf("%w: migration %q can't start while migration %q is active", ErrMigrationConflict, proposed.Migration.ID, running[0].Migration.ID)
+	}
+	if p.MaxParallel > 0 && len(running) >= p.MaxParallel {
+		return fmt.Errorf("%w: migration %q can't start while %d migrations are active", ErrMigrationConflict, proposed.Migration.ID, len(running))
+	}
+	conflicts := lo.Filter(DetectMigrationConflicts(running, proposed), func(c MigrationConflict, _ int) bool {
+		return !slices.Contains(p.AllowedConflicts, c.Kind)
+	})
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%w: migration %q: %s", ErrMigrationConflict, proposed.Migration.ID,
+			strings.Join(lo.Map(conflicts, func(c MigrationConflict, _ int) string { return c.String() }), ", "))
+	}
+	return nil
+}
+
+// activeMigrations returns the migrations which are not completed, other than the proposed one.
+func activeMigrations(migrations []MigrationScope, proposed MigrationScope) []MigrationScope {
+	return lo.Filter(migrations, func(m MigrationScope, _ int) bool {
+		return m.Migration.ID != proposed.Migration.ID && m.Migration.Status != PartitionMigrationStatusCompleted
+	})
+}
+
+func migrationPartitions(pm *PartitionMigration) []string {
+	return lo.FlatMap(pm.Jobs, func(job *PartitionMigrationJobHeader, _ int) []string {
+		return job.Partitions
+	})
+}
+
+func sorted[T int | string](s []T) []T {
+	slices.Sort(s)
+	return s
+}
@@ -0,0 +1,90 @@
+package cluster_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-schemas/go/cluster"
+)
+
+func TestMigrationConflicts(t *testing.T) {
+	migration := func(id string, jobs ...*cluster.PartitionMigrationJobHeader) *cluster.PartitionMigration {
+		return &cluster.PartitionMigration{ID: id, Status: cluster.PartitionMigrationStatusMigrating, Jobs: jobs}
+	}
+	job := func(source, target int, partitions ...string) *cluster.PartitionMigrationJobHeader {
+		return &cluster.PartitionMigrationJobHeader{JobID: "job", SourceNode: source, TargetNode: target, Partitions: partitions}
+	}
+
+	active := []cluster.MigrationScope{
+		{
+			Migration:      migration("migration-1", job(0, 1, "ws-0", "ws-1"), job(0, 2, "ws-2")),
+			GatewayNodes:   []int{0, 1},
+			SrcRouterNodes: []string{"srcrouter-0"},
+		},
+		{
+			Migration:    migration("migration-2", job(3, 4, "ws-3")),
+			GatewayNodes: []int{2},
+		},
+	}
+
+	t.Run("conflicts", func(t *testing.T) {
+		proposed := cluster.MigrationScope{
+			Migration:      migration("migration-3", job(1, 3, "ws-2", "ws-1", "ws-9"), job(5, 0, "ws-8")),
+			GatewayNodes:   []int{2, 1},
+			SrcRouterNodes: []string{"srcrouter-0", "srcrouter-1"},
+		}
+		require.Equal(t, []cluster.MigrationConflict{
+			{Kind: cluster.MigrationConflictKindPartition, MigrationID: "migration-1", Partitions: []string{"ws-1", "ws-2"}},
+			{Kind: cluster.MigrationConflictKindNodeRole, MigrationID: "migration-1", Nodes: []int{0, 1}},
+			{Kind: cluster.MigrationConflictKindGatewayReload, MigrationID: "migration-1", Nodes: []int{1}},
+			{Kind: cluster.MigrationConflictKindSrcRouterReload, MigrationID: "migration-1", NodeNames: []string{"srcrouter-0"}},
+			{Kind: cluster.MigrationConflictKindNodeRole, MigrationID: "migration-2", Nodes: []int{3}},
+			{Kind: cluster.MigrationConflictKindGatewayReload, MigrationID: "migration-2", Nodes: []int{2}},
+		}, cluster.DetectMigrationConflicts(active, proposed))
+	})
+
+	t.Run("no conflicts", func(t *testing.T) {
+		proposed := cluster.MigrationScope{
+			Migration:    migration("migration-3", job(0, 5, "ws-5"), job(3, 6, "ws-6")),
+			GatewayNodes: []int{3},
+		}
+		require.Empty(t, cluster.DetectMigrationConflicts(active, proposed), "sharing sources or targets isn't a conflict")
+	})
+
+	t.Run("completed migrations and the proposed one are not active", func(t *testing.T) {
+		completed := migration("migration-0", job(0, 1, "ws-0"))
+		completed.Status = cluster.PartitionMigrationStatusCompleted
+		proposed := cluster.MigrationScope{Migration: migration("migration-1", job(0, 1, "ws-0"))}
+		require.Empty(t, cluster.DetectMigrationConflicts(append([]cluster.MigrationScope{{Migration: completed}}, active[0]), proposed))
+	})
+
+	t.Run("policy", func(t *testing.T) {
+		independent := cluster.MigrationScope{Migration: migration("migration-3", job(5, 6, "ws-5"))}
+		reloading := cluster.MigrationScope{Migration: migration("migration-3", job(5, 6, "ws-5")), GatewayNodes: []int{0}}
+		conflicting := cluster.MigrationScope{Migration: migration("migration-3", job(4, 5, "ws-3"))}
+
+		var serial cluster.MigrationPolicy
+		require.NoError(t, serial.Check(nil, independent))
+		err := serial.Check(active, independent)
+		require.ErrorIs(t, err, cluster.ErrMigrationConflict)
+		require.ErrorContains(t, err, `migration "migration-3" can't start while migration "migration-1" is active`)
+
+		parallel := cluster.MigrationPolicy{Parallel: true}
+		require.NoError(t, parallel.Check(active, independent))
+		require.ErrorIs(t, parallel.Check(active, reloading), cluster.ErrMigrationConflict)
+		err = parallel.Check(active, conflicting)
+		require.ErrorIs(t, err, cluster.ErrMigrationConflict)
+		require.ErrorContains(t, err, `migration "migration-3": partitions [ws-3] are moved by migration "migration-2", `+
+			`nodes [4] are sources in one migration and targets in the other, migration "migration-2"`)
+
+		tolerant := cluster.MigrationPolicy{Parallel: true, AllowedConflicts: []cluster.MigrationConflictKind{cluster.MigrationConflictKindGatewayReload}}
+		require.NoError(t, tolerant.Check(active, reloading))
+
+		limited := cluster.MigrationPolicy{Parallel: true, MaxParallel: 2}
+		err = limited.Check(active, independent)
+		require.ErrorIs(t, err, cluster.ErrMigrationConflict)
+		require.ErrorContains(t, err, "while 2 migrations are active")
+		require.NoError(t, limited.Check(active[:1], independent))
+	})
+}
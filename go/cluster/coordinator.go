@@ -0,0 +1,358 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// Coordinator drives a PartitionMigration through its lifecycle, against a Store using a KeyLayout:
+//
+//   - new: the migration is published and every source and target node acknowledges it with a PartitionMigrationAck
+//   - reloading-gw: a ReloadGatewayCommand is published and every gateway node acknowledges it with a ReloadGatewayAck
+//   - reloading-srcrouter: a ReloadSrcRouterCommand is published and every source router acknowledges it with a ReloadSrcRouterAck
+//   - migrating: every job is published with status new, its source node marks it as moved once it has moved
+//     the partitions and its target node marks it as completed, by updating the job document
+//   - completed: all jobs are completed
+//
+// The PartitionMigrationInfo is persisted with compare-and-swap before every transition, so that a coordinator
+// can resume the migration from any persisted status after a crash, and concurrent coordinators can't both advance it.
+// Publishing is idempotent, nodes must tolerate seeing the same migration, command or job more than once.
+type Coordinator struct {
+	store  Store
+	layout *KeyLayout
+
+	gatewayNodes   []int
+	srcRouterNodes []string
+	now            func() time.Time
+}
+
+// NewCoordinator returns a Coordinator for the migration of the layout.
+func NewCoordinator(store Store, layout *KeyLayout, opt ...func(c *Coordinator)) *Coordinator {
+	c := &Coordinator{
+		store:  store,
+		layout: layout,
+		now:    time.Now,
+	}
+	for _, o := range opt {
+		o(c)
+	}
+	return c
+}
+
+// WithCoordinatorGatewayNodes sets the indexes of the gateway nodes to reload.
+func WithCoordinatorGatewayNodes(nodes ...int) func(c *Coordinator) {
+	return func(c *Coordinator) {
+		c.gatewayNodes = nodes
+	}
+}
+
+// WithCoordinatorSrcRouterNodes sets the names of the source router nodes to reload.
+func WithCoordinatorSrcRouterNodes(nodeNames ...string) func(c *Coordinator) {
+	return func(c *Coordinator) {
+		c.srcRouterNodes = nodeNames
+	}
+}
+
+// WithCoordinatorClock sets the clock used for the start times of the migration and its jobs.
+func WithCoordinatorClock(now func() time.Time) func(c *Coordinator) {
+	return func(c *Coordinator) {
+		c.now = now
+	}
+}
+
+// Run drives the migration until it is completed, returning its final information.
+//
+// If the store already contains the information of the migration, the migration is resumed from its persisted status
+// and pm is only used to verify the migration ID. Otherwise pm, which must be new, is persisted and started.
+func (c *Coordinator) Run(ctx context.Context, pm *PartitionMigration) (*PartitionMigrationInfo, error) {
+	if pm.ID != c.layout.MigrationID() {
+		return nil, fmt.Errorf("migration %q doesn't match the key layout of migration %q", pm.ID, c.layout.MigrationID())
+	}
+	r, err := c.load(ctx, pm)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if err := r.publishMigration(ctx); err != nil {
+			return nil, err
+		}
+		status := r.info.Status // advancing changes it, even if persisting fails
+		switch status {
+		case PartitionMigrationStatusNew:
+			err = r.awaitMigrationAcks(ctx)
+			if err == nil {
+				err = r.advance(ctx, PartitionMigrationStatusReloadingGW)
+			}
+		case PartitionMigrationStatusReloadingGW:
+			err = r.reloadGateways(ctx)
+			if err == nil {
+				err = r.advance(ctx, PartitionMigrationStatusReloadingSrcRouter)
+			}
+		case PartitionMigrationStatusReloadingSrcRouter:
+			err = r.reloadSrcRouters(ctx)
+			if err == nil {
+				err = r.advance(ctx, PartitionMigrationStatusMigrating)
+			}
+		case PartitionMigrationStatusMigrating:
+			err = r.migrate(ctx)
+			if err == nil {
+				err = r.advance(ctx, PartitionMigrationStatusCompleted)
+			}
+		case PartitionMigrationStatusCompleted:
+			return r.info.Clone(), nil
+		default:
+			return nil, fmt.Errorf("unknown status %q of migration %q", status, r.info.ID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("migration %q %s: %w", r.info.ID, status, err)
+		}
+	}
+}
+
+// coordinatorRun is the state of a running migration: its information and the revision it was persisted at.
+type coordinatorRun struct {
+	*Coordinator
+	info     *PartitionMigrationInfo
+	revision int64
+}
+
+// load returns the persisted information of the migration, or persists it if it is new.
+func (c *Coordinator) load(ctx context.Context, pm *PartitionMigration) (*coordinatorRun, error) {
+	info, revision, err := GetJSON[PartitionMigrationInfo](ctx, c.store, c.layout.MigrationInfoKey())
+	if err == nil {
+		if info.ID != pm.ID {
+			return nil, fmt.Errorf("persisted migration %q doesn't match migration %q", info.ID, pm.ID)
+		}
+		return &coordinatorRun{Coordinator: c, info: info, revision: revision}, nil
+	}
+	if !errors.Is(err, ErrKeyNotFound) {
+		return nil, err
+	}
+
+	if pm.Status != PartitionMigrationStatusNew {
+		return nil, fmt.Errorf("starting migration %q: status must be %q, got %q", pm.ID, PartitionMigrationStatusNew, pm.Status)
+	}
+	if pm.AckKeyPrefix != "" && pm.AckKeyPrefix != c.layout.MigrationAckKeyPrefix() {
+		return nil, fmt.Errorf("starting migration %q: ack key prefix %q doesn't match the key layout", pm.ID, pm.AckKeyPrefix)
+	}
+	for _, job := range pm.Jobs {
+		if _, err := c.layout.JobKey(job.JobID); err != nil {
+			return nil, fmt.Errorf("starting migration %q: %w", pm.ID, err)
+		}
+	}
+	info = &PartitionMigrationInfo{}
+	info.FromPartitionMigration(*pm, nil)
+	info.AckKeyPrefix = c.layout.MigrationAckKeyPrefix()
+	if info.StartTime.IsZero() {
+		info.StartTime = c.now()
+	}
+	for _, job := range info.Jobs {
+		job.Status = PartitionMigrationJobStatusNew
+	}
+	r := &coordinatorRun{Coordinator: c, info: info}
+	if err := r.persist(ctx); err != nil {
+		return nil, fmt.Errorf("starting migration %q: %w", pm.ID, err)
+	}
+	return r, nil
+}
+
+// persist persists the information of the migration, failing if it was modified since it was loaded.
+func (r *coordinatorRun) persist(ctx context.Context) error {
+	revision, err := CompareAndSwapJSON(ctx, r.store, r.layout.MigrationInfoKey(), r.revision, r.info)
+	if err != nil {
+		return err
+	}
+	r.revision = revision
+	return nil
+}
+
+// advance persists the transition of the migration to the given status.
+func (r *coordinatorRun) advance(ctx context.Context, status PartitionMigrationStatus) error {
+	r.info.PreviousStatus, r.info.Status = r.info.Status, status
+	return r.persist(ctx)
+}
+
+// publishMigration publishes the migration document for nodes, with the current status.
+func (r *coordinatorRun) publishMigration(ctx context.Context) error {
+	pm := &PartitionMigration{
+		ID:             r.info.ID,
+		Status:         r.info.Status,
+		PreviousStatus: r.info.PreviousStatus,
+		Jobs: lo.Map(r.info.Jobs, func(job *PartitionMigrationJob, _ int) *PartitionMigrationJobHeader {
+			return job.PartitionMigrationJobHeader.Clone()
+		}),
+		StartTime:    r.info.StartTime,
+		AckKeyPrefix: r.info.AckKeyPrefix,
+	}
+	return r.publish(ctx, r.layout.MigrationKey(), pm)
+}
+
+func (r *coordinatorRun) awaitMigrationAcks(ctx context.Context) error {
+	nodes := lo.Uniq(lo.FlatMap(r.info.Jobs, func(job *PartitionMigrationJob, _ int) []int {
+		return []int{job.SourceNode, job.TargetNode}
+	}))
+	return awaitAcks(ctx, r, r.layout.MigrationAckKeyPrefix(), nodes, func(ack *PartitionMigrationAck) int {
+		return ack.NodeIndex
+	})
+}
+
+func (r *coordinatorRun) reloadGateways(ctx context.Context) error {
+	if err := r.publish(ctx, r.layout.ReloadGatewayKey(), r.layout.ReloadGatewayCommand(r.gatewayNodes)); err != nil {
+		return err
+	}
+	return awaitAcks(ctx, r, r.layout.ReloadGatewayAckKeyPrefix(), r.gatewayNodes, func(ack *ReloadGatewayAck) int {
+		return ack.NodeIndex
+	})
+}
+
+func (r *coordinatorRun) reloadSrcRouters(ctx context.Context) error {
+	if err := r.publish(ctx, r.layout.ReloadSrcRouterKey(), r.layout.ReloadSrcRouterCommand()); err != nil {
+		return err
+	}
+	return awaitAcks(ctx, r, r.layout.ReloadSrcRouterAckKeyPrefix(), r.srcRouterNodes, func(ack *ReloadSrcRouterAck) string {
+		return ack.NodeName
+	})
+}
+
+// awaitAcks waits until all nodes acknowledged under the ack key prefix, identifying the node of an ack with node.
+func awaitAcks[A any, N comparable](ctx context.Context, r *coordinatorRun, ackKeyPrefix string, nodes []N, node func(ack *A) N) error {
+	return r.await(ctx, ackKeyPrefix+"/", func(kvs []KeyValue) (bool, error) {
+		acked := make(map[N]struct{}, len(kvs))
+		for _, kv := range kvs {
+			ack, err := unmarshalDocument[A](kv)
+			if err != nil {
+				return false, err
+			}
+			acked[node(ack)] = struct{}{}
+		}
+		return lo.EveryBy(nodes, func(n N) bool {
+			_, ok := acked[n]
+			return ok
+		}), nil
+	})
+}
+
+// migrate starts the jobs that are not started yet, and follows their documents until all of them are completed,
+// persisting the migration information whenever the status of a job changes.
+func (r *coordinatorRun) migrate(ctx context.Context) error {
+	return r.await(ctx, r.layout.JobsPrefix(), func(kvs []KeyValue) (bool, error) {
+		published := make(map[string]*PartitionMigrationJob, len(kvs))
+		for _, kv := range kvs {
+			job, err := unmarshalDocument[PartitionMigrationJob](kv)
+			if err != nil {
+				return false, err
+			}
+			published[job.JobID] = job
+		}
+
+		changed := false
+		completed := true
+		for _, job := range r.info.Jobs {
+			p, ok := published[job.JobID]
+			if !ok {
+				if err := r.startJob(ctx, job); err != nil {
+					return false, err
+				}
+				changed = true
+				completed = false
+				continue
+			}
+			if !slices.Contains(migrationJobStatuses, p.Status) {
+				return false, fmt.Errorf("unknown status %q of job %q", p.Status, job.JobID)
+			}
+			if slices.Index(migrationJobStatuses, p.Status) < slices.Index(migrationJobStatuses, job.Status) {
+				p = job // a stale write of a node landing late, e.g. a retry, doesn't move the job back
+			}
+			if p.Status != job.Status || !p.StartTime.Equal(job.StartTime) {
+				job.Status, job.StartTime = p.Status, p.StartTime
+				changed = true
+			}
+			completed = completed && job.Status == PartitionMigrationJobStatusCompleted
+		}
+		if changed {
+			if err := r.persist(ctx); err != nil {
+				return false, err
+			}
+		}
+		return completed, nil
+	})
+}
+
+// startJob publishes a job with status new, unless it was already published.
+func (r *coordinatorRun) startJob(ctx context.Context, job *PartitionMigrationJob) error {
+	key, err := r.layout.JobKey(job.JobID)
+	if err != nil {
+		return err
+	}
+	job.Status = PartitionMigrationJobStatusNew
+	job.StartTime = r.now()
+	if _, err := CompareAndSwapJSON(ctx, r.store, key, 0, job); err != nil && !errors.Is(err, ErrRevisionConflict) {
+		return err
+	}
+	return nil
+}
+
+// publish puts a document, unless it is already up to date.
+func (r *coordinatorRun) publish(ctx context.Context, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshalling %s: %w", key, err)
+	}
+	kv, err := r.store.Get(ctx, key)
+	if err == nil && bytes.Equal(kv.Value, data) {
+		return nil
+	}
+	if err != nil && !errors.Is(err, ErrKeyNotFound) {
+		return err
+	}
+	_, err = r.store.Put(ctx, key, data)
+	return err
+}
+
+// await lists the keys with the given prefix until done returns true, watching for modifications in between.
+func (r *coordinatorRun) await(ctx context.Context, prefix string, done func(kvs []KeyValue) (bool, error)) error {
+	for {
+		kvs, revision, err := r.store.List(ctx, prefix)
+		if err != nil {
+			return err
+		}
+		if ok, err := done(kvs); err != nil || ok {
+			return err
+		}
+		if err := awaitModification(ctx, r.store, prefix, revision); err != nil {
+			return err
+		}
+	}
+}
+
+// awaitModification waits for a modification of the keys with the given prefix after the given revision.
+func awaitModification(ctx context.Context, store Store, prefix string, revision int64) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := store.Watch(ctx, prefix, revision+1)
+	if err != nil {
+		return err
+	}
+	select {
+	case _, ok := <-events:
+		if !ok && ctx.Err() == nil {
+			return fmt.Errorf("watching %s: watch closed", prefix)
+		}
+		return ctx.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+var migrationJobStatuses = []PartitionMigrationJobStatus{
+	PartitionMigrationJobStatusNew,
+	PartitionMigrationJobStatusMoved,
+	PartitionMigrationJobStatusCompleted,
+}
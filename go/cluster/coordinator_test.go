@@ -0,0 +1,286 @@
+package cluster_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-schemas/go/cluster"
+	"github.com/rudderlabs/rudder-schemas/go/schemastest"
+)
+
+func TestCoordinator(t *testing.T) {
+	clock := func() time.Time { return schemastest.FixtureTime }
+	gatewayNodes := []int{0, 1}
+	srcRouterNodes := []string{"srcrouter-0"}
+	newCoordinator := func(store cluster.Store, layout *cluster.KeyLayout) *cluster.Coordinator {
+		return cluster.NewCoordinator(store, layout,
+			cluster.WithCoordinatorGatewayNodes(gatewayNodes...),
+			cluster.WithCoordinatorSrcRouterNodes(srcRouterNodes...),
+			cluster.WithCoordinatorClock(clock),
+		)
+	}
+	newLayout := func(t *testing.T, pm *cluster.PartitionMigration) *cluster.KeyLayout {
+		layout, err := cluster.NewKeyLayout("/migrations", pm.ID)
+		require.NoError(t, err)
+		return layout
+	}
+	expectedInfo := func(pm *cluster.PartitionMigration, layout *cluster.KeyLayout) *cluster.PartitionMigrationInfo {
+		var expected cluster.PartitionMigrationInfo
+		expected.FromPartitionMigration(*pm, nil)
+		expected.Status = cluster.PartitionMigrationStatusCompleted
+		expected.PreviousStatus = cluster.PartitionMigrationStatusMigrating
+		expected.AckKeyPrefix = layout.MigrationAckKeyPrefix()
+		for _, job := range expected.Jobs {
+			job.Status = cluster.PartitionMigrationJobStatusCompleted
+			job.StartTime = clock()
+		}
+		return &expected
+	}
+
+	t.Run("full lifecycle", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		pm := schemastest.NewPartitionMigration()
+		layout := newLayout(t, pm)
+		store := cluster.NewMemoryStore()
+		startAgents(ctx, t, store, pm, gatewayNodes, srcRouterNodes)
+
+		info, err := newCoordinator(store, layout).Run(ctx, pm)
+		require.NoError(t, err)
+		require.Equal(t, expectedInfo(pm, layout), info)
+
+		persisted, _, err := cluster.GetJSON[cluster.PartitionMigrationInfo](ctx, store, layout.MigrationInfoKey())
+		require.NoError(t, err)
+		require.Equal(t, info, persisted)
+
+		published, _, err := cluster.GetJSON[cluster.PartitionMigration](ctx, store, layout.MigrationKey())
+		require.NoError(t, err)
+		require.Equal(t, cluster.PartitionMigrationStatusCompleted, published.Status)
+		require.Equal(t, pm.Jobs, published.Jobs)
+
+		gw, _, err := cluster.GetJSON[cluster.ReloadGatewayCommand](ctx, store, layout.ReloadGatewayKey())
+		require.NoError(t, err)
+		require.Equal(t, layout.ReloadGatewayCommand(gatewayNodes), gw)
+
+		t.Run("running a completed migration returns immediately", func(t *testing.T) {
+			revision := store.Revision()
+			info, err := newCoordinator(store, layout).Run(ctx, pm)
+			require.NoError(t, err)
+			require.Equal(t, expectedInfo(pm, layout), info)
+			require.Equal(t, revision, store.Revision(), "nothing is written")
+		})
+	})
+
+	t.Run("resume after a crash at every write", func(t *testing.T) {
+		for seed := range int64(5) {
+			pm := schemastest.NewPartitionMigration()
+			if seed > 0 {
+				pm = schemastest.PartitionMigration(schemastest.NewRand(seed))
+				pm.Status, pm.PreviousStatus, pm.AckKeyPrefix = cluster.PartitionMigrationStatusNew, "", ""
+			}
+			layout := newLayout(t, pm)
+
+			// count the writes of a migration without crashes
+			writes := func() int64 {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				store := cluster.NewMemoryStore()
+				startAgents(ctx, t, store, pm, gatewayNodes, srcRouterNodes)
+				crashing := &crashingStore{Store: store, writesLeft: -1}
+				_, err := newCoordinator(crashing, layout).Run(ctx, pm)
+				require.NoError(t, err)
+				return crashing.writes.Load()
+			}()
+			require.Positive(t, writes)
+
+			for crashAt := range writes {
+				t.Run(fmt.Sprintf("seed %d write %d", seed, crashAt), func(t *testing.T) {
+					ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+					defer cancel()
+					store := cluster.NewMemoryStore()
+					startAgents(ctx, t, store, pm, gatewayNodes, srcRouterNodes)
+
+					info, err := newCoordinator(&crashingStore{Store: store, writesLeft: crashAt}, layout).Run(ctx, pm)
+					if err == nil { // job updates of the nodes may be batched, needing fewer writes than the first run
+						require.Equal(t, expectedInfo(pm, layout), info)
+						return
+					}
+					require.ErrorIs(t, err, errCrash)
+
+					info, err = newCoordinator(store, layout).Run(ctx, pm)
+					require.NoError(t, err)
+					require.Equal(t, expectedInfo(pm, layout), info)
+				})
+			}
+		}
+	})
+
+	t.Run("errors report the status the transition failed from", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		pm := schemastest.NewPartitionMigration()
+		layout := newLayout(t, pm)
+		store := cluster.NewMemoryStore()
+		startAgents(ctx, t, store, pm, gatewayNodes, srcRouterNodes)
+
+		// the information and the migration are written, advancing to reloading-gw fails
+		_, err := newCoordinator(&crashingStore{Store: store, writesLeft: 2}, layout).Run(ctx, pm)
+		require.EqualError(t, err, `migration "migration-1" new: crash`)
+	})
+
+	t.Run("concurrent modifications of the migration are detected", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		pm := schemastest.NewPartitionMigration()
+		layout := newLayout(t, pm)
+		store := cluster.NewMemoryStore()
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := newCoordinator(store, layout).Run(ctx, pm)
+			done <- err
+		}()
+		require.Eventually(t, func() bool {
+			_, err := store.Get(ctx, layout.MigrationKey())
+			return err == nil
+		}, 5*time.Second, time.Millisecond, "the coordinator waits for the acks of the nodes")
+
+		info, _, err := cluster.GetJSON[cluster.PartitionMigrationInfo](ctx, store, layout.MigrationInfoKey())
+		require.NoError(t, err)
+		_, err = cluster.PutJSON(ctx, store, layout.MigrationInfoKey(), info) // another coordinator
+		require.NoError(t, err)
+
+		startAgents(ctx, t, store, pm, gatewayNodes, srcRouterNodes)
+		require.ErrorIs(t, <-done, cluster.ErrRevisionConflict)
+	})
+
+	t.Run("stale job statuses are ignored", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		pm := schemastest.NewPartitionMigration()
+		layout := newLayout(t, pm)
+		store := cluster.NewMemoryStore()
+
+		// all the jobs are completed, but a stale write of their moved status landed afterwards
+		info := expectedInfo(pm, layout)
+		info.Status, info.PreviousStatus = cluster.PartitionMigrationStatusMigrating, cluster.PartitionMigrationStatusReloadingSrcRouter
+		_, err := cluster.PutJSON(ctx, store, layout.MigrationInfoKey(), info)
+		require.NoError(t, err)
+		for _, job := range info.Jobs {
+			key, err := layout.JobKey(job.JobID)
+			require.NoError(t, err)
+			stale := job.Clone()
+			stale.Status = cluster.PartitionMigrationJobStatusMoved
+			_, err = cluster.PutJSON(ctx, store, key, stale)
+			require.NoError(t, err)
+		}
+
+		completed, err := newCoordinator(store, layout).Run(ctx, pm)
+		require.NoError(t, err)
+		require.Equal(t, expectedInfo(pm, layout), completed)
+	})
+
+	t.Run("invalid migrations", func(t *testing.T) {
+		ctx := context.Background()
+		pm := schemastest.NewPartitionMigration()
+		layout := newLayout(t, pm)
+
+		other := pm.Clone()
+		other.ID = "migration-2"
+		_, err := newCoordinator(cluster.NewMemoryStore(), layout).Run(ctx, other)
+		require.ErrorContains(t, err, `migration "migration-2" doesn't match the key layout of migration "migration-1"`)
+
+		started := pm.Clone()
+		started.Status = cluster.PartitionMigrationStatusMigrating
+		_, err = newCoordinator(cluster.NewMemoryStore(), layout).Run(ctx, started)
+		require.ErrorContains(t, err, `status must be "new", got "migrating"`)
+
+		ackKeyPrefix := pm.Clone()
+		ackKeyPrefix.AckKeyPrefix = "/elsewhere"
+		_, err = newCoordinator(cluster.NewMemoryStore(), layout).Run(ctx, ackKeyPrefix)
+		require.ErrorContains(t, err, `ack key prefix "/elsewhere" doesn't match the key layout`)
+
+		jobID := pm.Clone()
+		jobID.Jobs[0].JobID = "../job"
+		_, err = newCoordinator(cluster.NewMemoryStore(), layout).Run(ctx, jobID)
+		require.ErrorIs(t, err, cluster.ErrInvalidKey)
+	})
+}
+
+var errCrash = errors.New("crash")
+
+// crashingStore fails every write after the given number of writes, simulating a crash of its user.
+// A negative number of writes never fails.
+type crashingStore struct {
+	cluster.Store
+	writesLeft int64
+	writes     atomic.Int64
+}
+
+func (s *crashingStore) write() error {
+	if n := s.writes.Add(1); s.writesLeft >= 0 && n > s.writesLeft {
+		return errCrash
+	}
+	return nil
+}
+
+func (s *crashingStore) Put(ctx context.Context, key string, value []byte) (int64, error) {
+	if err := s.write(); err != nil {
+		return 0, err
+	}
+	return s.Store.Put(ctx, key, value)
+}
+
+func (s *crashingStore) CompareAndSwap(ctx context.Context, key string, revision int64, value []byte) (int64, error) {
+	if err := s.write(); err != nil {
+		return 0, err
+	}
+	return s.Store.CompareAndSwap(ctx, key, revision, value)
+}
+
+func (s *crashingStore) Delete(ctx context.Context, key string) (int64, error) {
+	if err := s.write(); err != nil {
+		return 0, err
+	}
+	return s.Store.Delete(ctx, key)
+}
+
+// startAgents runs the agents of the nodes of a migration until the context is done: the processor nodes
+// it moves partitions between, named node-<index>, the gateway nodes, named gw-<index>, and the source router nodes.
+func startAgents(ctx context.Context, t *testing.T, store cluster.Store, pm *cluster.PartitionMigration, gatewayNodes []int, srcRouterNodes []string) {
+	t.Helper()
+	var agents []*cluster.Agent
+	newAgent := func(index int, name string, opt ...func(a *cluster.Agent)) {
+		agent, err := cluster.NewAgent(store, "/migrations", index, name, opt...)
+		require.NoError(t, err)
+		agents = append(agents, agent)
+	}
+	move := func(context.Context, *cluster.PartitionMigrationJob) error { return nil }
+	for _, node := range lo.Uniq(append(pm.SourceNodes(), pm.TargetNodes()...)) {
+		newAgent(node, fmt.Sprintf("node-%d", node), cluster.WithAgentPartitionsMovedOut(move), cluster.WithAgentPartitionsMovedIn(move))
+	}
+	for _, node := range gatewayNodes {
+		newAgent(node, fmt.Sprintf("gw-%d", node), cluster.WithAgentReloadGateway(func(context.Context, *cluster.ReloadGatewayCommand) error {
+			return nil
+		}))
+	}
+	for _, name := range srcRouterNodes {
+		newAgent(0, name, cluster.WithAgentReloadSrcRouter(func(context.Context, *cluster.ReloadSrcRouterCommand) error {
+			return nil
+		}))
+	}
+
+	done := runAgents(ctx, agents)
+	t.Cleanup(func() {
+		for range agents {
+			require.ErrorIs(t, <-done, ctx.Err(), "agents run until the context is done")
+		}
+	})
+}
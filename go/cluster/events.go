@@ -0,0 +1,218 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+var ErrInvalidEvents = errors.New("invalid migration events")
+
+// PartitionMigrationEventType is the type of a PartitionMigrationEvent.
+type PartitionMigrationEventType string
+
+const (
+	PartitionMigrationEventTypeStatusChanged    PartitionMigrationEventType = "status-changed"     // the migration moved to a new status
+	PartitionMigrationEventTypeJobStatusChanged PartitionMigrationEventType = "job-status-changed" // a migration job moved to a new status
+	PartitionMigrationEventTypeAckReceived      PartitionMigrationEventType = "ack-received"       // a node acknowledged a command
+	PartitionMigrationEventTypeCommandIssued    PartitionMigrationEventType = "command-issued"     // the coordinator issued a command to nodes
+	PartitionMigrationEventTypeFailure          PartitionMigrationEventType = "failure"            // an actor failed, without changing the migration
+)
+
+// PartitionMigrationCommand is a command acknowledged by nodes during a migration.
+type PartitionMigrationCommand string
+
+const (
+	PartitionMigrationCommandMigration       PartitionMigrationCommand = "migration"        // the migration itself, issued when it is created
+	PartitionMigrationCommandReloadGateway   PartitionMigrationCommand = "reload-gw"        // see ReloadGatewayCommand
+	PartitionMigrationCommandReloadSrcRouter PartitionMigrationCommand = "reload-srcrouter" // see ReloadSrcRouterCommand
+)
+
+// PartitionMigrationEvent is an entry of the append-only audit log of a migration, keeping the history that
+// PartitionMigration doesn't. The first event of a migration is its status change to new, carrying the migration.
+type PartitionMigrationEvent struct {
+	Type        PartitionMigrationEventType `json:"type"`        // type of the event
+	MigrationID string                      `json:"migrationId"` // identifier of the migration
+	Time        time.Time                   `json:"time"`        // time when the event occurred
+	Actor       string                      `json:"actor"`       // name of the coordinator or node causing the event, e.g. the node acknowledging a command
+
+	Status    PartitionMigrationStatus    `json:"status,omitempty"`    // new status of the migration, for status-changed events
+	Migration *PartitionMigration         `json:"migration,omitempty"` // migration created, for the status-changed event to new
+	JobID     string                      `json:"jobId,omitempty"`     // identifier of the job, for job-status-changed events
+	JobStatus PartitionMigrationJobStatus `json:"jobStatus,omitempty"` // new status of the job, for job-status-changed events
+	Command   PartitionMigrationCommand   `json:"command,omitempty"`   // command issued or acknowledged, for command-issued and ack-received events
+	Nodes     []string                    `json:"nodes,omitempty"`     // names of the nodes expected to acknowledge the command, for command-issued events
+	Error     string                      `json:"error,omitempty"`     // error message, for failure events
+}
+
+var (
+	migrationStatusOrder = []PartitionMigrationStatus{
+		PartitionMigrationStatusNew,
+		PartitionMigrationStatusReloadingGW,
+		PartitionMigrationStatusReloadingSrcRouter,
+		PartitionMigrationStatusMigrating,
+		PartitionMigrationStatusCompleted,
+	}
+	migrationJobStatusOrder = []PartitionMigrationJobStatus{
+		"", // not started
+		PartitionMigrationJobStatusNew,
+		PartitionMigrationJobStatusMoved,
+		PartitionMigrationJobStatusCompleted,
+	}
+	// commandStatuses are the statuses of the migration in which commands are issued
+	commandStatuses = map[PartitionMigrationCommand]PartitionMigrationStatus{
+		PartitionMigrationCommandReloadGateway:   PartitionMigrationStatusReloadingGW,
+		PartitionMigrationCommandReloadSrcRouter: PartitionMigrationStatusReloadingSrcRouter,
+	}
+	// statusCommands are the commands to acknowledge before the migration leaves a status
+	statusCommands = map[PartitionMigrationStatus]PartitionMigrationCommand{
+		PartitionMigrationStatusNew:                PartitionMigrationCommandMigration,
+		PartitionMigrationStatusReloadingGW:        PartitionMigrationCommandReloadGateway,
+		PartitionMigrationStatusReloadingSrcRouter: PartitionMigrationCommandReloadSrcRouter,
+	}
+)
+
+// ReducePartitionMigrationEvents rebuilds the information about a migration from its events, in order. It returns
+// an error wrapping ErrInvalidEvents if the events are not consistent, see ValidatePartitionMigrationEvents.
+//
+// Jobs start when they move to status new, failures don't change the migration. Acks are delivered at least once,
+// so a node may acknowledge a command more than once.
+func ReducePartitionMigrationEvents(events []*PartitionMigrationEvent) (*PartitionMigrationInfo, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%w: no events", ErrInvalidEvents)
+	}
+	first := events[0]
+	if first.Type != PartitionMigrationEventTypeStatusChanged || first.Status != PartitionMigrationStatusNew || first.Migration == nil {
+		return nil, fmt.Errorf("%w %q: the first event must be the status change to %s carrying the migration", ErrInvalidEvents, first.MigrationID, PartitionMigrationStatusNew)
+	}
+	if first.Migration.ID != first.MigrationID || first.Migration.Status != PartitionMigrationStatusNew {
+		return nil, fmt.Errorf("%w %q: the first event carries migration %q with status %q", ErrInvalidEvents, first.MigrationID, first.Migration.ID, first.Migration.Status)
+	}
+	var info PartitionMigrationInfo
+	info.FromPartitionMigration(*first.Migration, nil)
+	jobs := make(map[string]*PartitionMigrationJob, len(info.Jobs))
+	for _, job := range info.Jobs {
+		jobs[job.JobID] = job
+	}
+	// nodes expected to acknowledge every issued command, nil if they are not named
+	issued := map[PartitionMigrationCommand][]string{PartitionMigrationCommandMigration: nil}
+	acks := make(map[PartitionMigrationCommand]map[string]bool)
+	// the names of the source and target nodes acknowledging the migration are not known, only their number
+	migrationAcks := len(lo.Uniq(append(first.Migration.SourceNodes(), first.Migration.TargetNodes()...)))
+
+	for i, e := range events {
+		invalid := func(format string, a ...any) error {
+			return fmt.Errorf("%w %q: event %d (%s): %s", ErrInvalidEvents, info.ID, i, e.Type, fmt.Sprintf(format, a...))
+		}
+		switch {
+		case e.MigrationID != info.ID:
+			return nil, invalid("belongs to migration %q", e.MigrationID)
+		case e.Time.IsZero():
+			return nil, invalid("missing time")
+		case i > 0 && e.Time.Before(events[i-1].Time):
+			return nil, invalid("time %s is before the previous event", e.Time.Format(time.RFC3339Nano))
+		case e.Actor == "":
+			return nil, invalid("missing actor")
+		}
+		if i == 0 {
+			continue
+		}
+
+		switch e.Type {
+		case PartitionMigrationEventTypeStatusChanged:
+			next := slices.Index(migrationStatusOrder, info.Status) + 1
+			if next >= len(migrationStatusOrder) || e.Status != migrationStatusOrder[next] {
+				return nil, invalid("status changed from %q to %q", info.Status, e.Status)
+			}
+			if command, ok := statusCommands[info.Status]; ok {
+				nodes, ok := issued[command]
+				if !ok {
+					return nil, invalid("migration left %s before command %q was issued", info.Status, command)
+				}
+				if command == PartitionMigrationCommandMigration && len(acks[command]) < migrationAcks {
+					return nil, invalid("migration left %s with %d acks of command %q out of %d", info.Status, len(acks[command]), command, migrationAcks)
+				}
+				for _, node := range nodes {
+					if !acks[command][node] {
+						return nil, invalid("migration left %s before node %q acknowledged command %q", info.Status, node, command)
+					}
+				}
+			}
+			if e.Status == PartitionMigrationStatusCompleted {
+				for _, job := range info.Jobs {
+					if job.Status != PartitionMigrationJobStatusCompleted {
+						return nil, invalid("migration completed before job %q", job.JobID)
+					}
+				}
+			}
+			info.PreviousStatus, info.Status = info.Status, e.Status
+
+		case PartitionMigrationEventTypeJobStatusChanged:
+			job, ok := jobs[e.JobID]
+			if !ok {
+				return nil, invalid("unknown job %q", e.JobID)
+			}
+			if info.Status != PartitionMigrationStatusMigrating {
+				return nil, invalid("job %q changed while the migration is %s", e.JobID, info.Status)
+			}
+			next := slices.Index(migrationJobStatusOrder, job.Status) + 1
+			if next >= len(migrationJobStatusOrder) || e.JobStatus != migrationJobStatusOrder[next] {
+				return nil, invalid("job %q status changed from %q to %q", e.JobID, job.Status, e.JobStatus)
+			}
+			if e.JobStatus == PartitionMigrationJobStatusNew {
+				job.StartTime = e.Time
+			}
+			job.Status = e.JobStatus
+
+		case PartitionMigrationEventTypeCommandIssued:
+			status, ok := commandStatuses[e.Command]
+			if !ok {
+				return nil, invalid("command %q can't be issued", e.Command)
+			}
+			if info.Status != status {
+				return nil, invalid("command %q issued while the migration is %s", e.Command, info.Status)
+			}
+			if _, ok := issued[e.Command]; ok {
+				return nil, invalid("command %q issued more than once", e.Command)
+			}
+			issued[e.Command] = e.Nodes
+
+		case PartitionMigrationEventTypeAckReceived:
+			nodes, ok := issued[e.Command]
+			if !ok {
+				return nil, invalid("ack of command %q not issued", e.Command)
+			}
+			if e.Command != PartitionMigrationCommandMigration && !slices.Contains(nodes, e.Actor) {
+				return nil, invalid("command %q acknowledged by %q, which is not expected to", e.Command, e.Actor)
+			}
+			if acks[e.Command] == nil {
+				acks[e.Command] = make(map[string]bool)
+			}
+			acks[e.Command][e.Actor] = true
+
+		case PartitionMigrationEventTypeFailure:
+			if e.Error == "" {
+				return nil, invalid("missing error")
+			}
+
+		default:
+			return nil, invalid("unknown type")
+		}
+	}
+	return &info, nil
+}
+
+// ValidatePartitionMigrationEvents returns an error wrapping ErrInvalidEvents unless the events are a consistent history
+// of a single migration: starting with its status change to new, in chronological order, with actors, moving the
+// migration and its jobs one status at a time, completing the migration after all of its jobs, changing jobs only
+// while migrating, issuing every reload command once during its phase, acknowledging commands only after they are
+// issued and by the nodes they are issued to, and leaving every phase only after its command was acknowledged by
+// all of them. The migration itself, the command of status new, must be acknowledged by as many nodes as it has
+// source and target nodes, whose names are not recorded.
+func ValidatePartitionMigrationEvents(events []*PartitionMigrationEvent) error {
+	_, err := ReducePartitionMigrationEvents(events)
+	return err
+}
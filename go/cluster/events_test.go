@@ -0,0 +1,184 @@
+package cluster_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-go-kit/jsonrs"
+	"github.com/rudderlabs/rudder-schemas/go/cluster"
+	"github.com/rudderlabs/rudder-schemas/go/schemastest"
+)
+
+func TestPartitionMigrationEvents(t *testing.T) {
+	at := func(minutes int) time.Time { return schemastest.FixtureTime.Add(time.Duration(minutes) * time.Minute) }
+	event := func(typ cluster.PartitionMigrationEventType, minutes int, actor string) *cluster.PartitionMigrationEvent {
+		return &cluster.PartitionMigrationEvent{Type: typ, MigrationID: "migration-1", Time: at(minutes), Actor: actor}
+	}
+	status := func(minutes int, status cluster.PartitionMigrationStatus) *cluster.PartitionMigrationEvent {
+		e := event(cluster.PartitionMigrationEventTypeStatusChanged, minutes, "coordinator")
+		e.Status = status
+		if status == cluster.PartitionMigrationStatusNew {
+			e.Migration = schemastest.NewPartitionMigration()
+		}
+		return e
+	}
+	job := func(minutes int, jobID string, status cluster.PartitionMigrationJobStatus) *cluster.PartitionMigrationEvent {
+		e := event(cluster.PartitionMigrationEventTypeJobStatusChanged, minutes, "coordinator")
+		e.JobID, e.JobStatus = jobID, status
+		return e
+	}
+	command := func(minutes int, command cluster.PartitionMigrationCommand, nodes ...string) *cluster.PartitionMigrationEvent {
+		e := event(cluster.PartitionMigrationEventTypeCommandIssued, minutes, "coordinator")
+		e.Command, e.Nodes = command, nodes
+		return e
+	}
+	ack := func(minutes int, command cluster.PartitionMigrationCommand, node string) *cluster.PartitionMigrationEvent {
+		e := event(cluster.PartitionMigrationEventTypeAckReceived, minutes, node)
+		e.Command = command
+		return e
+	}
+	failure := func(minutes int, actor, err string) *cluster.PartitionMigrationEvent {
+		e := event(cluster.PartitionMigrationEventTypeFailure, minutes, actor)
+		e.Error = err
+		return e
+	}
+	// history of the migration of schemastest.CompletedPartitionMigration
+	history := func() []*cluster.PartitionMigrationEvent {
+		return []*cluster.PartitionMigrationEvent{
+			status(0, cluster.PartitionMigrationStatusNew),
+			ack(0, cluster.PartitionMigrationCommandMigration, "node-0"),
+			ack(0, cluster.PartitionMigrationCommandMigration, "node-1"),
+			ack(0, cluster.PartitionMigrationCommandMigration, "node-2"),
+			status(0, cluster.PartitionMigrationStatusReloadingGW),
+			command(0, cluster.PartitionMigrationCommandReloadGateway, "gw-0"),
+			failure(0, "gw-0", "reloading: timeout"),
+			ack(0, cluster.PartitionMigrationCommandReloadGateway, "gw-0"),
+			status(0, cluster.PartitionMigrationStatusReloadingSrcRouter),
+			command(0, cluster.PartitionMigrationCommandReloadSrcRouter, "srcrouter-0"),
+			ack(0, cluster.PartitionMigrationCommandReloadSrcRouter, "srcrouter-0"),
+			status(0, cluster.PartitionMigrationStatusMigrating),
+			job(1, "job-1", cluster.PartitionMigrationJobStatusNew),
+			job(2, "job-2", cluster.PartitionMigrationJobStatusNew),
+			job(2, "job-1", cluster.PartitionMigrationJobStatusMoved),
+			job(3, "job-3", cluster.PartitionMigrationJobStatusNew),
+			job(3, "job-1", cluster.PartitionMigrationJobStatusCompleted),
+			job(4, "job-2", cluster.PartitionMigrationJobStatusMoved),
+			job(4, "job-3", cluster.PartitionMigrationJobStatusMoved),
+			job(5, "job-2", cluster.PartitionMigrationJobStatusCompleted),
+			job(5, "job-3", cluster.PartitionMigrationJobStatusCompleted),
+			status(6, cluster.PartitionMigrationStatusCompleted),
+		}
+	}
+
+	t.Run("reduce", func(t *testing.T) {
+		events := history()
+		info, err := cluster.ReducePartitionMigrationEvents(events)
+		require.NoError(t, err)
+		require.Equal(t, schemastest.CompletedPartitionMigration(), info)
+
+		info, err = cluster.ReducePartitionMigrationEvents(events[:16])
+		require.NoError(t, err)
+		require.Equal(t, cluster.PartitionMigrationStatusMigrating, info.Status)
+		require.Equal(t, cluster.PartitionMigrationStatusReloadingSrcRouter, info.PreviousStatus)
+		require.Equal(t, []cluster.PartitionMigrationJobStatus{
+			cluster.PartitionMigrationJobStatusMoved,
+			cluster.PartitionMigrationJobStatusNew,
+			cluster.PartitionMigrationJobStatusNew,
+		}, []cluster.PartitionMigrationJobStatus{info.Jobs[0].Status, info.Jobs[1].Status, info.Jobs[2].Status})
+		require.Equal(t, at(3), info.Jobs[2].StartTime)
+
+		info, err = cluster.ReducePartitionMigrationEvents(events[:1])
+		require.NoError(t, err)
+		var expected cluster.PartitionMigrationInfo
+		expected.FromPartitionMigration(*schemastest.NewPartitionMigration(), nil)
+		require.Equal(t, &expected, info)
+		require.NotSame(t, events[0].Migration.Jobs[0], info.Jobs[0], "jobs are cloned")
+
+		t.Run("nothing to acknowledge", func(t *testing.T) {
+			empty := status(0, cluster.PartitionMigrationStatusNew)
+			empty.Migration.Jobs = nil
+			info, err := cluster.ReducePartitionMigrationEvents([]*cluster.PartitionMigrationEvent{
+				empty,
+				status(0, cluster.PartitionMigrationStatusReloadingGW),
+				command(0, cluster.PartitionMigrationCommandReloadGateway),
+				status(0, cluster.PartitionMigrationStatusReloadingSrcRouter),
+				command(0, cluster.PartitionMigrationCommandReloadSrcRouter),
+				status(0, cluster.PartitionMigrationStatusMigrating),
+				status(0, cluster.PartitionMigrationStatusCompleted),
+			})
+			require.NoError(t, err)
+			require.Equal(t, cluster.PartitionMigrationStatusCompleted, info.Status)
+			require.Empty(t, info.Jobs)
+		})
+
+		t.Run("acks delivered more than once", func(t *testing.T) {
+			redelivered := slices.Insert(history(), 5,
+				ack(0, cluster.PartitionMigrationCommandMigration, "node-0"),
+				ack(0, cluster.PartitionMigrationCommandMigration, "node-2"),
+			)
+			info, err := cluster.ReducePartitionMigrationEvents(redelivered)
+			require.NoError(t, err)
+			require.Equal(t, schemastest.CompletedPartitionMigration(), info)
+		})
+	})
+
+	t.Run("json", func(t *testing.T) {
+		data, err := jsonrs.Marshal(history())
+		require.NoError(t, err)
+		var events []*cluster.PartitionMigrationEvent
+		require.NoError(t, jsonrs.Unmarshal(data, &events))
+		require.Equal(t, history(), events)
+		require.NoError(t, cluster.ValidatePartitionMigrationEvents(events))
+
+		data, err = jsonrs.Marshal(ack(0, cluster.PartitionMigrationCommandMigration, "node-0"))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"type":"ack-received","migrationId":"migration-1","time":"2024-08-01T02:30:50.0000002Z","actor":"node-0","command":"migration"}`, string(data))
+	})
+
+	t.Run("invalid events", func(t *testing.T) {
+		require.ErrorIs(t, cluster.ValidatePartitionMigrationEvents(nil), cluster.ErrInvalidEvents)
+
+		// replace the events of a valid history from the given index
+		replace := func(i int, events ...*cluster.PartitionMigrationEvent) []*cluster.PartitionMigrationEvent {
+			h := history()
+			return append(h[:i], append(events, h[i+len(events):]...)...)
+		}
+		other := status(0, cluster.PartitionMigrationStatusReloadingGW)
+		other.MigrationID = "migration-2"
+		anonymous := ack(0, cluster.PartitionMigrationCommandMigration, "")
+		untimed := ack(0, cluster.PartitionMigrationCommandMigration, "node-0")
+		untimed.Time = time.Time{}
+		timeout := failure(0, "gw-0", "reloading: timeout")
+		for expected, events := range map[string][]*cluster.PartitionMigrationEvent{
+			"the first event must be the status change to new carrying the migration":                              history()[1:],
+			`belongs to migration "migration-2"`:                                                                   replace(4, other),
+			"missing actor":                                                                                        replace(1, anonymous),
+			"missing time":                                                                                         replace(1, untimed),
+			"is before the previous event":                                                                         replace(12, job(5, "job-1", cluster.PartitionMigrationJobStatusNew)),
+			`status changed from "new" to "migrating"`:                                                             replace(4, status(0, cluster.PartitionMigrationStatusMigrating)),
+			`status changed from "reloading-gw" to "reloading-gw"`:                                                 replace(8, status(0, cluster.PartitionMigrationStatusReloadingGW)),
+			`migration completed before job "job-3"`:                                                               replace(20, status(5, cluster.PartitionMigrationStatusCompleted)),
+			`unknown job "job-4"`:                                                                                  replace(12, job(1, "job-4", cluster.PartitionMigrationJobStatusNew)),
+			`job "job-1" changed while the migration is reloading-srcrouter`:                                       replace(11, job(0, "job-1", cluster.PartitionMigrationJobStatusNew)),
+			`job "job-1" status changed from "" to "moved"`:                                                        replace(12, job(1, "job-1", cluster.PartitionMigrationJobStatusMoved)),
+			`command "reload-srcrouter" issued while the migration is reloading-gw`:                                replace(5, command(0, cluster.PartitionMigrationCommandReloadSrcRouter)),
+			`command "reload-gw" issued more than once`:                                                            replace(6, command(0, cluster.PartitionMigrationCommandReloadGateway)),
+			`command "migration" can't be issued`:                                                                  replace(5, command(0, cluster.PartitionMigrationCommandMigration)),
+			`ack of command "reload-gw" not issued`:                                                                replace(5, ack(0, cluster.PartitionMigrationCommandReloadGateway, "gw-0")),
+			`migration left new with 2 acks of command "migration" out of 3`:                                       replace(1, timeout),
+			`migration left reloading-gw before command "reload-gw" was issued`:                                    replace(5, timeout, timeout, timeout),
+			`migration left reloading-gw before node "gw-0" acknowledged command "reload-gw"`:                      replace(7, timeout),
+			`migration left reloading-srcrouter before node "srcrouter-0" acknowledged command "reload-srcrouter"`: replace(10, timeout),
+			`command "reload-gw" acknowledged by "gw-1", which is not expected to`:                                 replace(7, ack(0, cluster.PartitionMigrationCommandReloadGateway, "gw-1")),
+			"missing error": replace(6, failure(0, "gw-0", "")),
+			"unknown type":  replace(6, event("restarted", 0, "gw-0")),
+		} {
+			_, err := cluster.ReducePartitionMigrationEvents(events)
+			require.ErrorIs(t, err, cluster.ErrInvalidEvents, expected)
+			require.ErrorContains(t, err, expected)
+		}
+	})
+}
@@ -0,0 +1,232 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+var ErrInvalidKey = errors.New("invalid key")
+
+// KeyKind is the kind of document stored under a key of a KeyLayout.
+type KeyKind string
+
+const (
+	KeyKindMigration          KeyKind = "migration"            // the PartitionMigration
+	KeyKindMigrationInfo      KeyKind = "migration-info"       // the PartitionMigrationInfo
+	KeyKindMigrationAck       KeyKind = "migration-ack"        // a PartitionMigrationAck
+	KeyKindJob                KeyKind = "job"                  // a PartitionMigrationJob
+	KeyKindReloadGateway      KeyKind = "reload-gw"            // the ReloadGatewayCommand
+	KeyKindReloadGatewayAck   KeyKind = "reload-gw-ack"        // a ReloadGatewayAck
+	KeyKindReloadSrcRouter    KeyKind = "reload-srcrouter"     // the ReloadSrcRouterCommand
+	KeyKindReloadSrcRouterAck KeyKind = "reload-srcrouter-ack" // a ReloadSrcRouterAck
+)
+
+const (
+	keySegmentMigration       = "migration"
+	keySegmentMigrationInfo   = "info"
+	keySegmentJobs            = "jobs"
+	keySegmentAck             = "ack"
+	keySegmentReloadGateway   = "reload-gw"
+	keySegmentReloadSrcRouter = "reload-srcrouter"
+)
+
+// KeyLayout is the canonical layout of the keys of a migration, under a root prefix:
+//
+//	<root>/<migrationID>/migration                        PartitionMigration
+//	<root>/<migrationID>/info                             PartitionMigrationInfo
+//	<root>/<migrationID>/ack/<nodeName>                   PartitionMigrationAck
+//	<root>/<migrationID>/jobs/<jobID>                     PartitionMigrationJob
+//	<root>/<migrationID>/reload-gw                        ReloadGatewayCommand
+//	<root>/<migrationID>/reload-gw/ack/<nodeName>         ReloadGatewayAck
+//	<root>/<migrationID>/reload-srcrouter                 ReloadSrcRouterCommand
+//	<root>/<migrationID>/reload-srcrouter/ack/<nodeName>  ReloadSrcRouterAck
+//
+// Migration IDs, job IDs and node names are single key segments: they can't be empty,
+// nor contain "/" or "..", so that keys never escape their prefix.
+type KeyLayout struct {
+	root        string
+	migrationID string
+}
+
+// NewKeyLayout returns the key layout of a migration. The root must be a clean path, e.g. "/migrations".
+func NewKeyLayout(root, migrationID string) (*KeyLayout, error) {
+	if err := validateKeyRoot(root); err != nil {
+		return nil, err
+	}
+	if err := validateKeySegment("migration ID", migrationID); err != nil {
+		return nil, err
+	}
+	return &KeyLayout{root: root, migrationID: migrationID}, nil
+}
+
+// Root returns the root prefix of the layout.
+func (l *KeyLayout) Root() string {
+	return l.root
+}
+
+// MigrationID returns the ID of the migration of the layout.
+func (l *KeyLayout) MigrationID() string {
+	return l.migrationID
+}
+
+// Prefix returns the prefix of all the keys of the migration, e.g. for watching them.
+func (l *KeyLayout) Prefix() string {
+	return path.Join(l.root, l.migrationID) + "/"
+}
+
+// MigrationKey returns the key of the PartitionMigration.
+func (l *KeyLayout) MigrationKey() string {
+	return path.Join(l.root, l.migrationID, keySegmentMigration)
+}
+
+// MigrationInfoKey returns the key of the PartitionMigrationInfo.
+func (l *KeyLayout) MigrationInfoKey() string {
+	return path.Join(l.root, l.migrationID, keySegmentMigrationInfo)
+}
+
+// MigrationAckKeyPrefix returns the AckKeyPrefix of the PartitionMigration.
+func (l *KeyLayout) MigrationAckKeyPrefix() string {
+	return path.Join(l.root, l.migrationID, keySegmentAck)
+}
+
+// MigrationAckKey returns the key of the PartitionMigrationAck of a node.
+func (l *KeyLayout) MigrationAckKey(nodeName string) (string, error) {
+	return ackKey(l.MigrationAckKeyPrefix(), nodeName)
+}
+
+// JobsPrefix returns the prefix of the keys of the PartitionMigrationJobs.
+func (l *KeyLayout) JobsPrefix() string {
+	return path.Join(l.root, l.migrationID, keySegmentJobs) + "/"
+}
+
+// JobKey returns the key of a PartitionMigrationJob.
+func (l *KeyLayout) JobKey(jobID string) (string, error) {
+	if err := validateKeySegment("job ID", jobID); err != nil {
+		return "", err
+	}
+	return path.Join(l.root, l.migrationID, keySegmentJobs, jobID), nil
+}
+
+// ReloadGatewayKey returns the key of the ReloadGatewayCommand.
+func (l *KeyLayout) ReloadGatewayKey() string {
+	return path.Join(l.root, l.migrationID, keySegmentReloadGateway)
+}
+
+// ReloadGatewayAckKeyPrefix returns the AckKeyPrefix of the ReloadGatewayCommand.
+func (l *KeyLayout) ReloadGatewayAckKeyPrefix() string {
+	return path.Join(l.ReloadGatewayKey(), keySegmentAck)
+}
+
+// ReloadGatewayAckKey returns the key of the ReloadGatewayAck of a node.
+func (l *KeyLayout) ReloadGatewayAckKey(nodeName string) (string, error) {
+	return ackKey(l.ReloadGatewayAckKeyPrefix(), nodeName)
+}
+
+// ReloadSrcRouterKey returns the key of the ReloadSrcRouterCommand.
+func (l *KeyLayout) ReloadSrcRouterKey() string {
+	return path.Join(l.root, l.migrationID, keySegmentReloadSrcRouter)
+}
+
+// ReloadSrcRouterAckKeyPrefix returns the AckKeyPrefix of the ReloadSrcRouterCommand.
+func (l *KeyLayout) ReloadSrcRouterAckKeyPrefix() string {
+	return path.Join(l.ReloadSrcRouterKey(), keySegmentAck)
+}
+
+// ReloadSrcRouterAckKey returns the key of the ReloadSrcRouterAck of a node.
+func (l *KeyLayout) ReloadSrcRouterAckKey(nodeName string) (string, error) {
+	return ackKey(l.ReloadSrcRouterAckKeyPrefix(), nodeName)
+}
+
+// ReloadGatewayCommand returns a command for reloading the given gateway nodes, acknowledged under the layout.
+func (l *KeyLayout) ReloadGatewayCommand(nodes []int) *ReloadGatewayCommand {
+	return &ReloadGatewayCommand{Nodes: nodes, AckKeyPrefix: l.ReloadGatewayAckKeyPrefix()}
+}
+
+// ReloadSrcRouterCommand returns a command for reloading the source routers, acknowledged under the layout.
+func (l *KeyLayout) ReloadSrcRouterCommand() *ReloadSrcRouterCommand {
+	return &ReloadSrcRouterCommand{AckKeyPrefix: l.ReloadSrcRouterAckKeyPrefix()}
+}
+
+// ParseKey parses a key of the layout, which must belong to its migration.
+func (l *KeyLayout) ParseKey(key string) (*ParsedKey, error) {
+	parsed, err := ParseKey(l.root, key)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.MigrationID != l.migrationID {
+		return nil, fmt.Errorf("%w: %q belongs to migration %q", ErrInvalidKey, key, parsed.MigrationID)
+	}
+	return parsed, nil
+}
+
+// ParsedKey is a key of a KeyLayout, along with the identifiers it contains.
+type ParsedKey struct {
+	Kind        KeyKind
+	MigrationID string
+	JobID       string // for KeyKindJob
+	NodeName    string // for ack kinds
+}
+
+// ParseKey parses a key of the KeyLayout of any migration under the given root.
+func ParseKey(root, key string) (*ParsedKey, error) {
+	if err := validateKeyRoot(root); err != nil {
+		return nil, err
+	}
+	rest, ok := strings.CutPrefix(key, strings.TrimSuffix(root, "/")+"/")
+	if !ok {
+		return nil, fmt.Errorf("%w: %q is not under %q", ErrInvalidKey, key, root)
+	}
+	segments := strings.Split(rest, "/")
+	for _, segment := range segments {
+		if err := validateKeySegment("key segment", segment); err != nil {
+			return nil, fmt.Errorf("parsing %q: %w", key, err)
+		}
+	}
+
+	parsed := &ParsedKey{MigrationID: segments[0]}
+	switch s := segments[1:]; {
+	case len(s) == 1 && s[0] == keySegmentMigration:
+		parsed.Kind = KeyKindMigration
+	case len(s) == 1 && s[0] == keySegmentMigrationInfo:
+		parsed.Kind = KeyKindMigrationInfo
+	case len(s) == 2 && s[0] == keySegmentAck:
+		parsed.Kind, parsed.NodeName = KeyKindMigrationAck, s[1]
+	case len(s) == 2 && s[0] == keySegmentJobs:
+		parsed.Kind, parsed.JobID = KeyKindJob, s[1]
+	case len(s) == 1 && s[0] == keySegmentReloadGateway:
+		parsed.Kind = KeyKindReloadGateway
+	case len(s) == 3 && s[0] == keySegmentReloadGateway && s[1] == keySegmentAck:
+		parsed.Kind, parsed.NodeName = KeyKindReloadGatewayAck, s[2]
+	case len(s) == 1 && s[0] == keySegmentReloadSrcRouter:
+		parsed.Kind = KeyKindReloadSrcRouter
+	case len(s) == 3 && s[0] == keySegmentReloadSrcRouter && s[1] == keySegmentAck:
+		parsed.Kind, parsed.NodeName = KeyKindReloadSrcRouterAck, s[2]
+	default:
+		return nil, fmt.Errorf("%w: %q is not part of the key layout", ErrInvalidKey, key)
+	}
+	return parsed, nil
+}
+
+func ackKey(prefix, nodeName string) (string, error) {
+	if err := validateKeySegment("node name", nodeName); err != nil {
+		return "", err
+	}
+	return path.Join(prefix, nodeName), nil
+}
+
+func validateKeyRoot(root string) error {
+	if root == "" || path.Clean(root) != root {
+		return fmt.Errorf("%w: root %q must be a clean path", ErrInvalidKey, root)
+	}
+	return nil
+}
+
+// validateKeySegment rejects identifiers that can't be used as a single key segment.
+func validateKeySegment(name, segment string) error {
+	if segment == "" || segment == "." || strings.Contains(segment, "/") || strings.Contains(segment, "..") {
+		return fmt.Errorf("%w: invalid %s %q", ErrInvalidKey, name, segment)
+	}
+	return nil
+}
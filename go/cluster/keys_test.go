@@ -0,0 +1,146 @@
+package cluster_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-schemas/go/cluster"
+)
+
+func TestKeyLayout(t *testing.T) {
+	l, err := cluster.NewKeyLayout("/migrations", "migration-1")
+	require.NoError(t, err)
+	require.Equal(t, "/migrations", l.Root())
+	require.Equal(t, "migration-1", l.MigrationID())
+
+	t.Run("keys", func(t *testing.T) {
+		require.Equal(t, "/migrations/migration-1/", l.Prefix())
+		require.Equal(t, "/migrations/migration-1/migration", l.MigrationKey())
+		require.Equal(t, "/migrations/migration-1/info", l.MigrationInfoKey())
+		require.Equal(t, "/migrations/migration-1/ack", l.MigrationAckKeyPrefix())
+		require.Equal(t, "/migrations/migration-1/jobs/", l.JobsPrefix())
+		require.Equal(t, "/migrations/migration-1/reload-gw", l.ReloadGatewayKey())
+		require.Equal(t, "/migrations/migration-1/reload-gw/ack", l.ReloadGatewayAckKeyPrefix())
+		require.Equal(t, "/migrations/migration-1/reload-srcrouter", l.ReloadSrcRouterKey())
+		require.Equal(t, "/migrations/migration-1/reload-srcrouter/ack", l.ReloadSrcRouterAckKeyPrefix())
+
+		key, err := l.MigrationAckKey("node-1")
+		require.NoError(t, err)
+		require.Equal(t, "/migrations/migration-1/ack/node-1", key)
+		key, err = l.JobKey("job-1")
+		require.NoError(t, err)
+		require.Equal(t, "/migrations/migration-1/jobs/job-1", key)
+		key, err = l.ReloadGatewayAckKey("gw-2")
+		require.NoError(t, err)
+		require.Equal(t, "/migrations/migration-1/reload-gw/ack/gw-2", key)
+		key, err = l.ReloadSrcRouterAckKey("srcrouter-0")
+		require.NoError(t, err)
+		require.Equal(t, "/migrations/migration-1/reload-srcrouter/ack/srcrouter-0", key)
+	})
+
+	t.Run("ack keys match the ack keys of the commands", func(t *testing.T) {
+		gw := l.ReloadGatewayCommand([]int{0, 1})
+		require.Equal(t, []int{0, 1}, gw.Nodes)
+		key, err := l.ReloadGatewayAckKey("gw-1")
+		require.NoError(t, err)
+		require.Equal(t, gw.AckKey("gw-1"), key)
+
+		sr := l.ReloadSrcRouterCommand()
+		key, err = l.ReloadSrcRouterAckKey("srcrouter-0")
+		require.NoError(t, err)
+		require.Equal(t, sr.AckKey("srcrouter-0"), key)
+
+		pm := cluster.PartitionMigration{ID: l.MigrationID(), AckKeyPrefix: l.MigrationAckKeyPrefix()}
+		key, err = l.MigrationAckKey("node-0")
+		require.NoError(t, err)
+		require.Equal(t, pm.AckKey("node-0"), key)
+	})
+
+	t.Run("invalid names", func(t *testing.T) {
+		for _, name := range []string{"", ".", "..", "../node-1", "node/1", "a..b"} {
+			_, err := l.MigrationAckKey(name)
+			require.ErrorIs(t, err, cluster.ErrInvalidKey, name)
+			_, err = l.ReloadGatewayAckKey(name)
+			require.ErrorIs(t, err, cluster.ErrInvalidKey, name)
+			_, err = l.ReloadSrcRouterAckKey(name)
+			require.ErrorIs(t, err, cluster.ErrInvalidKey, name)
+			_, err = l.JobKey(name)
+			require.ErrorIs(t, err, cluster.ErrInvalidKey, name)
+			_, err = cluster.NewKeyLayout("/migrations", name)
+			require.ErrorIs(t, err, cluster.ErrInvalidKey, name)
+		}
+		for _, root := range []string{"", "/migrations/", "/migrations/../x", "migrations//x"} {
+			_, err := cluster.NewKeyLayout(root, "migration-1")
+			require.ErrorIs(t, err, cluster.ErrInvalidKey, root)
+		}
+	})
+
+	t.Run("parse", func(t *testing.T) {
+		for key, expected := range map[string]cluster.ParsedKey{
+			l.MigrationKey():                                         {Kind: cluster.KeyKindMigration, MigrationID: "migration-1"},
+			l.MigrationInfoKey():                                     {Kind: cluster.KeyKindMigrationInfo, MigrationID: "migration-1"},
+			l.ReloadGatewayKey():                                     {Kind: cluster.KeyKindReloadGateway, MigrationID: "migration-1"},
+			l.ReloadSrcRouterKey():                                   {Kind: cluster.KeyKindReloadSrcRouter, MigrationID: "migration-1"},
+			"/migrations/migration-1/jobs/job-1":                     {Kind: cluster.KeyKindJob, MigrationID: "migration-1", JobID: "job-1"},
+			"/migrations/migration-1/ack/node-1":                     {Kind: cluster.KeyKindMigrationAck, MigrationID: "migration-1", NodeName: "node-1"},
+			"/migrations/migration-1/reload-gw/ack/gw-2":             {Kind: cluster.KeyKindReloadGatewayAck, MigrationID: "migration-1", NodeName: "gw-2"},
+			"/migrations/migration-1/reload-srcrouter/ack/srcrouter": {Kind: cluster.KeyKindReloadSrcRouterAck, MigrationID: "migration-1", NodeName: "srcrouter"},
+		} {
+			parsed, err := l.ParseKey(key)
+			require.NoError(t, err, key)
+			require.Equal(t, expected, *parsed, key)
+		}
+
+		t.Run("any migration", func(t *testing.T) {
+			parsed, err := cluster.ParseKey("/migrations", "/migrations/migration-2/jobs/job-1")
+			require.NoError(t, err)
+			require.Equal(t, cluster.ParsedKey{Kind: cluster.KeyKindJob, MigrationID: "migration-2", JobID: "job-1"}, *parsed)
+
+			_, err = l.ParseKey("/migrations/migration-2/jobs/job-1")
+			require.ErrorIs(t, err, cluster.ErrInvalidKey, "belongs to another migration")
+
+			parsed, err = cluster.ParseKey("/", "/migration-1/migration")
+			require.NoError(t, err)
+			require.Equal(t, cluster.ParsedKey{Kind: cluster.KeyKindMigration, MigrationID: "migration-1"}, *parsed)
+		})
+
+		t.Run("invalid keys", func(t *testing.T) {
+			for _, key := range []string{
+				"",
+				"/migrations",
+				"/migrations/",
+				"/other/migration-1/migration",
+				"/migrations-2/migration-1/migration",
+				"/migrations/migration-1",
+				"/migrations/migration-1/unknown",
+				"/migrations/migration-1/jobs",
+				"/migrations/migration-1/jobs/",
+				"/migrations/migration-1/jobs/job-1/extra",
+				"/migrations/migration-1/ack/../../other",
+				"/migrations/migration-1/ack/a..b",
+				"/migrations/migration-1/reload-gw/ack",
+				"/migrations/migration-1/reload-gw/acks/gw-1",
+				"/migrations//migration",
+			} {
+				_, err := cluster.ParseKey("/migrations", key)
+				require.ErrorIs(t, err, cluster.ErrInvalidKey, key)
+			}
+		})
+
+		t.Run("round trip", func(t *testing.T) {
+			for _, key := range []func() (string, error){
+				func() (string, error) { return l.MigrationAckKey("node-1") },
+				func() (string, error) { return l.JobKey("job-1") },
+				func() (string, error) { return l.ReloadGatewayAckKey("gw-1") },
+				func() (string, error) { return l.ReloadSrcRouterAckKey("sr-1") },
+			} {
+				k, err := key()
+				require.NoError(t, err)
+				parsed, err := l.ParseKey(k)
+				require.NoError(t, err)
+				require.Equal(t, "migration-1", parsed.MigrationID)
+			}
+		})
+	})
+}
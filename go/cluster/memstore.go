@@ -0,0 +1,200 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// MemoryStore is an in-memory Store, e.g. for testing coordinators and nodes without an external key-value store.
+// It keeps the whole history of modifications, so watches can start from any past revision.
+type MemoryStore struct {
+	mu       sync.Mutex
+	revision int64
+	values   map[string]KeyValue
+	history  []WatchEvent
+	watchers map[*memoryWatcher]struct{}
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		values:   make(map[string]KeyValue),
+		watchers: make(map[*memoryWatcher]struct{}),
+	}
+}
+
+// Revision returns the current revision of the store.
+func (s *MemoryStore) Revision() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.revision
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (KeyValue, error) {
+	if err := ctx.Err(); err != nil {
+		return KeyValue{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kv, ok := s.values[key]
+	if !ok {
+		return KeyValue{}, fmt.Errorf("getting %s: %w", key, ErrKeyNotFound)
+	}
+	return kv.clone(), nil
+}
+
+func (s *MemoryStore) Put(ctx context.Context, key string, value []byte) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.put(key, value), nil
+}
+
+func (s *MemoryStore) CompareAndSwap(ctx context.Context, key string, revision int64, value []byte) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current := s.values[key].Revision; current != revision {
+		return 0, fmt.Errorf("swapping %s at revision %d, last modified at %d: %w", key, revision, current, ErrRevisionConflict)
+	}
+	return s.put(key, value), nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[key]; !ok {
+		return 0, fmt.Errorf("deleting %s: %w", key, ErrKeyNotFound)
+	}
+	s.revision++
+	delete(s.values, key)
+	s.notify(WatchEvent{Type: WatchEventTypeDelete, KeyValue: KeyValue{Key: key, Revision: s.revision}})
+	return s.revision, nil
+}
+
+func (s *MemoryStore) List(ctx context.Context, prefix string) ([]KeyValue, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var kvs []KeyValue
+	for key, kv := range s.values {
+		if strings.HasPrefix(key, prefix) {
+			kvs = append(kvs, kv.clone())
+		}
+	}
+	slices.SortFunc(kvs, func(a, b KeyValue) int { return strings.Compare(a.Key, b.Key) })
+	return kvs, s.revision, nil
+}
+
+func (s *MemoryStore) Watch(ctx context.Context, prefix string, revision int64) (<-chan WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if revision < 0 {
+		return nil, fmt.Errorf("watching %s: invalid revision %d", prefix, revision)
+	}
+	s.mu.Lock()
+	if revision == 0 {
+		revision = s.revision + 1
+	}
+	w := &memoryWatcher{prefix: prefix, revision: revision, notify: make(chan struct{}, 1)}
+	for _, event := range s.history[min(int(revision-1), len(s.history)):] {
+		w.push(event)
+	}
+	s.watchers[w] = struct{}{}
+	s.mu.Unlock()
+
+	ch := make(chan WatchEvent)
+	go func() {
+		defer close(ch)
+		defer func() {
+			s.mu.Lock()
+			delete(s.watchers, w)
+			s.mu.Unlock()
+		}()
+		for {
+			for _, event := range w.pop() {
+				event.KeyValue = event.clone()
+				select {
+				case ch <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-w.notify:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// put must be called with the lock held.
+func (s *MemoryStore) put(key string, value []byte) int64 {
+	s.revision++
+	kv := KeyValue{Key: key, Value: bytes.Clone(value), Revision: s.revision}
+	s.values[key] = kv
+	s.notify(WatchEvent{Type: WatchEventTypePut, KeyValue: kv})
+	return s.revision
+}
+
+// notify must be called with the lock held, after increasing the revision.
+func (s *MemoryStore) notify(event WatchEvent) {
+	s.history = append(s.history, event)
+	for w := range s.watchers {
+		w.push(event)
+	}
+}
+
+// memoryWatcher buffers the events of a watch, so that slow consumers never block modifications of the store.
+type memoryWatcher struct {
+	prefix   string
+	revision int64 // first revision to deliver
+
+	mu      sync.Mutex
+	pending []WatchEvent
+	notify  chan struct{}
+}
+
+func (w *memoryWatcher) push(event WatchEvent) {
+	if event.Revision < w.revision || !strings.HasPrefix(event.Key, w.prefix) {
+		return
+	}
+	w.mu.Lock()
+	w.pending = append(w.pending, event)
+	w.mu.Unlock()
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (w *memoryWatcher) pop() []WatchEvent {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	events := w.pending
+	w.pending = nil
+	return events
+}
+
+func (kv KeyValue) clone() KeyValue {
+	kv.Value = bytes.Clone(kv.Value)
+	return kv
+}
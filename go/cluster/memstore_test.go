@@ -0,0 +1,199 @@
+package cluster_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-schemas/go/cluster"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("get put delete", func(t *testing.T) {
+		s := cluster.NewMemoryStore()
+		_, err := s.Get(ctx, "/a")
+		require.ErrorIs(t, err, cluster.ErrKeyNotFound)
+
+		rev, err := s.Put(ctx, "/a", []byte("1"))
+		require.NoError(t, err)
+		require.EqualValues(t, 1, rev)
+		rev, err = s.Put(ctx, "/b", []byte("2"))
+		require.NoError(t, err)
+		require.EqualValues(t, 2, rev)
+
+		kv, err := s.Get(ctx, "/a")
+		require.NoError(t, err)
+		require.Equal(t, cluster.KeyValue{Key: "/a", Value: []byte("1"), Revision: 1}, kv)
+
+		kv.Value[0] = 'x'
+		kv, err = s.Get(ctx, "/a")
+		require.NoError(t, err)
+		require.Equal(t, []byte("1"), kv.Value, "values are not shared")
+
+		rev, err = s.Delete(ctx, "/a")
+		require.NoError(t, err)
+		require.EqualValues(t, 3, rev)
+		require.EqualValues(t, 3, s.Revision())
+		_, err = s.Get(ctx, "/a")
+		require.ErrorIs(t, err, cluster.ErrKeyNotFound)
+		_, err = s.Delete(ctx, "/a")
+		require.ErrorIs(t, err, cluster.ErrKeyNotFound)
+	})
+
+	t.Run("compare and swap", func(t *testing.T) {
+		s := cluster.NewMemoryStore()
+		rev, err := s.CompareAndSwap(ctx, "/a", 0, []byte("1"))
+		require.NoError(t, err, "revision 0 creates the key")
+
+		_, err = s.CompareAndSwap(ctx, "/a", 0, []byte("2"))
+		require.ErrorIs(t, err, cluster.ErrRevisionConflict, "the key already exists")
+
+		_, err = s.Put(ctx, "/a", []byte("2"))
+		require.NoError(t, err)
+		_, err = s.CompareAndSwap(ctx, "/a", rev, []byte("3"))
+		require.ErrorIs(t, err, cluster.ErrRevisionConflict, "the key was modified concurrently")
+
+		kv, err := s.Get(ctx, "/a")
+		require.NoError(t, err)
+		rev, err = s.CompareAndSwap(ctx, "/a", kv.Revision, []byte("3"))
+		require.NoError(t, err)
+		kv, err = s.Get(ctx, "/a")
+		require.NoError(t, err)
+		require.Equal(t, cluster.KeyValue{Key: "/a", Value: []byte("3"), Revision: rev}, kv)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		s := cluster.NewMemoryStore()
+		for _, key := range []string{"/m/2", "/m/1", "/n/1", "/m"} {
+			_, err := s.Put(ctx, key, []byte(key))
+			require.NoError(t, err)
+		}
+		kvs, rev, err := s.List(ctx, "/m/")
+		require.NoError(t, err)
+		require.EqualValues(t, 4, rev)
+		require.Equal(t, []cluster.KeyValue{
+			{Key: "/m/1", Value: []byte("/m/1"), Revision: 2},
+			{Key: "/m/2", Value: []byte("/m/2"), Revision: 1},
+		}, kvs)
+
+		kvs, _, err = s.List(ctx, "/x/")
+		require.NoError(t, err)
+		require.Empty(t, kvs)
+	})
+
+	t.Run("watch", func(t *testing.T) {
+		t.Run("from the next modification", func(t *testing.T) {
+			s := cluster.NewMemoryStore()
+			_, err := s.Put(ctx, "/m/before", []byte("0"))
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			events, err := s.Watch(ctx, "/m/", 0)
+			require.NoError(t, err)
+
+			_, err = s.Put(ctx, "/m/a", []byte("1"))
+			require.NoError(t, err)
+			_, err = s.Put(ctx, "/n/a", []byte("ignored"))
+			require.NoError(t, err)
+			_, err = s.Delete(ctx, "/m/a")
+			require.NoError(t, err)
+
+			require.Equal(t, cluster.WatchEvent{Type: cluster.WatchEventTypePut, KeyValue: cluster.KeyValue{Key: "/m/a", Value: []byte("1"), Revision: 2}}, receive(t, events))
+			require.Equal(t, cluster.WatchEvent{Type: cluster.WatchEventTypeDelete, KeyValue: cluster.KeyValue{Key: "/m/a", Revision: 4}}, receive(t, events))
+
+			cancel()
+			requireClosed(t, events)
+		})
+
+		t.Run("list then watch misses nothing", func(t *testing.T) {
+			s := cluster.NewMemoryStore()
+			_, err := s.Put(ctx, "/m/a", []byte("1"))
+			require.NoError(t, err)
+			kvs, rev, err := s.List(ctx, "/m/")
+			require.NoError(t, err)
+			require.Len(t, kvs, 1)
+
+			_, err = s.Put(ctx, "/m/b", []byte("2")) // modification between list and watch
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			events, err := s.Watch(ctx, "/m/", rev+1)
+			require.NoError(t, err)
+			event := receive(t, events)
+			require.Equal(t, "/m/b", event.Key)
+			require.EqualValues(t, rev+1, event.Revision)
+		})
+
+		t.Run("from a past revision", func(t *testing.T) {
+			s := cluster.NewMemoryStore()
+			for i := range 5 {
+				_, err := s.Put(ctx, "/m/a", fmt.Appendf(nil, "%d", i))
+				require.NoError(t, err)
+			}
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			events, err := s.Watch(ctx, "/m/", 3)
+			require.NoError(t, err)
+			for _, expected := range []string{"2", "3", "4"} {
+				require.Equal(t, expected, string(receive(t, events).Value))
+			}
+
+			_, err = s.Watch(ctx, "/m/", -1)
+			require.Error(t, err)
+		})
+
+		t.Run("slow consumers don't block modifications", func(t *testing.T) {
+			s := cluster.NewMemoryStore()
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			events, err := s.Watch(ctx, "", 0)
+			require.NoError(t, err)
+			for i := range 1000 {
+				_, err := s.Put(ctx, fmt.Sprintf("/k/%d", i), nil)
+				require.NoError(t, err)
+			}
+			for i := range 1000 {
+				require.EqualValues(t, i+1, receive(t, events).Revision)
+			}
+		})
+	})
+
+	t.Run("context", func(t *testing.T) {
+		s := cluster.NewMemoryStore()
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := s.Put(ctx, "/a", nil)
+		require.ErrorIs(t, err, context.Canceled)
+		_, err = s.Watch(ctx, "/", 0)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func receive(t *testing.T, events <-chan cluster.WatchEvent) cluster.WatchEvent {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		require.True(t, ok, "watch closed")
+		return event
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no watch event received")
+		return cluster.WatchEvent{}
+	}
+}
+
+func requireClosed(t *testing.T, events <-chan cluster.WatchEvent) {
+	t.Helper()
+	select {
+	case _, ok := <-events:
+		require.False(t, ok, "watch not closed")
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "watch not closed")
+	}
+}
@@ -417,3 +417,34 @@ func TestMigrationTypes(t *testing.T) {
 		})
 	})
 }
+
+// FuzzMigrationTypesJSON feeds JSON documents to every migration type.
+// Decoding must never panic and documents that decode must survive a JSON round trip unchanged.
+func FuzzMigrationTypesJSON(f *testing.F) {
+	for _, c := range loadCompatCorpus(f) {
+		for _, doc := range c.Content {
+			f.Add([]byte(doc))
+		}
+	}
+	f.Add([]byte(`{"jobs":null,"nodes":[],"startTime":"2026-01-02T10:00:00.123456789+05:30"}`))
+	f.Add([]byte(`{"jobId":"job-1","partitions":["ws1-0"],"jobs":[{"partitions":null}]}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for name, newValue := range compatTypes {
+			v := newValue()
+			if err := jsonrs.Unmarshal(data, v); err != nil {
+				continue
+			}
+			encoded, err := jsonrs.Marshal(v)
+			require.NoError(t, err, name)
+
+			decoded := newValue()
+			require.NoError(t, jsonrs.Unmarshal(encoded, decoded), name)
+			require.Equal(t, v, decoded, name)
+
+			reencoded, err := jsonrs.Marshal(decoded)
+			require.NoError(t, err, name)
+			require.JSONEq(t, string(encoded), string(reencoded), name)
+		}
+	})
+}
@@ -0,0 +1,187 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/samber/lo"
+)
+
+var ErrInvalidPlan = errors.New("invalid migration plan")
+
+// PartitionMigrationLimits throttles the waves of a PartitionMigrationPlan. Zero values mean no limit.
+type PartitionMigrationLimits struct {
+	MaxPartitionsPerJob int   `json:"maxPartitionsPerJob"` // maximum number of partitions moved by a job
+	MaxJobsPerNode      int   `json:"maxJobsPerNode"`      // maximum number of jobs of a wave having the same source, or the same target node
+	MaxBytesPerWave     int64 `json:"maxBytesPerWave"`     // maximum size of the partitions moved by a wave, if partition sizes are known
+}
+
+// PartitionMigrationWave is a set of migration jobs running concurrently.
+type PartitionMigrationWave struct {
+	Jobs  []*PartitionMigrationJobHeader `json:"jobs"`  // list of migration jobs
+	Bytes int64                          `json:"bytes"` // size of the partitions moved by the wave, 0 if unknown
+}
+
+// PartitionMigrationPlan is a migration split into ordered waves within limits, every wave being migrated
+// as a PartitionMigration once the previous one is completed, so that nodes are not overloaded.
+type PartitionMigrationPlan struct {
+	ID     string                    `json:"id"`     // unique identifier for the plan
+	Limits PartitionMigrationLimits  `json:"limits"` // limits of the waves
+	Waves  []*PartitionMigrationWave `json:"waves"`  // ordered list of waves
+}
+
+// NewPartitionMigrationPlan splits migration jobs into waves within the given limits, preserving their final assignment:
+// every partition is moved once, from the same source node to the same target node.
+//
+// Jobs with too many partitions are split into jobs with IDs suffixed by their index, skipping the IDs of other jobs,
+// then every job is placed in the first wave it fits in. Sizes of partitions are optional, bytes being limited only if they are given.
+// A partition bigger than MaxBytesPerWave is moved alone, by a job and a wave of its own.
+func NewPartitionMigrationPlan(id string, jobs []*PartitionMigrationJobHeader, limits PartitionMigrationLimits, sizes map[string]int64) (*PartitionMigrationPlan, error) {
+	if limits.MaxPartitionsPerJob < 0 || limits.MaxJobsPerNode < 0 || limits.MaxBytesPerWave < 0 {
+		return nil, fmt.Errorf("%w %q: negative limits %+v", ErrInvalidPlan, id, limits)
+	}
+	p := &PartitionMigrationPlan{ID: id, Limits: limits}
+	type waveLoad struct {
+		wave             *PartitionMigrationWave
+		sources, targets map[int]int // number of jobs by source and target node
+	}
+	var loads []*waveLoad
+	for _, job := range splitMigrationJobs(jobs, limits, sizes) {
+		bytes := partitionsBytes(job.Partitions, sizes)
+		i := slices.IndexFunc(loads, func(l *waveLoad) bool {
+			return (limits.MaxJobsPerNode == 0 || l.sources[job.SourceNode] < limits.MaxJobsPerNode && l.targets[job.TargetNode] < limits.MaxJobsPerNode) &&
+				(limits.MaxBytesPerWave == 0 || l.wave.Bytes+bytes <= limits.MaxBytesPerWave)
+		})
+		if i < 0 {
+			i = len(loads)
+			loads = append(loads, &waveLoad{wave: &PartitionMigrationWave{}, sources: make(map[int]int), targets: make(map[int]int)})
+			p.Waves = append(p.Waves, loads[i].wave)
+		}
+		loads[i].wave.Jobs = append(loads[i].wave.Jobs, job)
+		loads[i].wave.Bytes += bytes
+		loads[i].sources[job.SourceNode]++
+		loads[i].targets[job.TargetNode]++
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// splitMigrationJobs splits jobs with more partitions or bytes than allowed, keeping the order of their partitions.
+func splitMigrationJobs(jobs []*PartitionMigrationJobHeader, limits PartitionMigrationLimits, sizes map[string]int64) []*PartitionMigrationJobHeader {
+	jobIDs := make(map[string]struct{}, len(jobs))
+	for _, job := range jobs {
+		jobIDs[job.JobID] = struct{}{}
+	}
+	var split []*PartitionMigrationJobHeader
+	for _, job := range jobs {
+		var chunks [][]string
+		var bytes int64
+		for _, partition := range job.Partitions {
+			size := sizes[partition]
+			if len(chunks) == 0 ||
+				limits.MaxPartitionsPerJob > 0 && len(chunks[len(chunks)-1]) >= limits.MaxPartitionsPerJob ||
+				limits.MaxBytesPerWave > 0 && bytes+size > limits.MaxBytesPerWave {
+				chunks = append(chunks, nil)
+				bytes = 0
+			}
+			chunks[len(chunks)-1] = append(chunks[len(chunks)-1], partition)
+			bytes += size
+		}
+		if len(chunks) <= 1 {
+			split = append(split, job.Clone())
+			continue
+		}
+		n := 0
+		for _, partitions := range chunks {
+			var jobID string
+			for {
+				n++
+				jobID = fmt.Sprintf("%s-%d", job.JobID, n)
+				if _, exists := jobIDs[jobID]; !exists {
+					break
+				}
+			}
+			jobIDs[jobID] = struct{}{}
+			split = append(split, &PartitionMigrationJobHeader{
+				JobID:      jobID,
+				SourceNode: job.SourceNode,
+				TargetNode: job.TargetNode,
+				Partitions: partitions,
+			})
+		}
+	}
+	return split
+}
+
+func partitionsBytes(partitions []string, sizes map[string]int64) int64 {
+	return lo.SumBy(partitions, func(p string) int64 { return sizes[p] })
+}
+
+// Validate returns an error wrapping ErrInvalidPlan if the plan moves a partition more than once, reuses a job ID,
+// or if its waves exceed its limits. Waves may exceed MaxBytesPerWave with a single job moving a single partition.
+func (p *PartitionMigrationPlan) Validate() error {
+	partitions := make(map[string]struct{})
+	jobIDs := make(map[string]struct{})
+	for i, wave := range p.Waves {
+		sources, targets := make(map[int]int), make(map[int]int)
+		for _, job := range wave.Jobs {
+			if _, ok := jobIDs[job.JobID]; ok {
+				return fmt.Errorf("%w %q: job %q is planned more than once", ErrInvalidPlan, p.ID, job.JobID)
+			}
+			jobIDs[job.JobID] = struct{}{}
+			if p.Limits.MaxPartitionsPerJob > 0 && len(job.Partitions) > p.Limits.MaxPartitionsPerJob {
+				return fmt.Errorf("%w %q: job %q moves %d partitions, more than %d", ErrInvalidPlan, p.ID, job.JobID, len(job.Partitions), p.Limits.MaxPartitionsPerJob)
+			}
+			for _, partition := range job.Partitions {
+				if _, ok := partitions[partition]; ok {
+					return fmt.Errorf("%w %q: partition %q is moved more than once", ErrInvalidPlan, p.ID, partition)
+				}
+				partitions[partition] = struct{}{}
+			}
+			sources[job.SourceNode]++
+			targets[job.TargetNode]++
+		}
+		for _, job := range wave.Jobs {
+			if p.Limits.MaxJobsPerNode > 0 && sources[job.SourceNode] > p.Limits.MaxJobsPerNode {
+				return fmt.Errorf("%w %q: wave %d has %d jobs from node %d, more than %d", ErrInvalidPlan, p.ID, i, sources[job.SourceNode], job.SourceNode, p.Limits.MaxJobsPerNode)
+			}
+			if p.Limits.MaxJobsPerNode > 0 && targets[job.TargetNode] > p.Limits.MaxJobsPerNode {
+				return fmt.Errorf("%w %q: wave %d has %d jobs to node %d, more than %d", ErrInvalidPlan, p.ID, i, targets[job.TargetNode], job.TargetNode, p.Limits.MaxJobsPerNode)
+			}
+		}
+		single := len(wave.Jobs) == 1 && len(wave.Jobs[0].Partitions) == 1
+		if p.Limits.MaxBytesPerWave > 0 && wave.Bytes > p.Limits.MaxBytesPerWave && !single {
+			return fmt.Errorf("%w %q: wave %d moves %d bytes, more than %d", ErrInvalidPlan, p.ID, i, wave.Bytes, p.Limits.MaxBytesPerWave)
+		}
+	}
+	return nil
+}
+
+// Assignment returns the target node of every partition moved by the plan.
+func (p *PartitionMigrationPlan) Assignment() map[string]int {
+	assignment := make(map[string]int)
+	for _, wave := range p.Waves {
+		for _, job := range wave.Jobs {
+			for _, partition := range job.Partitions {
+				assignment[partition] = job.TargetNode
+			}
+		}
+	}
+	return assignment
+}
+
+// Migrations returns the new migrations of the waves, in order, with IDs made of the plan ID and the wave number.
+func (p *PartitionMigrationPlan) Migrations() []*PartitionMigration {
+	return lo.Map(p.Waves, func(wave *PartitionMigrationWave, i int) *PartitionMigration {
+		return &PartitionMigration{
+			ID:     fmt.Sprintf("%s-wave-%d", p.ID, i+1),
+			Status: PartitionMigrationStatusNew,
+			Jobs: lo.Map(wave.Jobs, func(job *PartitionMigrationJobHeader, _ int) *PartitionMigrationJobHeader {
+				return job.Clone()
+			}),
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package cluster_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-schemas/go/cluster"
+	"github.com/rudderlabs/rudder-schemas/go/schemastest"
+)
+
+func TestPartitionMigrationPlan(t *testing.T) {
+	job := func(id string, source, target int, partitions ...string) *cluster.PartitionMigrationJobHeader {
+		return &cluster.PartitionMigrationJobHeader{JobID: id, SourceNode: source, TargetNode: target, Partitions: partitions}
+	}
+	jobIDs := func(p *cluster.PartitionMigrationPlan) [][]string {
+		var ids [][]string
+		for _, wave := range p.Waves {
+			var wids []string
+			for _, job := range wave.Jobs {
+				wids = append(wids, job.JobID)
+			}
+			ids = append(ids, wids)
+		}
+		return ids
+	}
+
+	t.Run("without limits", func(t *testing.T) {
+		pm := schemastest.NewPartitionMigration()
+		p, err := cluster.NewPartitionMigrationPlan("plan-1", pm.Jobs, cluster.PartitionMigrationLimits{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, &cluster.PartitionMigrationPlan{
+			ID:    "plan-1",
+			Waves: []*cluster.PartitionMigrationWave{{Jobs: pm.Jobs}},
+		}, p)
+		require.NotSame(t, pm.Jobs[0], p.Waves[0].Jobs[0], "jobs are cloned")
+	})
+
+	t.Run("max partitions per job", func(t *testing.T) {
+		jobs := []*cluster.PartitionMigrationJobHeader{
+			job("job-1", 0, 1, "p-0", "p-1", "p-2", "p-3", "p-4"),
+			job("job-2", 1, 2, "p-5", "p-6"),
+		}
+		p, err := cluster.NewPartitionMigrationPlan("plan-1", jobs, cluster.PartitionMigrationLimits{MaxPartitionsPerJob: 2}, nil)
+		require.NoError(t, err)
+		require.Equal(t, [][]string{{"job-1-1", "job-1-2", "job-1-3", "job-2"}}, jobIDs(p))
+		require.Equal(t, []string{"p-4"}, p.Waves[0].Jobs[2].Partitions)
+
+		t.Run("split job IDs don't collide", func(t *testing.T) {
+			jobs := []*cluster.PartitionMigrationJobHeader{
+				job("job-1", 0, 1, "p-0", "p-1", "p-2"),
+				job("job-1-1", 0, 2, "p-3", "p-4", "p-5"),
+			}
+			p, err := cluster.NewPartitionMigrationPlan("plan-1", jobs, cluster.PartitionMigrationLimits{MaxPartitionsPerJob: 2}, nil)
+			require.NoError(t, err)
+			require.Equal(t, [][]string{{"job-1-2", "job-1-3", "job-1-1-1", "job-1-1-2"}}, jobIDs(p))
+		})
+	})
+
+	t.Run("max jobs per node", func(t *testing.T) {
+		jobs := []*cluster.PartitionMigrationJobHeader{
+			job("job-1", 0, 1, "p-0"),
+			job("job-2", 0, 2, "p-1"),
+			job("job-3", 3, 2, "p-2"),
+			job("job-4", 3, 4, "p-3"),
+			job("job-5", 5, 6, "p-4"),
+		}
+		p, err := cluster.NewPartitionMigrationPlan("plan-1", jobs, cluster.PartitionMigrationLimits{MaxJobsPerNode: 1}, nil)
+		require.NoError(t, err)
+		require.Equal(t, [][]string{{"job-1", "job-3", "job-5"}, {"job-2", "job-4"}}, jobIDs(p))
+	})
+
+	t.Run("max bytes per wave", func(t *testing.T) {
+		jobs := []*cluster.PartitionMigrationJobHeader{
+			job("job-1", 0, 1, "p-0", "p-1", "p-2"),
+			job("job-2", 1, 2, "huge"),
+			job("job-3", 2, 0, "p-3"),
+		}
+		sizes := map[string]int64{"p-0": 40, "p-1": 40, "p-2": 40, "huge": 500, "p-3": 10}
+		p, err := cluster.NewPartitionMigrationPlan("plan-1", jobs, cluster.PartitionMigrationLimits{MaxBytesPerWave: 100}, sizes)
+		require.NoError(t, err)
+		require.Equal(t, [][]string{{"job-1-1", "job-3"}, {"job-1-2"}, {"job-2"}}, jobIDs(p), "huge is moved alone")
+		require.Equal(t, []string{"p-0", "p-1"}, p.Waves[0].Jobs[0].Partitions)
+		require.Equal(t, []int64{90, 40, 500}, []int64{p.Waves[0].Bytes, p.Waves[1].Bytes, p.Waves[2].Bytes})
+	})
+
+	t.Run("final assignment is preserved", func(t *testing.T) {
+		for seed := range int64(100) {
+			r := schemastest.NewRand(seed)
+			pm := schemastest.PartitionMigration(r)
+			sizes := make(map[string]int64)
+			expected := make(map[string]int)
+			for _, job := range pm.Jobs {
+				for _, partition := range job.Partitions {
+					sizes[partition] = r.Int63n(100)
+					expected[partition] = job.TargetNode
+				}
+			}
+			limits := cluster.PartitionMigrationLimits{
+				MaxPartitionsPerJob: r.Intn(3),
+				MaxJobsPerNode:      r.Intn(3),
+				MaxBytesPerWave:     r.Int63n(200),
+			}
+			p, err := cluster.NewPartitionMigrationPlan("plan", pm.Jobs, limits, sizes)
+			require.NoError(t, err, "seed %d", seed)
+			require.Equal(t, expected, p.Assignment(), "seed %d", seed)
+			require.NoError(t, p.Validate())
+		}
+	})
+
+	t.Run("migrations", func(t *testing.T) {
+		jobs := []*cluster.PartitionMigrationJobHeader{job("job-1", 0, 1, "p-0"), job("job-2", 0, 2, "p-1")}
+		p, err := cluster.NewPartitionMigrationPlan("plan-1", jobs, cluster.PartitionMigrationLimits{MaxJobsPerNode: 1}, nil)
+		require.NoError(t, err)
+		migrations := p.Migrations()
+		require.Len(t, migrations, 2)
+		for i, pm := range migrations {
+			require.Equal(t, fmt.Sprintf("plan-1-wave-%d", i+1), pm.ID)
+			require.Equal(t, cluster.PartitionMigrationStatusNew, pm.Status)
+			require.Equal(t, []*cluster.PartitionMigrationJobHeader{jobs[i]}, pm.Jobs)
+			_, err := cluster.NewKeyLayout("/migrations", pm.ID)
+			require.NoError(t, err, "migration IDs are valid keys")
+		}
+	})
+
+	t.Run("invalid plans", func(t *testing.T) {
+		_, err := cluster.NewPartitionMigrationPlan("plan-1", nil, cluster.PartitionMigrationLimits{MaxJobsPerNode: -1}, nil)
+		require.ErrorIs(t, err, cluster.ErrInvalidPlan)
+
+		_, err = cluster.NewPartitionMigrationPlan("plan-1", []*cluster.PartitionMigrationJobHeader{
+			job("job-1", 0, 1, "p-0"), job("job-2", 0, 2, "p-0"),
+		}, cluster.PartitionMigrationLimits{}, nil)
+		require.ErrorIs(t, err, cluster.ErrInvalidPlan)
+		require.ErrorContains(t, err, `partition "p-0" is moved more than once`)
+
+		for expected, p := range map[string]*cluster.PartitionMigrationPlan{
+			`job "job-1" is planned more than once`: {Waves: []*cluster.PartitionMigrationWave{
+				{Jobs: []*cluster.PartitionMigrationJobHeader{job("job-1", 0, 1, "p-0")}},
+				{Jobs: []*cluster.PartitionMigrationJobHeader{job("job-1", 0, 1, "p-1")}},
+			}},
+			`job "job-1" moves 2 partitions, more than 1`: {
+				Limits: cluster.PartitionMigrationLimits{MaxPartitionsPerJob: 1},
+				Waves:  []*cluster.PartitionMigrationWave{{Jobs: []*cluster.PartitionMigrationJobHeader{job("job-1", 0, 1, "p-0", "p-1")}}},
+			},
+			`wave 0 has 2 jobs to node 1, more than 1`: {
+				Limits: cluster.PartitionMigrationLimits{MaxJobsPerNode: 1},
+				Waves: []*cluster.PartitionMigrationWave{{Jobs: []*cluster.PartitionMigrationJobHeader{
+					job("job-1", 0, 1, "p-0"), job("job-2", 2, 1, "p-1"),
+				}}},
+			},
+			`wave 0 moves 20 bytes, more than 10`: {
+				Limits: cluster.PartitionMigrationLimits{MaxBytesPerWave: 10},
+				Waves:  []*cluster.PartitionMigrationWave{{Bytes: 20, Jobs: []*cluster.PartitionMigrationJobHeader{job("job-1", 0, 1, "p-0", "p-1")}}},
+			},
+		} {
+			err := p.Validate()
+			require.ErrorIs(t, err, cluster.ErrInvalidPlan, expected)
+			require.ErrorContains(t, err, expected)
+		}
+	})
+}
@@ -0,0 +1,222 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// PartitionMigrationProgress summarizes the progress of a migration at a point in time, see PartitionMigrationInfo.Progress.
+type PartitionMigrationProgress struct {
+	ID           string
+	Phase        PartitionMigrationStatus
+	AsOf         time.Time // time of the summary
+	StartTime    time.Time // time when the migration was started
+	Elapsed      time.Duration
+	Jobs         PartitionMigrationCounts // jobs by status
+	Partitions   PartitionMigrationCounts // partitions by status of their jobs
+	Nodes        []*PartitionMigrationNodeProgress
+	JobsProgress []*PartitionMigrationJobProgress
+
+	// ETA is the estimated remaining time, at the rate at which jobs were completed since the first one started.
+	// The estimated completion time is zero while it is unknown, i.e. until a job is completed, and once the migration
+	// is completed.
+	ETA                 time.Duration
+	EstimatedCompletion time.Time
+}
+
+// PartitionMigrationCounts counts jobs, or their partitions, by status.
+type PartitionMigrationCounts struct {
+	Pending   int `json:"pending"` // not published yet, before the migrating phase
+	New       int `json:"new"`
+	Moved     int `json:"moved"`
+	Completed int `json:"completed"`
+	Total     int `json:"total"`
+}
+
+func (c *PartitionMigrationCounts) add(status PartitionMigrationJobStatus, n int) {
+	switch status {
+	case "":
+		c.Pending += n
+	case PartitionMigrationJobStatusNew:
+		c.New += n
+	case PartitionMigrationJobStatusMoved:
+		c.Moved += n
+	case PartitionMigrationJobStatusCompleted:
+		c.Completed += n
+	}
+	c.Total += n
+}
+
+// PartitionMigrationNodeProgress counts the partitions moved out of and into a node, by status of their jobs.
+type PartitionMigrationNodeProgress struct {
+	Node int                      `json:"node"`
+	Out  PartitionMigrationCounts `json:"out"`
+	In   PartitionMigrationCounts `json:"in"`
+}
+
+// PartitionMigrationJobProgress is the progress of a job of a migration.
+type PartitionMigrationJobProgress struct {
+	JobID      string
+	SourceNode int
+	TargetNode int
+	Partitions int // number of partitions moved by the job
+	Status     PartitionMigrationJobStatus
+	StartTime  time.Time     // zero until the job is started
+	Elapsed    time.Duration // since the job was started
+}
+
+// Progress summarizes the progress of the migration at the given time.
+func (pmi *PartitionMigrationInfo) Progress(now time.Time) *PartitionMigrationProgress {
+	p := &PartitionMigrationProgress{
+		ID:        pmi.ID,
+		Phase:     pmi.Status,
+		AsOf:      now,
+		StartTime: pmi.StartTime,
+	}
+	if !pmi.StartTime.IsZero() {
+		p.Elapsed = now.Sub(pmi.StartTime)
+	}
+	nodes := make(map[int]*PartitionMigrationNodeProgress)
+	node := func(index int) *PartitionMigrationNodeProgress {
+		if _, ok := nodes[index]; !ok {
+			nodes[index] = &PartitionMigrationNodeProgress{Node: index}
+		}
+		return nodes[index]
+	}
+	var firstStart time.Time
+	for _, job := range pmi.Jobs {
+		p.Jobs.add(job.Status, 1)
+		p.Partitions.add(job.Status, len(job.Partitions))
+		node(job.SourceNode).Out.add(job.Status, len(job.Partitions))
+		node(job.TargetNode).In.add(job.Status, len(job.Partitions))
+
+		jp := &PartitionMigrationJobProgress{
+			JobID:      job.JobID,
+			SourceNode: job.SourceNode,
+			TargetNode: job.TargetNode,
+			Partitions: len(job.Partitions),
+			Status:     job.Status,
+			StartTime:  job.StartTime,
+		}
+		if !job.StartTime.IsZero() {
+			jp.Elapsed = now.Sub(job.StartTime)
+			if firstStart.IsZero() || job.StartTime.Before(firstStart) {
+				firstStart = job.StartTime
+			}
+		}
+		p.JobsProgress = append(p.JobsProgress, jp)
+	}
+	for _, index := range slices.Sorted(maps.Keys(nodes)) {
+		p.Nodes = append(p.Nodes, nodes[index])
+	}
+
+	switch {
+	case pmi.Status == PartitionMigrationStatusCompleted:
+		// nothing left to estimate
+	case p.Jobs.Completed > 0 && now.After(firstStart):
+		// remaining jobs at the rate of completed jobs per unit of time
+		p.ETA = time.Duration(float64(now.Sub(firstStart)) * float64(p.Jobs.Total-p.Jobs.Completed) / float64(p.Jobs.Completed))
+		p.EstimatedCompletion = now.Add(p.ETA)
+	}
+	return p
+}
+
+// MarshalJSON renders durations in seconds, omitting the ETA while it is unknown.
+func (p PartitionMigrationProgress) MarshalJSON() ([]byte, error) {
+	type jobProgress struct {
+		JobID          string                      `json:"jobId"`
+		SourceNode     int                         `json:"sourceNode"`
+		TargetNode     int                         `json:"targetNode"`
+		Partitions     int                         `json:"partitions"`
+		Status         PartitionMigrationJobStatus `json:"status"`
+		StartTime      *time.Time                  `json:"startTime,omitempty"`
+		ElapsedSeconds float64                     `json:"elapsedSeconds"`
+	}
+	v := struct {
+		ID                  string                            `json:"id"`
+		Phase               PartitionMigrationStatus          `json:"phase"`
+		AsOf                time.Time                         `json:"asOf"`
+		StartTime           time.Time                         `json:"startTime"`
+		ElapsedSeconds      float64                           `json:"elapsedSeconds"`
+		Jobs                PartitionMigrationCounts          `json:"jobs"`
+		Partitions          PartitionMigrationCounts          `json:"partitions"`
+		Nodes               []*PartitionMigrationNodeProgress `json:"nodes"`
+		JobsProgress        []jobProgress                     `json:"jobsProgress"`
+		ETASeconds          *float64                          `json:"etaSeconds,omitempty"`
+		EstimatedCompletion *time.Time                        `json:"estimatedCompletion,omitempty"`
+	}{
+		ID:             p.ID,
+		Phase:          p.Phase,
+		AsOf:           p.AsOf,
+		StartTime:      p.StartTime,
+		ElapsedSeconds: p.Elapsed.Seconds(),
+		Jobs:           p.Jobs,
+		Partitions:     p.Partitions,
+		Nodes:          p.Nodes,
+	}
+	for _, job := range p.JobsProgress {
+		jp := jobProgress{
+			JobID:          job.JobID,
+			SourceNode:     job.SourceNode,
+			TargetNode:     job.TargetNode,
+			Partitions:     job.Partitions,
+			Status:         job.Status,
+			ElapsedSeconds: job.Elapsed.Seconds(),
+		}
+		if !job.StartTime.IsZero() {
+			jp.StartTime = &job.StartTime
+		}
+		v.JobsProgress = append(v.JobsProgress, jp)
+	}
+	if !p.EstimatedCompletion.IsZero() {
+		eta := p.ETA.Seconds()
+		v.ETASeconds, v.EstimatedCompletion = &eta, &p.EstimatedCompletion
+	}
+	return json.Marshal(v)
+}
+
+// Text renders the progress for humans: a summary of the migration and its counts, followed by tables of nodes and jobs.
+func (p *PartitionMigrationProgress) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "migration %s %s", p.ID, p.Phase)
+	if !p.StartTime.IsZero() {
+		fmt.Fprintf(&b, ", started %s, %s ago", p.StartTime.Format(time.RFC3339), p.Elapsed.Round(time.Second))
+	}
+	switch {
+	case p.Phase == PartitionMigrationStatusCompleted:
+	case p.EstimatedCompletion.IsZero():
+		b.WriteString(", ETA unknown")
+	default:
+		fmt.Fprintf(&b, ", ETA %s", p.ETA.Round(time.Second))
+	}
+	b.WriteString("\n")
+	counts := func(name string, c PartitionMigrationCounts) {
+		fmt.Fprintf(&b, "%s: %d/%d completed, %d moved, %d new, %d pending\n", name, c.Completed, c.Total, c.Moved, c.New, c.Pending)
+	}
+	counts("jobs", p.Jobs)
+	counts("partitions", p.Partitions)
+
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprint(w, "\nNODE\tOUT\tIN\n")
+	for _, n := range p.Nodes {
+		fmt.Fprintf(w, "%d\t%d/%d done\t%d/%d done\n", n.Node, n.Out.Completed, n.Out.Total, n.In.Completed, n.In.Total)
+	}
+	fmt.Fprint(w, "\nJOB\tSOURCE\tTARGET\tPARTITIONS\tSTATUS\tSTARTED\tELAPSED\n")
+	for _, job := range p.JobsProgress {
+		status, started, elapsed := string(job.Status), "-", "-"
+		if status == "" {
+			status = "pending"
+		}
+		if !job.StartTime.IsZero() {
+			started, elapsed = job.StartTime.Format(time.RFC3339), job.Elapsed.Round(time.Second).String()
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t%s\t%s\n", job.JobID, job.SourceNode, job.TargetNode, job.Partitions, status, started, elapsed)
+	}
+	_ = w.Flush() // writing to a strings.Builder doesn't fail
+	return b.String()
+}
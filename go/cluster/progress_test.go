@@ -0,0 +1,108 @@
+package cluster_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-go-kit/jsonrs"
+	"github.com/rudderlabs/rudder-schemas/go/cluster"
+	"github.com/rudderlabs/rudder-schemas/go/schemastest"
+)
+
+func TestPartitionMigrationProgress(t *testing.T) {
+	now := schemastest.FixtureTime.Add(time.Hour + time.Minute)
+
+	t.Run("mid-flight", func(t *testing.T) {
+		p := schemastest.MidFlightPartitionMigration().Progress(now)
+		require.Equal(t, "migration-1", p.ID)
+		require.Equal(t, cluster.PartitionMigrationStatusMigrating, p.Phase)
+		require.Equal(t, time.Hour+time.Minute, p.Elapsed)
+		require.Equal(t, cluster.PartitionMigrationCounts{New: 1, Moved: 1, Completed: 1, Total: 3}, p.Jobs)
+		require.Equal(t, cluster.PartitionMigrationCounts{New: 1, Moved: 1, Completed: 2, Total: 4}, p.Partitions)
+		require.Equal(t, []*cluster.PartitionMigrationNodeProgress{
+			{Node: 0, Out: cluster.PartitionMigrationCounts{New: 1, Moved: 1, Completed: 2, Total: 4}},
+			{Node: 1, In: cluster.PartitionMigrationCounts{Completed: 2, Total: 2}},
+			{Node: 2, In: cluster.PartitionMigrationCounts{New: 1, Moved: 1, Total: 2}},
+		}, p.Nodes)
+		require.Len(t, p.JobsProgress, 3)
+		require.Equal(t, time.Hour, p.JobsProgress[0].Elapsed)
+		require.Equal(t, time.Hour-time.Minute, p.JobsProgress[1].Elapsed)
+		require.Zero(t, p.JobsProgress[2].Elapsed, "job-3 isn't started")
+		require.Equal(t, 2*time.Hour, p.ETA, "1 job completed in the hour since the first one started, 2 remaining")
+		require.Equal(t, now.Add(2*time.Hour), p.EstimatedCompletion)
+	})
+
+	t.Run("not migrating yet", func(t *testing.T) {
+		var pmi cluster.PartitionMigrationInfo
+		pmi.FromPartitionMigration(*schemastest.NewPartitionMigration(), nil)
+		p := pmi.Progress(now)
+		require.Equal(t, cluster.PartitionMigrationCounts{Pending: 3, Total: 3}, p.Jobs)
+		require.Equal(t, cluster.PartitionMigrationCounts{Pending: 4, Total: 4}, p.Partitions)
+		require.True(t, p.EstimatedCompletion.IsZero(), "ETA is unknown")
+		require.Contains(t, p.Text(), "migration migration-1 new, started 2024-08-01T02:30:50Z, 1h1m0s ago, ETA unknown\n")
+		require.Contains(t, p.Text(), "job-3  0       2       1           pending  -        -\n")
+	})
+
+	t.Run("completed", func(t *testing.T) {
+		p := schemastest.CompletedPartitionMigration().Progress(now)
+		require.Equal(t, cluster.PartitionMigrationCounts{Completed: 3, Total: 3}, p.Jobs)
+		require.Zero(t, p.ETA)
+		require.True(t, p.EstimatedCompletion.IsZero(), "the completion time isn't estimated")
+		require.NotContains(t, p.Text(), "ETA")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		data, err := jsonrs.Marshal(schemastest.MidFlightPartitionMigration().Progress(now))
+		require.NoError(t, err)
+		var v map[string]any
+		require.NoError(t, jsonrs.Unmarshal(data, &v))
+		require.Equal(t, "migrating", v["phase"])
+		require.EqualValues(t, 3660, v["elapsedSeconds"])
+		require.EqualValues(t, 7200, v["etaSeconds"])
+		require.Equal(t, map[string]any{"pending": 0.0, "new": 1.0, "moved": 1.0, "completed": 1.0, "total": 3.0}, v["jobs"])
+		jobs := v["jobsProgress"].([]any)
+		require.Len(t, jobs, 3)
+		require.Equal(t, "job-1", jobs[0].(map[string]any)["jobId"])
+		require.EqualValues(t, 3600, jobs[0].(map[string]any)["elapsedSeconds"])
+		require.NotContains(t, jobs[2], "startTime", "job-3 isn't started")
+
+		var pmi cluster.PartitionMigrationInfo
+		pmi.FromPartitionMigration(*schemastest.NewPartitionMigration(), nil)
+		data, err = jsonrs.Marshal(pmi.Progress(now))
+		require.NoError(t, err)
+		require.NotContains(t, string(data), "etaSeconds")
+
+		t.Run("values", func(t *testing.T) {
+			p := schemastest.MidFlightPartitionMigration().Progress(now)
+			byPointer, err := jsonrs.Marshal(p)
+			require.NoError(t, err)
+			byValue, err := jsonrs.Marshal(struct {
+				Progress cluster.PartitionMigrationProgress
+			}{*p})
+			require.NoError(t, err)
+			require.JSONEq(t, `{"Progress":`+string(byPointer)+`}`, string(byValue))
+		})
+	})
+
+	t.Run("text", func(t *testing.T) {
+		require.Equal(t, `migration migration-1 migrating, started 2024-08-01T02:30:50Z, 1h1m0s ago, ETA 2h0m0s
+jobs: 1/3 completed, 1 moved, 1 new, 0 pending
+partitions: 2/4 completed, 1 moved, 1 new, 0 pending
+
+NODE  OUT       IN
+0     2/4 done  0/0 done
+1     0/0 done  2/2 done
+2     0/0 done  0/2 done
+
+JOB    SOURCE  TARGET  PARTITIONS  STATUS     STARTED               ELAPSED
+job-1  0       1       2           completed  2024-08-01T02:31:50Z  1h0m0s
+job-2  0       2       1           moved      2024-08-01T02:32:50Z  59m0s
+job-3  0       2       1           new        -                     -
+`, schemastest.MidFlightPartitionMigration().Progress(now).Text())
+
+		text := schemastest.CompletedPartitionMigration().Progress(now).Text()
+		require.Contains(t, text, "migration migration-1 completed, started 2024-08-01T02:30:50Z, 1h1m0s ago\n")
+	})
+}
@@ -0,0 +1,97 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrKeyNotFound      = errors.New("key not found")
+	ErrRevisionConflict = errors.New("revision conflict")
+)
+
+// Store is the contract of the key-value store where migration documents, commands and acknowledgments are written,
+// as JSON values, by coordinators and nodes.
+//
+// Every modification increases the revision of the whole store, and the value of a key carries
+// the revision of its last modification, so that the state read by Get or List can be followed by
+// watching from the next revision, without missing or repeating events.
+type Store interface {
+	// Get returns the value of a key, or ErrKeyNotFound.
+	Get(ctx context.Context, key string) (KeyValue, error)
+	// Put sets the value of a key, returning the revision of the modification.
+	Put(ctx context.Context, key string, value []byte) (int64, error)
+	// CompareAndSwap sets the value of a key only if its last modification happened at the given revision,
+	// or if the key doesn't exist when the revision is 0, otherwise it returns ErrRevisionConflict.
+	CompareAndSwap(ctx context.Context, key string, revision int64, value []byte) (int64, error)
+	// Delete deletes a key, returning the revision of the modification, or ErrKeyNotFound.
+	Delete(ctx context.Context, key string) (int64, error)
+	// List returns the values of the keys with the given prefix, sorted by key,
+	// along with the revision of the store at the time they were read.
+	List(ctx context.Context, prefix string) ([]KeyValue, int64, error)
+	// Watch returns the modifications of the keys with the given prefix, in revision order,
+	// starting from the given revision, or from the next modification if the revision is 0.
+	// The channel is closed once the context is done.
+	Watch(ctx context.Context, prefix string, revision int64) (<-chan WatchEvent, error)
+}
+
+// KeyValue is the value of a key in a Store.
+type KeyValue struct {
+	Key      string
+	Value    []byte
+	Revision int64 // revision of the last modification of the key
+}
+
+type WatchEventType string
+
+const (
+	WatchEventTypePut    WatchEventType = "put"    // the key was created or updated
+	WatchEventTypeDelete WatchEventType = "delete" // the key was deleted
+)
+
+// WatchEvent is a modification of a key in a Store. The value of delete events is nil.
+type WatchEvent struct {
+	Type WatchEventType
+	KeyValue
+}
+
+// GetJSON gets the value of a key and unmarshals it, returning it along with the revision of its last modification.
+func GetJSON[T any](ctx context.Context, s Store, key string) (*T, int64, error) {
+	kv, err := s.Get(ctx, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	v, err := unmarshalDocument[T](kv)
+	if err != nil {
+		return nil, 0, err
+	}
+	return v, kv.Revision, nil
+}
+
+// PutJSON marshals a value and sets it as the value of a key, returning the revision of the modification.
+func PutJSON(ctx context.Context, s Store, key string, v any) (int64, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0, fmt.Errorf("marshalling %s: %w", key, err)
+	}
+	return s.Put(ctx, key, data)
+}
+
+// CompareAndSwapJSON marshals a value and sets it as the value of a key, see Store.CompareAndSwap.
+func CompareAndSwapJSON(ctx context.Context, s Store, key string, revision int64, v any) (int64, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0, fmt.Errorf("marshalling %s: %w", key, err)
+	}
+	return s.CompareAndSwap(ctx, key, revision, data)
+}
+
+func unmarshalDocument[T any](kv KeyValue) (*T, error) {
+	var v T
+	if err := json.Unmarshal(kv.Value, &v); err != nil {
+		return nil, fmt.Errorf("unmarshalling %s: %w", kv.Key, err)
+	}
+	return &v, nil
+}
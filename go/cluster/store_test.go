@@ -0,0 +1,40 @@
+package cluster_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-schemas/go/cluster"
+)
+
+func TestStoreJSON(t *testing.T) {
+	ctx := context.Background()
+	s := cluster.NewMemoryStore()
+	cmd := &cluster.ReloadGatewayCommand{Nodes: []int{0, 1}, AckKeyPrefix: "/migrations/migration-1/reload-gw/ack"}
+
+	rev, err := cluster.PutJSON(ctx, s, "/migrations/migration-1/reload-gw", cmd)
+	require.NoError(t, err)
+
+	got, gotRev, err := cluster.GetJSON[cluster.ReloadGatewayCommand](ctx, s, "/migrations/migration-1/reload-gw")
+	require.NoError(t, err)
+	require.Equal(t, cmd, got)
+	require.Equal(t, rev, gotRev)
+
+	_, err = cluster.CompareAndSwapJSON(ctx, s, "/migrations/migration-1/reload-gw", rev+1, cmd)
+	require.ErrorIs(t, err, cluster.ErrRevisionConflict)
+	_, err = cluster.CompareAndSwapJSON(ctx, s, "/migrations/migration-1/reload-gw", rev, cmd)
+	require.NoError(t, err)
+
+	_, _, err = cluster.GetJSON[cluster.ReloadGatewayCommand](ctx, s, "/missing")
+	require.ErrorIs(t, err, cluster.ErrKeyNotFound)
+
+	_, err = s.Put(ctx, "/invalid", []byte("{"))
+	require.NoError(t, err)
+	_, _, err = cluster.GetJSON[cluster.ReloadGatewayCommand](ctx, s, "/invalid")
+	require.ErrorContains(t, err, "unmarshalling /invalid")
+
+	_, err = cluster.PutJSON(ctx, s, "/invalid", make(chan int))
+	require.ErrorContains(t, err, "marshalling /invalid")
+}
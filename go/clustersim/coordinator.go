@@ -0,0 +1,177 @@
+package clustersim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rudderlabs/rudder-schemas/go/cluster"
+)
+
+var errCrash = errors.New("simulated crash")
+
+// coordinator is the state of the simulated coordinator. It runs in its own goroutine, but only while the simulation
+// waits for it to yield, which it does when it watches the store or returns.
+type coordinator struct {
+	running  bool
+	crashing bool // the next write fails, and so the run
+	cancel   context.CancelFunc
+	yields   chan yield
+	watch    *watch                          // the watch the coordinator waits on
+	info     *cluster.PartitionMigrationInfo // once the migration is completed
+}
+
+// yield is sent by the coordinator goroutine when it gives control back to the simulation.
+type yield struct {
+	watch *watch // the coordinator waits for an event of the watch, or it returned
+	info  *cluster.PartitionMigrationInfo
+	err   error
+}
+
+type watch struct {
+	prefix   string
+	revision int64
+	events   chan cluster.WatchEvent
+}
+
+func (sim *simulation) startCoordinator(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	gatewayNodes := make([]int, sim.gatewayNodes)
+	for i := range gatewayNodes {
+		gatewayNodes[i] = i
+	}
+	var srcRouterNodes []string
+	for _, n := range sim.nodes {
+		if n.role == roleSrcRouter {
+			srcRouterNodes = append(srcRouterNodes, n.name)
+		}
+	}
+	c := cluster.NewCoordinator(&coordinatorStore{sim: sim}, sim.layout,
+		cluster.WithCoordinatorGatewayNodes(gatewayNodes...),
+		cluster.WithCoordinatorSrcRouterNodes(srcRouterNodes...),
+		cluster.WithCoordinatorClock(func() time.Time { return sim.now }),
+	)
+	sim.coordinator.running, sim.coordinator.crashing, sim.coordinator.cancel = true, false, cancel
+	go func() {
+		info, err := c.Run(ctx, sim.migration)
+		sim.coordinator.yields <- yield{info: info, err: err}
+	}()
+	return sim.awaitCoordinator()
+}
+
+// awaitCoordinator waits until the coordinator yields.
+func (sim *simulation) awaitCoordinator() error {
+	y := <-sim.coordinator.yields
+	if sim.coordinator.watch = y.watch; y.watch != nil {
+		return nil
+	}
+	sim.coordinator.running = false
+	sim.coordinator.cancel()
+	switch {
+	case y.err == nil:
+		sim.coordinator.info = y.info
+		sim.logf("coordinator completes the migration")
+	case errors.Is(y.err, errCrash):
+		sim.logf("coordinator crashes: %v", y.err)
+	default:
+		return fmt.Errorf("coordinator: %w", y.err)
+	}
+	return nil
+}
+
+// stopCoordinator stops the coordinator, if it is running.
+func (sim *simulation) stopCoordinator() {
+	if !sim.coordinator.running {
+		return
+	}
+	sim.coordinator.cancel()
+	for y := range sim.coordinator.yields {
+		if y.watch == nil {
+			break
+		}
+	}
+	sim.coordinator.running = false
+}
+
+// coordinatorAction returns the possible action of the coordinator, if any.
+func (sim *simulation) coordinatorAction() *action {
+	if !sim.coordinator.running {
+		return &action{
+			description: "coordinator restarts",
+			run:         sim.startCoordinator,
+		}
+	}
+	w := sim.coordinator.watch
+	e, ok := sim.store.next(w.prefix, w.revision)
+	if !ok || e.observedAt > sim.step {
+		return nil
+	}
+	return &action{
+		description: fmt.Sprintf("coordinator observes the %s of %s at revision %d", e.Type, e.Key, e.Revision),
+		run: func(ctx context.Context) error {
+			select {
+			case w.events <- e.WatchEvent:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			return sim.awaitCoordinator()
+		},
+	}
+}
+
+// coordinatorStore is the store of the coordinator, failing its writes when it crashes
+// and yielding to the simulation when it watches.
+type coordinatorStore struct {
+	sim *simulation
+}
+
+var _ cluster.Store = (*coordinatorStore)(nil)
+
+func (s *coordinatorStore) Get(ctx context.Context, key string) (cluster.KeyValue, error) {
+	return s.sim.store.Get(ctx, key)
+}
+
+func (s *coordinatorStore) List(ctx context.Context, prefix string) ([]cluster.KeyValue, int64, error) {
+	return s.sim.store.List(ctx, prefix)
+}
+
+func (s *coordinatorStore) Put(ctx context.Context, key string, value []byte) (int64, error) {
+	if err := s.write(key); err != nil {
+		return 0, err
+	}
+	return s.sim.store.Put(ctx, key, value)
+}
+
+func (s *coordinatorStore) CompareAndSwap(ctx context.Context, key string, revision int64, value []byte) (int64, error) {
+	if err := s.write(key); err != nil {
+		return 0, err
+	}
+	return s.sim.store.CompareAndSwap(ctx, key, revision, value)
+}
+
+func (s *coordinatorStore) Delete(ctx context.Context, key string) (int64, error) {
+	if err := s.write(key); err != nil {
+		return 0, err
+	}
+	return s.sim.store.Delete(ctx, key)
+}
+
+func (s *coordinatorStore) write(key string) error {
+	s.sim.writes++
+	if s.sim.coordinator.crashing {
+		s.sim.coordinator.crashing = false
+		return fmt.Errorf("writing %s: %w", key, errCrash)
+	}
+	s.sim.logf("coordinator writes %s", key)
+	return nil
+}
+
+func (s *coordinatorStore) Watch(ctx context.Context, prefix string, revision int64) (<-chan cluster.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	w := &watch{prefix: prefix, revision: revision, events: make(chan cluster.WatchEvent)}
+	s.sim.coordinator.yields <- yield{watch: w}
+	return w.events, nil
+}
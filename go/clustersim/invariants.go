@@ -0,0 +1,227 @@
+package clustersim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/rudderlabs/rudder-schemas/go/cluster"
+)
+
+// Invariant checks the state of a simulation after every step, returning an error if it doesn't hold.
+type Invariant func(ctx context.Context, state *State) error
+
+// State is the state of a simulation after a step.
+type State struct {
+	Step           int
+	Now            time.Time
+	Store          cluster.Store // the store of the simulation, not to be modified
+	Layout         *cluster.KeyLayout
+	Migration      *cluster.PartitionMigration // the simulated migration, as it was started
+	GatewayNodes   []int
+	SrcRouterNodes []string
+	Owners         map[string][]int // sorted indexes of the nodes owning each partition
+}
+
+// DefaultInvariants returns the invariants checked by every simulation.
+func DefaultInvariants() []Invariant {
+	return []Invariant{ExclusiveOwnership, OrderedProtocol, MonotonicProgress()}
+}
+
+// ExclusiveOwnership checks that no partition is owned by two nodes at once.
+func ExclusiveOwnership(_ context.Context, state *State) error {
+	for _, partition := range slices.Sorted(maps.Keys(state.Owners)) {
+		if owners := state.Owners[partition]; len(owners) > 1 {
+			return fmt.Errorf("partition %s is owned by nodes %v", partition, owners)
+		}
+	}
+	return nil
+}
+
+// OrderedProtocol checks that every phase of the migration starts once all the nodes acknowledged the previous one:
+// gateways reload once the source and target nodes acknowledged the migration, source routers reload once
+// the gateways reloaded, and jobs start once the source routers reloaded.
+func OrderedProtocol(ctx context.Context, state *State) error {
+	phases := []struct {
+		key, ackKeyPrefix string
+		missing           func(kvs []cluster.KeyValue) ([]string, error)
+	}{
+		{
+			key:          state.Layout.ReloadGatewayKey(),
+			ackKeyPrefix: state.Layout.MigrationAckKeyPrefix(),
+			missing: func(kvs []cluster.KeyValue) ([]string, error) {
+				nodes := lo.Uniq(append(state.Migration.SourceNodes(), state.Migration.TargetNodes()...))
+				slices.Sort(nodes)
+				return missingAcks(kvs, nodes, func(ack *cluster.PartitionMigrationAck) int { return ack.NodeIndex })
+			},
+		},
+		{
+			key:          state.Layout.ReloadSrcRouterKey(),
+			ackKeyPrefix: state.Layout.ReloadGatewayAckKeyPrefix(),
+			missing: func(kvs []cluster.KeyValue) ([]string, error) {
+				return missingAcks(kvs, state.GatewayNodes, func(ack *cluster.ReloadGatewayAck) int { return ack.NodeIndex })
+			},
+		},
+		{
+			key:          state.Layout.JobsPrefix(),
+			ackKeyPrefix: state.Layout.ReloadSrcRouterAckKeyPrefix(),
+			missing: func(kvs []cluster.KeyValue) ([]string, error) {
+				return missingAcks(kvs, state.SrcRouterNodes, func(ack *cluster.ReloadSrcRouterAck) string { return ack.NodeName })
+			},
+		},
+	}
+	for _, phase := range phases {
+		started, _, err := state.Store.List(ctx, phase.key)
+		if err != nil {
+			return err
+		}
+		if len(started) == 0 {
+			continue
+		}
+		acks, _, err := state.Store.List(ctx, phase.ackKeyPrefix+"/")
+		if err != nil {
+			return err
+		}
+		missing, err := phase.missing(acks)
+		if err != nil {
+			return err
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("%s is written before the acks of nodes %v", phase.key, missing)
+		}
+	}
+	return nil
+}
+
+// missingAcks returns the nodes without an ack.
+func missingAcks[A any, N comparable](kvs []cluster.KeyValue, nodes []N, node func(ack *A) N) ([]string, error) {
+	acked := make(map[N]bool, len(kvs))
+	for _, kv := range kvs {
+		var ack A
+		if err := json.Unmarshal(kv.Value, &ack); err != nil {
+			return nil, fmt.Errorf("unmarshalling %s: %w", kv.Key, err)
+		}
+		acked[node(&ack)] = true
+	}
+	var missing []string
+	for _, n := range nodes {
+		if !acked[n] {
+			missing = append(missing, fmt.Sprint(n))
+		}
+	}
+	return missing, nil
+}
+
+// MonotonicProgress returns an invariant checking that the persisted information of the migration only moves forward:
+// its status and the statuses of its jobs never go back, and start times never change once set.
+func MonotonicProgress() Invariant {
+	var last *cluster.PartitionMigrationInfo
+	return func(ctx context.Context, state *State) error {
+		info, _, err := cluster.GetJSON[cluster.PartitionMigrationInfo](ctx, state.Store, state.Layout.MigrationInfoKey())
+		if errors.Is(err, cluster.ErrKeyNotFound) {
+			if last != nil {
+				return errors.New("migration information is deleted")
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		defer func() { last = info }()
+		if last == nil {
+			return nil
+		}
+		if slices.Index(migrationStatuses, info.Status) < slices.Index(migrationStatuses, last.Status) {
+			return fmt.Errorf("migration status goes back from %q to %q", last.Status, info.Status)
+		}
+		if !info.StartTime.Equal(last.StartTime) {
+			return fmt.Errorf("migration start time changes from %s to %s", last.StartTime, info.StartTime)
+		}
+		if len(info.Jobs) != len(last.Jobs) {
+			return fmt.Errorf("number of jobs changes from %d to %d", len(last.Jobs), len(info.Jobs))
+		}
+		for i, job := range info.Jobs {
+			previous := last.Jobs[i]
+			if slices.Index(jobStatuses, job.Status) < slices.Index(jobStatuses, previous.Status) {
+				return fmt.Errorf("status of job %s goes back from %q to %q", job.JobID, previous.Status, job.Status)
+			}
+			if !previous.StartTime.IsZero() && !job.StartTime.Equal(previous.StartTime) {
+				return fmt.Errorf("start time of job %s changes from %s to %s", job.JobID, previous.StartTime, job.StartTime)
+			}
+		}
+		return nil
+	}
+}
+
+var (
+	migrationStatuses = []cluster.PartitionMigrationStatus{
+		cluster.PartitionMigrationStatusNew,
+		cluster.PartitionMigrationStatusReloadingGW,
+		cluster.PartitionMigrationStatusReloadingSrcRouter,
+		cluster.PartitionMigrationStatusMigrating,
+		cluster.PartitionMigrationStatusCompleted,
+	}
+	jobStatuses = []cluster.PartitionMigrationJobStatus{
+		"", // not started yet
+		cluster.PartitionMigrationJobStatusNew,
+		cluster.PartitionMigrationJobStatusMoved,
+		cluster.PartitionMigrationJobStatusCompleted,
+	}
+)
+
+// checkInvariants checks the invariants after a step.
+func (sim *simulation) checkInvariants(ctx context.Context) error {
+	state := sim.state()
+	for _, invariant := range sim.invariants {
+		if err := invariant(ctx, state); err != nil {
+			return fmt.Errorf("step %d: %w: %w", sim.step, ErrInvariantViolated, err)
+		}
+	}
+	return nil
+}
+
+// checkCompleted checks the outcome of a completed migration: all of its jobs are completed
+// and their partitions are owned by their target nodes only.
+func (sim *simulation) checkCompleted() error {
+	for _, job := range sim.coordinator.info.Jobs {
+		if job.Status != cluster.PartitionMigrationJobStatusCompleted {
+			return fmt.Errorf("%w: job %s is %q once the migration is completed", ErrInvariantViolated, job.JobID, job.Status)
+		}
+		for _, partition := range job.Partitions {
+			if owners := sim.owners[partition]; !slices.Equal(owners, []int{job.TargetNode}) {
+				return fmt.Errorf("%w: partition %s is owned by nodes %v instead of %d once the migration is completed",
+					ErrInvariantViolated, partition, owners, job.TargetNode)
+			}
+		}
+	}
+	return nil
+}
+
+func (sim *simulation) state() *State {
+	state := &State{
+		Step:      sim.step,
+		Now:       sim.now,
+		Store:     sim.store.MemoryStore,
+		Layout:    sim.layout,
+		Migration: sim.migration,
+		Owners:    make(map[string][]int, len(sim.owners)),
+	}
+	for _, n := range sim.nodes {
+		switch n.role {
+		case roleGateway:
+			state.GatewayNodes = append(state.GatewayNodes, n.index)
+		case roleSrcRouter:
+			state.SrcRouterNodes = append(state.SrcRouterNodes, n.name)
+		}
+	}
+	for partition, owners := range sim.owners {
+		state.Owners[partition] = slices.Clone(owners)
+	}
+	return state
+}
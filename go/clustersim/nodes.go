@@ -0,0 +1,250 @@
+package clustersim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/rudderlabs/rudder-schemas/go/cluster"
+)
+
+type role int
+
+const (
+	roleProcessor role = iota
+	roleGateway
+	roleSrcRouter
+)
+
+// maxDuplicateDelay is the maximum number of steps before a duplicate write lands, see Faults.DuplicateAck.
+const maxDuplicateDelay = 50
+
+// node is a simulated node, running a cluster.Agent with the callbacks of its role. Like the coordinator,
+// the agent runs in its own goroutine, but only while the simulation waits for it to yield,
+// which it does when it watches the store, when it is done with an event of its watch or when it returns.
+type node struct {
+	role    role
+	index   int
+	name    string
+	agent   *cluster.Agent
+	running bool
+	cancel  context.CancelFunc
+	yields  chan yield
+	watch   *watch // the watch the agent reads, once it handled the documents already in the store
+}
+
+// duplicate is a write of a node delivered again at a later step.
+type duplicate struct {
+	node  string
+	key   string
+	value []byte
+	step  int
+}
+
+func (sim *simulation) newNode(role role, index int, name string) (*node, error) {
+	n := &node{role: role, index: index, name: name, yields: make(chan yield)}
+	var opts []func(a *cluster.Agent)
+	switch role {
+	case roleProcessor:
+		opts = append(opts,
+			cluster.WithAgentPartitionsMovedOut(func(_ context.Context, job *cluster.PartitionMigrationJob) error {
+				sim.logf("%s moves out the partitions of job %s", name, job.JobID)
+				sim.release(index, job.Partitions)
+				return nil
+			}),
+			cluster.WithAgentPartitionsMovedIn(func(_ context.Context, job *cluster.PartitionMigrationJob) error {
+				sim.logf("%s moves in the partitions of job %s", name, job.JobID)
+				sim.acquire(index, job.Partitions)
+				return nil
+			}),
+		)
+	case roleGateway:
+		opts = append(opts, cluster.WithAgentReloadGateway(func(context.Context, *cluster.ReloadGatewayCommand) error {
+			sim.logf("%s reloads its gateway", name)
+			return nil
+		}))
+	case roleSrcRouter:
+		opts = append(opts, cluster.WithAgentReloadSrcRouter(func(context.Context, *cluster.ReloadSrcRouterCommand) error {
+			sim.logf("%s reloads its source router", name)
+			return nil
+		}))
+	}
+	store := &nodeStore{sim: sim, node: n}
+	agent, err := cluster.NewAgent(store, Root, index, name, append(opts, cluster.WithAgentEventHandled(store.handled))...)
+	if err != nil {
+		return nil, err
+	}
+	n.agent = agent
+	return n, nil
+}
+
+// startNode runs the agent of a node until it watches the store or returns.
+func (sim *simulation) startNode(ctx context.Context, n *node) error {
+	ctx, cancel := context.WithCancel(ctx)
+	n.running, n.cancel = true, cancel
+	go func() {
+		n.yields <- yield{err: n.agent.Run(ctx)}
+	}()
+	y := <-n.yields
+	if n.watch = y.watch; y.watch != nil {
+		return nil
+	}
+	return sim.nodeStopped(n, y.err)
+}
+
+// nodeStopped records that the agent of a node returned, which is an error unless it crashed.
+func (sim *simulation) nodeStopped(n *node, err error) error {
+	n.running, n.watch = false, nil
+	n.cancel()
+	if !errors.Is(err, errCrash) {
+		return fmt.Errorf("%s: %w", n.name, err)
+	}
+	return nil
+}
+
+// stopNode stops the agent of a node, if it is running.
+func (sim *simulation) stopNode(n *node) {
+	if !n.running {
+		return
+	}
+	n.cancel()
+	close(n.watch.events)
+	<-n.yields
+	n.running, n.watch = false, nil
+}
+
+// restartNode restarts the agent of a node, as an operator would do.
+func (sim *simulation) restartNode(ctx context.Context, n *node) error {
+	sim.stopNode(n)
+	return sim.startNode(ctx, n)
+}
+
+// nodeAction returns the possible action of a node, if any.
+func (sim *simulation) nodeAction(n *node) *action {
+	if !n.running {
+		return &action{
+			description: n.name + " restarts",
+			run:         func(ctx context.Context) error { return sim.startNode(ctx, n) },
+		}
+	}
+	w := n.watch
+	e, ok := sim.store.next(w.prefix, w.revision)
+	if !ok || e.observedAt > sim.step {
+		return nil
+	}
+	return &action{
+		description: fmt.Sprintf("%s observes the %s of %s at revision %d", n.name, e.Type, e.Key, e.Revision),
+		run: func(ctx context.Context) error {
+			w.revision = e.Revision + 1
+			w.events <- e.WatchEvent
+			if y := <-n.yields; y.watch == nil {
+				return sim.nodeStopped(n, y.err)
+			}
+			return nil
+		},
+	}
+}
+
+// deliverDuplicates writes again the duplicate writes of nodes landing at the current step.
+func (sim *simulation) deliverDuplicates(ctx context.Context) error {
+	var err error
+	sim.duplicates = slices.DeleteFunc(sim.duplicates, func(d duplicate) bool {
+		if d.step > sim.step || err != nil {
+			return false
+		}
+		sim.logf("duplicate write of %s by %s lands", d.key, d.node)
+		_, err = sim.store.Put(ctx, d.key, d.value)
+		return true
+	})
+	return err
+}
+
+// nodeStore is the store of a node, injecting the faults of its writes and yielding to the simulation when it watches
+// and once it is done with an event.
+type nodeStore struct {
+	sim  *simulation
+	node *node
+}
+
+var _ cluster.Store = (*nodeStore)(nil)
+
+func (s *nodeStore) Get(ctx context.Context, key string) (cluster.KeyValue, error) {
+	return s.sim.store.Get(ctx, key)
+}
+
+func (s *nodeStore) List(ctx context.Context, prefix string) ([]cluster.KeyValue, int64, error) {
+	return s.sim.store.List(ctx, prefix)
+}
+
+func (s *nodeStore) Put(ctx context.Context, key string, value []byte) (int64, error) {
+	return s.write(key, value, func() (int64, error) { return s.sim.store.Put(ctx, key, value) })
+}
+
+func (s *nodeStore) CompareAndSwap(ctx context.Context, key string, revision int64, value []byte) (int64, error) {
+	return s.write(key, value, func() (int64, error) { return s.sim.store.CompareAndSwap(ctx, key, revision, value) })
+}
+
+func (s *nodeStore) Delete(ctx context.Context, key string) (int64, error) {
+	return s.write(key, nil, func() (int64, error) { return s.sim.store.Delete(ctx, key) })
+}
+
+// write performs a write of the node, unless it crashes before writing or the write is lost,
+// and queues its duplicate, if any.
+func (s *nodeStore) write(key string, value []byte, write func() (int64, error)) (int64, error) {
+	sim, n := s.sim, s.node
+	sim.writes++
+	if sim.roll(sim.faults.NodeCrash) {
+		sim.logf("%s crashes before writing %s", n.name, key)
+		return 0, fmt.Errorf("writing %s: %w", key, errCrash)
+	}
+	if sim.roll(sim.faults.DropAck) {
+		sim.logf("write of %s by %s is lost", key, n.name)
+		return 0, nil
+	}
+	revision, err := write()
+	if errors.Is(err, cluster.ErrRevisionConflict) {
+		sim.logf("write of %s by %s conflicts", key, n.name)
+	}
+	if err != nil {
+		return 0, err
+	}
+	sim.logf("%s writes %s", n.name, key)
+	if value != nil && sim.roll(sim.faults.DuplicateAck) {
+		step := sim.step + 1 + sim.rand.Intn(maxDuplicateDelay)
+		sim.logf("write of %s by %s is duplicated, landing at step %d", key, n.name, step)
+		sim.duplicates = append(sim.duplicates, duplicate{node: n.name, key: key, value: slices.Clone(value), step: step})
+	}
+	return revision, nil
+}
+
+func (s *nodeStore) Watch(ctx context.Context, prefix string, revision int64) (<-chan cluster.WatchEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	w := &watch{prefix: prefix, revision: revision, events: make(chan cluster.WatchEvent)}
+	s.node.yields <- yield{watch: w}
+	return w.events, nil
+}
+
+// handled yields to the simulation once the agent is done with an event, waiting for the next one of its watch.
+func (s *nodeStore) handled(cluster.WatchEvent) {
+	s.node.yields <- yield{watch: s.node.watch}
+}
+
+// release releases the ownership of partitions by a node.
+func (sim *simulation) release(node int, partitions []string) {
+	for _, p := range partitions {
+		sim.owners[p] = slices.DeleteFunc(sim.owners[p], func(owner int) bool { return owner == node })
+	}
+}
+
+// acquire acquires the ownership of partitions by a node.
+func (sim *simulation) acquire(node int, partitions []string) {
+	for _, p := range partitions {
+		if !slices.Contains(sim.owners[p], node) {
+			sim.owners[p] = append(sim.owners[p], node)
+			slices.Sort(sim.owners[p])
+		}
+	}
+}
@@ -0,0 +1,304 @@
+// Package clustersim simulates partition migrations: it runs a cluster.Coordinator along with the cluster.Agent of
+// every gateway, source router and processor node over a cluster.MemoryStore, injecting faults, see Faults.
+//
+// Simulations are deterministic for a given seed. The coordinator and the agents never run concurrently:
+// at every step, one of the possible actions is chosen at random, either delivering a watch event to the coordinator
+// or to an agent, or restarting one of them after a crash. Invariants are checked after every step, see Invariant.
+package clustersim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/rudderlabs/rudder-schemas/go/cluster"
+)
+
+var (
+	ErrInvariantViolated = errors.New("invariant violated")
+	ErrDeadlock          = errors.New("deadlock")
+	ErrMaxSteps          = errors.New("maximum number of steps reached")
+)
+
+// Root is the root prefix of the keys of simulated migrations, see cluster.KeyLayout.
+const Root = "/migrations"
+
+// startTime is the time of the simulated clock when a simulation starts.
+var startTime = time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)
+
+// Faults configures the faults injected in a simulation. Probabilities are between 0 and 1.
+type Faults struct {
+	DropAck          float64       // probability that a write of a node, an ack or a job update, is lost while the node believes it written
+	DuplicateAck     float64       // probability that a write of a node, an ack or a job update, lands again at a random later step
+	NodeCrash        float64       // probability that a node crashes on a write, after acting on the document but before writing
+	CoordinatorCrash float64       // probability, at every step, that the coordinator crashes on its next write
+	WatchDelay       int           // maximum number of steps before a modification is observed by the coordinator and the nodes
+	ClockJump        float64       // probability, at every step, that the clock jumps
+	MaxClockJump     time.Duration // maximum jump of the clock, forwards or backwards
+}
+
+// Simulator simulates a partition migration, see Run.
+type Simulator struct {
+	migration      *cluster.PartitionMigration
+	seed           int64
+	gatewayNodes   int
+	srcRouterNodes int
+	faults         Faults
+	maxSteps       int
+	invariants     []Invariant
+}
+
+// NewSimulator returns a Simulator of the given new migration. Its processor nodes are the source and target nodes
+// of the migration, there is one gateway and one source router node by default.
+func NewSimulator(pm *cluster.PartitionMigration, opt ...func(s *Simulator)) *Simulator {
+	s := &Simulator{
+		migration:      pm,
+		gatewayNodes:   1,
+		srcRouterNodes: 1,
+		maxSteps:       10000,
+	}
+	for _, o := range opt {
+		o(s)
+	}
+	return s
+}
+
+// WithSimulatorSeed sets the seed of the random choices of the simulation.
+func WithSimulatorSeed(seed int64) func(s *Simulator) {
+	return func(s *Simulator) {
+		s.seed = seed
+	}
+}
+
+// WithSimulatorGatewayNodes sets the number of gateway nodes, with indexes from 0.
+func WithSimulatorGatewayNodes(n int) func(s *Simulator) {
+	return func(s *Simulator) {
+		s.gatewayNodes = n
+	}
+}
+
+// WithSimulatorSrcRouterNodes sets the number of source router nodes.
+func WithSimulatorSrcRouterNodes(n int) func(s *Simulator) {
+	return func(s *Simulator) {
+		s.srcRouterNodes = n
+	}
+}
+
+// WithSimulatorFaults sets the faults to inject, none by default.
+func WithSimulatorFaults(faults Faults) func(s *Simulator) {
+	return func(s *Simulator) {
+		s.faults = faults
+	}
+}
+
+// WithSimulatorMaxSteps sets the maximum number of steps of the simulation.
+func WithSimulatorMaxSteps(n int) func(s *Simulator) {
+	return func(s *Simulator) {
+		s.maxSteps = n
+	}
+}
+
+// WithSimulatorInvariants adds invariants to check after every step, in addition to DefaultInvariants.
+func WithSimulatorInvariants(invariants ...Invariant) func(s *Simulator) {
+	return func(s *Simulator) {
+		s.invariants = append(s.invariants, invariants...)
+	}
+}
+
+// Result is the outcome of a simulation.
+type Result struct {
+	Info  *cluster.PartitionMigrationInfo // final information of the migration, nil unless it completed
+	Steps int                             // number of steps simulated
+	Trace []string                        // what happened, step by step
+}
+
+// Run simulates the migration until it is completed, returning an error if an invariant is violated,
+// the simulation can't make progress or exceeds its maximum number of steps. The result is returned in any case,
+// its trace being the same for the same seed.
+func (s *Simulator) Run(ctx context.Context) (*Result, error) {
+	sim, err := s.newSimulation()
+	if err != nil {
+		return nil, err
+	}
+	err = sim.run(ctx)
+	sim.stopCoordinator()
+	for _, n := range sim.nodes {
+		sim.stopNode(n)
+	}
+	return &Result{Info: sim.coordinator.info, Steps: sim.step, Trace: sim.trace}, err
+}
+
+// simulation is the state of a running simulation.
+type simulation struct {
+	*Simulator
+	rand       *rand.Rand
+	layout     *cluster.KeyLayout
+	store      *store
+	step       int
+	now        time.Time
+	nodes      []*node
+	owners     map[string][]int // indexes of the nodes owning each partition
+	duplicates []duplicate      // duplicate writes of nodes, not landed yet
+	writes     int              // writes attempted by the coordinator and the nodes, lost or not
+	invariants []Invariant
+	trace      []string
+
+	coordinator coordinator
+}
+
+func (s *Simulator) newSimulation() (*simulation, error) {
+	layout, err := cluster.NewKeyLayout(Root, s.migration.ID)
+	if err != nil {
+		return nil, err
+	}
+	sim := &simulation{
+		Simulator:  s,
+		rand:       rand.New(rand.NewSource(s.seed)), // #nosec G404 -- reproducible simulation, not security sensitive
+		layout:     layout,
+		now:        startTime,
+		owners:     make(map[string][]int),
+		invariants: append(DefaultInvariants(), s.invariants...),
+	}
+	sim.store = newStore(sim.observedAt)
+	sim.coordinator.yields = make(chan yield)
+
+	processors := lo.Uniq(append(s.migration.SourceNodes(), s.migration.TargetNodes()...))
+	slices.Sort(processors)
+	addNode := func(role role, index int, name string) error {
+		n, err := sim.newNode(role, index, name)
+		if err == nil {
+			sim.nodes = append(sim.nodes, n)
+		}
+		return err
+	}
+	for _, index := range processors {
+		if err := addNode(roleProcessor, index, fmt.Sprintf("node-%d", index)); err != nil {
+			return nil, err
+		}
+	}
+	for index := range s.gatewayNodes {
+		if err := addNode(roleGateway, index, fmt.Sprintf("gw-%d", index)); err != nil {
+			return nil, err
+		}
+	}
+	for index := range s.srcRouterNodes {
+		if err := addNode(roleSrcRouter, index, fmt.Sprintf("srcrouter-%d", index)); err != nil {
+			return nil, err
+		}
+	}
+	for _, job := range s.migration.Jobs {
+		for _, partition := range job.Partitions {
+			sim.owners[partition] = []int{job.SourceNode}
+		}
+	}
+	return sim, nil
+}
+
+// action is a possible step of a simulation.
+type action struct {
+	description string
+	run         func(ctx context.Context) error
+}
+
+func (sim *simulation) run(ctx context.Context) error {
+	sim.logf("coordinator starts")
+	if err := sim.startCoordinator(ctx); err != nil {
+		return err
+	}
+	for _, n := range sim.nodes {
+		sim.logf("%s starts", n.name)
+		if err := sim.startNode(ctx, n); err != nil {
+			return err
+		}
+	}
+	restartWrites := -1 // writes attempted when the nodes were last restarted, to detect restarts without effect
+	for sim.coordinator.info == nil {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if sim.step >= sim.maxSteps {
+			return fmt.Errorf("%w: %d", ErrMaxSteps, sim.maxSteps)
+		}
+		sim.step++
+		sim.now = sim.now.Add(time.Second)
+		sim.injectFaults()
+		if err := sim.deliverDuplicates(ctx); err != nil {
+			return fmt.Errorf("step %d: %w", sim.step, err)
+		}
+
+		switch actions := sim.actions(); {
+		case len(actions) > 0:
+			a := actions[sim.rand.Intn(len(actions))]
+			sim.logf("%s", a.description)
+			if err := a.run(ctx); err != nil {
+				return fmt.Errorf("step %d: %w", sim.step, err)
+			}
+		case sim.store.delayed(sim.step) || len(sim.duplicates) > 0:
+			sim.logf("waiting for delayed modifications")
+		case restartWrites == sim.writes:
+			return fmt.Errorf("step %d: %w: neither the coordinator nor the nodes can make progress", sim.step, ErrDeadlock)
+		default:
+			// nodes which lost writes don't know it until they restart, as an operator would restart them
+			sim.logf("stalled, all nodes restart")
+			restartWrites = sim.writes
+			for _, n := range sim.nodes {
+				if err := sim.restartNode(ctx, n); err != nil {
+					return fmt.Errorf("step %d: %w", sim.step, err)
+				}
+			}
+		}
+		if err := sim.checkInvariants(ctx); err != nil {
+			return err
+		}
+	}
+	return sim.checkCompleted()
+}
+
+// injectFaults injects the faults happening independently of the actions of a step.
+func (sim *simulation) injectFaults() {
+	if sim.roll(sim.faults.ClockJump) && sim.faults.MaxClockJump > 0 {
+		jump := time.Duration(sim.rand.Int63n(int64(2*sim.faults.MaxClockJump)+1)) - sim.faults.MaxClockJump
+		sim.now = sim.now.Add(jump)
+		sim.logf("clock jumps by %s", jump)
+	}
+	if sim.coordinator.running && !sim.coordinator.crashing && sim.roll(sim.faults.CoordinatorCrash) {
+		sim.coordinator.crashing = true
+		sim.logf("coordinator will crash on its next write")
+	}
+}
+
+// actions returns the possible actions of the current step, in a deterministic order.
+func (sim *simulation) actions() []action {
+	var actions []action
+	if a := sim.coordinatorAction(); a != nil {
+		actions = append(actions, *a)
+	}
+	for _, n := range sim.nodes {
+		if a := sim.nodeAction(n); a != nil {
+			actions = append(actions, *a)
+		}
+	}
+	return actions
+}
+
+// roll returns true with the given probability.
+func (sim *simulation) roll(probability float64) bool {
+	return probability > 0 && sim.rand.Float64() < probability
+}
+
+// observedAt returns the step at which a modification happening now is observed.
+func (sim *simulation) observedAt() int {
+	if sim.faults.WatchDelay <= 0 {
+		return sim.step
+	}
+	return sim.step + sim.rand.Intn(sim.faults.WatchDelay+1)
+}
+
+func (sim *simulation) logf(format string, a ...any) {
+	sim.trace = append(sim.trace, fmt.Sprintf("%d: ", sim.step)+fmt.Sprintf(format, a...))
+}
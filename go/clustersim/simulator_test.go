@@ -0,0 +1,169 @@
+package clustersim_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-schemas/go/cluster"
+	"github.com/rudderlabs/rudder-schemas/go/clustersim"
+	"github.com/rudderlabs/rudder-schemas/go/schemastest"
+)
+
+var allFaults = clustersim.Faults{
+	DropAck:          0.1,
+	DuplicateAck:     0.1,
+	NodeCrash:        0.1,
+	CoordinatorCrash: 0.05,
+	WatchDelay:       3,
+	ClockJump:        0.05,
+	MaxClockJump:     time.Hour,
+}
+
+func TestSimulator(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("without faults", func(t *testing.T) {
+		pm := schemastest.NewPartitionMigration()
+		result, err := clustersim.NewSimulator(pm, clustersim.WithSimulatorGatewayNodes(2)).Run(ctx)
+		require.NoError(t, err, strings.Join(result.Trace, "\n"))
+		requireCompleted(t, pm, result)
+	})
+
+	t.Run("with faults", func(t *testing.T) {
+		injected := make(map[string]bool)
+		for seed := range int64(50) {
+			pm := schemastest.NewPartitionMigration()
+			if seed > 0 {
+				pm = schemastest.PartitionMigration(schemastest.NewRand(seed))
+				pm.Status, pm.PreviousStatus, pm.AckKeyPrefix = cluster.PartitionMigrationStatusNew, "", ""
+			}
+			result, err := clustersim.NewSimulator(pm,
+				clustersim.WithSimulatorSeed(seed),
+				clustersim.WithSimulatorGatewayNodes(3),
+				clustersim.WithSimulatorSrcRouterNodes(2),
+				clustersim.WithSimulatorFaults(allFaults),
+			).Run(ctx)
+			require.NoError(t, err, "seed %d:\n%s", seed, strings.Join(result.Trace, "\n"))
+			requireCompleted(t, pm, result)
+
+			for _, line := range result.Trace {
+				for _, fault := range []string{"is lost", "is duplicated", "lands", "crashes before writing", "coordinator crashes", "clock jumps", "waiting for delayed", "stalled"} {
+					if strings.Contains(line, fault) {
+						injected[fault] = true
+					}
+				}
+			}
+		}
+		require.Len(t, injected, 8, "all faults are injected: %v", injected)
+	})
+
+	t.Run("deterministic", func(t *testing.T) {
+		pm := schemastest.PartitionMigration(schemastest.NewRand(42))
+		pm.Status, pm.PreviousStatus, pm.AckKeyPrefix = cluster.PartitionMigrationStatusNew, "", ""
+		run := func(seed int64) *clustersim.Result {
+			result, err := clustersim.NewSimulator(pm,
+				clustersim.WithSimulatorSeed(seed),
+				clustersim.WithSimulatorFaults(allFaults),
+			).Run(ctx)
+			require.NoError(t, err)
+			return result
+		}
+		first := run(1)
+		for range 5 {
+			require.Equal(t, first, run(1))
+		}
+		require.NotEqual(t, first.Trace, run(2).Trace)
+	})
+
+	t.Run("invariant violations", func(t *testing.T) {
+		pm := schemastest.NewPartitionMigration()
+		violation := errors.New("violation")
+		result, err := clustersim.NewSimulator(pm,
+			clustersim.WithSimulatorInvariants(func(ctx context.Context, state *clustersim.State) error {
+				if state.Step == 5 {
+					return violation
+				}
+				return nil
+			}),
+		).Run(ctx)
+		require.ErrorIs(t, err, clustersim.ErrInvariantViolated)
+		require.ErrorIs(t, err, violation)
+		require.ErrorContains(t, err, "step 5")
+		require.Equal(t, 5, result.Steps)
+		require.Nil(t, result.Info)
+	})
+
+	t.Run("default invariants", func(t *testing.T) {
+		pm := schemastest.NewPartitionMigration()
+		store := cluster.NewMemoryStore()
+		layout, err := cluster.NewKeyLayout(clustersim.Root, pm.ID)
+		require.NoError(t, err)
+		state := &clustersim.State{
+			Store:          store,
+			Layout:         layout,
+			Migration:      pm,
+			GatewayNodes:   []int{0},
+			SrcRouterNodes: []string{"srcrouter-0"},
+			Owners:         map[string][]int{"workspaceID-0": {0}, "workspaceID-1": {0, 1}},
+		}
+		require.ErrorContains(t, clustersim.ExclusiveOwnership(ctx, state), "partition workspaceID-1 is owned by nodes [0 1]")
+
+		_, err = cluster.PutJSON(ctx, store, layout.ReloadGatewayKey(), layout.ReloadGatewayCommand(state.GatewayNodes))
+		require.NoError(t, err)
+		require.ErrorContains(t, clustersim.OrderedProtocol(ctx, state), "reload-gw is written before the acks of nodes [0 1 2]")
+		published := pm.Clone()
+		published.AckKeyPrefix = layout.MigrationAckKeyPrefix()
+		for _, node := range []int{0, 1, 2} {
+			name := fmt.Sprintf("node-%d", node)
+			_, err = cluster.PutJSON(ctx, store, published.AckKey(name), published.Ack(node, name))
+			require.NoError(t, err)
+		}
+		require.NoError(t, clustersim.OrderedProtocol(ctx, state))
+
+		monotonic := clustersim.MonotonicProgress()
+		var info cluster.PartitionMigrationInfo
+		info.FromPartitionMigration(*pm, nil)
+		info.Status = cluster.PartitionMigrationStatusReloadingGW
+		_, err = cluster.PutJSON(ctx, store, layout.MigrationInfoKey(), &info)
+		require.NoError(t, err)
+		require.NoError(t, monotonic(ctx, state))
+		info.Status = cluster.PartitionMigrationStatusNew
+		_, err = cluster.PutJSON(ctx, store, layout.MigrationInfoKey(), &info)
+		require.NoError(t, err)
+		require.ErrorContains(t, monotonic(ctx, state), `migration status goes back from "reloading-gw" to "new"`)
+		jobs := len(info.Jobs)
+		info.Jobs = info.Jobs[1:]
+		_, err = cluster.PutJSON(ctx, store, layout.MigrationInfoKey(), &info)
+		require.NoError(t, err)
+		require.ErrorContains(t, monotonic(ctx, state), fmt.Sprintf("number of jobs changes from %d to %d", jobs, jobs-1))
+	})
+
+	t.Run("maximum number of steps", func(t *testing.T) {
+		result, err := clustersim.NewSimulator(schemastest.NewPartitionMigration(), clustersim.WithSimulatorMaxSteps(3)).Run(ctx)
+		require.ErrorIs(t, err, clustersim.ErrMaxSteps)
+		require.Equal(t, 3, result.Steps)
+	})
+
+	t.Run("cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := clustersim.NewSimulator(schemastest.NewPartitionMigration()).Run(ctx)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func requireCompleted(t *testing.T, pm *cluster.PartitionMigration, result *clustersim.Result) {
+	t.Helper()
+	require.NotNil(t, result.Info)
+	require.Equal(t, cluster.PartitionMigrationStatusCompleted, result.Info.Status)
+	require.Len(t, result.Info.Jobs, len(pm.Jobs))
+	for _, job := range result.Info.Jobs {
+		require.Equal(t, cluster.PartitionMigrationJobStatusCompleted, job.Status)
+	}
+}
@@ -0,0 +1,86 @@
+package clustersim
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/rudderlabs/rudder-schemas/go/cluster"
+)
+
+// store is a MemoryStore recording its modifications along with the step at which they are observed,
+// so that watches can be served deterministically by the simulation.
+type store struct {
+	*cluster.MemoryStore
+	observedAt func() int
+	events     []event
+}
+
+type event struct {
+	cluster.WatchEvent
+	observedAt int
+}
+
+func newStore(observedAt func() int) *store {
+	return &store{
+		MemoryStore: cluster.NewMemoryStore(),
+		observedAt:  observedAt,
+	}
+}
+
+func (s *store) Put(ctx context.Context, key string, value []byte) (int64, error) {
+	revision, err := s.MemoryStore.Put(ctx, key, value)
+	if err == nil {
+		s.record(cluster.WatchEventTypePut, key, value, revision)
+	}
+	return revision, err
+}
+
+func (s *store) CompareAndSwap(ctx context.Context, key string, revision int64, value []byte) (int64, error) {
+	revision, err := s.MemoryStore.CompareAndSwap(ctx, key, revision, value)
+	if err == nil {
+		s.record(cluster.WatchEventTypePut, key, value, revision)
+	}
+	return revision, err
+}
+
+func (s *store) Delete(ctx context.Context, key string) (int64, error) {
+	revision, err := s.MemoryStore.Delete(ctx, key)
+	if err == nil {
+		s.record(cluster.WatchEventTypeDelete, key, nil, revision)
+	}
+	return revision, err
+}
+
+// record records a modification, observed no sooner than the previous ones so that they are observed in order.
+func (s *store) record(typ cluster.WatchEventType, key string, value []byte, revision int64) {
+	observedAt := s.observedAt()
+	if len(s.events) > 0 {
+		observedAt = max(observedAt, s.events[len(s.events)-1].observedAt)
+	}
+	s.events = append(s.events, event{
+		WatchEvent: cluster.WatchEvent{
+			Type:     typ,
+			KeyValue: cluster.KeyValue{Key: key, Value: slices.Clone(value), Revision: revision},
+		},
+		observedAt: observedAt,
+	})
+}
+
+// next returns the first modification of the keys with the given prefix from the given revision.
+func (s *store) next(prefix string, revision int64) (event, bool) {
+	i, _ := slices.BinarySearchFunc(s.events, revision, func(e event, revision int64) int {
+		return int(e.Revision - revision)
+	})
+	for _, e := range s.events[i:] {
+		if strings.HasPrefix(e.Key, prefix) {
+			return e, true
+		}
+	}
+	return event{}, false
+}
+
+// delayed returns whether some modifications are not observed yet at the given step.
+func (s *store) delayed(step int) bool {
+	return len(s.events) > 0 && s.events[len(s.events)-1].observedAt > step
+}
@@ -0,0 +1,77 @@
+// Package compattest loads the golden compatibility corpora of the schema packages, made of one JSON file
+// per released version of the library that changed the shape of their documents, and of a head.json file
+// holding the shapes of the upcoming version, if they changed since the last release.
+package compattest
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// HeadVersion is the version of the shapes of the upcoming version, in head.json. The file is renamed after
+// the version once it is released.
+const HeadVersion = "head"
+
+// Version is the content of the corpus file of a version.
+type Version[T any] struct {
+	Version string // version of the form v<major>.<minor>.<patch>, or HeadVersion, named after the file
+	Content T
+}
+
+// Load decodes the v*.json files of dir, sorted by version, followed by head.json if it exists.
+// The corpus must have at least one file, and no file can be empty.
+func Load[T any](t testing.TB, dir string) []Version[T] {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "v*.json"))
+	require.NoError(t, err)
+	slices.SortFunc(files, func(a, b string) int {
+		return CompareVersions(t, versionOf(a), versionOf(b))
+	})
+	head := filepath.Join(dir, HeadVersion+".json")
+	if _, err := os.Stat(head); !errors.Is(err, fs.ErrNotExist) {
+		require.NoError(t, err)
+		files = append(files, head)
+	}
+	require.NotEmpty(t, files, "%s has no corpus files", dir)
+
+	corpus := make([]Version[T], 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		v := Version[T]{Version: versionOf(file)}
+		require.NoError(t, json.Unmarshal(data, &v.Content), "parsing %s", file)
+		require.NotEmpty(t, v.Content, "%s is empty", file)
+		corpus = append(corpus, v)
+	}
+	return corpus
+}
+
+func versionOf(file string) string {
+	return strings.TrimSuffix(filepath.Base(file), ".json")
+}
+
+// CompareVersions compares two versions of the form v<major>.<minor>.<patch>.
+func CompareVersions(t testing.TB, a, b string) int {
+	t.Helper()
+	parse := func(v string) []int {
+		parts := strings.Split(strings.TrimPrefix(v, "v"), ".")
+		require.Len(t, parts, 3, "invalid version %q", v)
+		var nums []int
+		for _, p := range parts {
+			n, err := strconv.Atoi(p)
+			require.NoError(t, err, "invalid version %q", v)
+			nums = append(nums, n)
+		}
+		return nums
+	}
+	return slices.Compare(parse(a), parse(b))
+}
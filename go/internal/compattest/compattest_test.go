@@ -0,0 +1,38 @@
+package compattest_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-schemas/go/internal/compattest"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"v0.10.0.json": `["c"]`,
+		"v0.2.1.json":  `["b"]`,
+		"v0.2.0.json":  `["a"]`,
+		"README.md":    `ignored`,
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+	require.Equal(t, []compattest.Version[[]string]{
+		{Version: "v0.2.0", Content: []string{"a"}},
+		{Version: "v0.2.1", Content: []string{"b"}},
+		{Version: "v0.10.0", Content: []string{"c"}},
+	}, compattest.Load[[]string](t, dir), "versions are compared numerically")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "head.json"), []byte(`["d"]`), 0o600))
+	require.Equal(t, compattest.Version[[]string]{Version: compattest.HeadVersion, Content: []string{"d"}},
+		compattest.Load[[]string](t, dir)[3], "the upcoming version comes last")
+}
+
+func TestCompareVersions(t *testing.T) {
+	require.Equal(t, -1, compattest.CompareVersions(t, "v0.9.9", "v0.10.0"))
+	require.Equal(t, 0, compattest.CompareVersions(t, "v1.2.3", "v1.2.3"))
+	require.Equal(t, 1, compattest.CompareVersions(t, "v2.0.0", "v1.99.99"))
+}
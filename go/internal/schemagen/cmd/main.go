@@ -0,0 +1,58 @@
+// Command schemagen regenerates the files under the schemas directory from the go schema types.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/rudderlabs/rudder-schemas/go/internal/schemagen"
+)
+
+func main() {
+	srcRoot := flag.String("src", "go", "directory containing the go packages")
+	outDir := flag.String("out", schemagen.OutputDir, "output directory")
+	flag.Parse()
+
+	if err := run(*srcRoot, *outDir); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(srcRoot, outDir string) error {
+	files, err := schemagen.Generate(srcRoot)
+	if err != nil {
+		return err
+	}
+
+	// remove files of types that no longer exist
+	if err := filepath.WalkDir(outDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		name, err := filepath.Rel(outDir, path)
+		if err != nil {
+			return err
+		}
+		if _, ok := files[filepath.ToSlash(name)]; !ok {
+			return os.Remove(path)
+		}
+		return nil
+	}); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cleaning %s: %w", outDir, err)
+	}
+
+	for name, data := range files {
+		path := filepath.Join(outDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
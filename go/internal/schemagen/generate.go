@@ -0,0 +1,34 @@
+package schemagen
+
+import (
+	"fmt"
+	"path"
+)
+
+// OutputDir is the directory, relative to the repository root, where generated files are written.
+const OutputDir = "schemas"
+
+// Generate renders all generated files for the registered types.
+// srcRoot is the directory containing the module's go packages, files are keyed by their path relative to OutputDir.
+func Generate(srcRoot string) (map[string][]byte, error) {
+	m, err := Load(srcRoot, Types)
+	if err != nil {
+		return nil, fmt.Errorf("loading model: %w", err)
+	}
+
+	files := map[string][]byte{}
+	jsonSchemas, err := JSONSchemaFiles(m)
+	if err != nil {
+		return nil, fmt.Errorf("rendering json schemas: %w", err)
+	}
+	for name, data := range jsonSchemas {
+		files[path.Join("jsonschema", name)] = data
+	}
+	for name, data := range TypeScriptFiles(m) {
+		files[path.Join("typescript", name)] = data
+	}
+	for name, data := range PythonFiles(m) {
+		files[path.Join("python", name)] = data
+	}
+	return files, nil
+}
@@ -0,0 +1,144 @@
+package schemagen
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// jsonSchema is a JSON Schema (draft 2020-12) document or subschema.
+// Fields are declared in the order they are rendered.
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 any                    `json:"type,omitempty"` // a type name or a list of type names
+	Const                *string                `json:"const,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Minimum              *int                   `json:"minimum,omitempty"`
+	Properties           *jsonSchemaProperties  `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	AdditionalProperties *jsonSchema            `json:"additionalProperties,omitempty"`
+	AnyOf                []*jsonSchema          `json:"anyOf,omitempty"`
+	Defs                 map[string]*jsonSchema `json:"$defs,omitempty"`
+}
+
+// jsonSchemaProperties are the properties of an object schema, rendered in field declaration order.
+type jsonSchemaProperties struct {
+	names   []string
+	schemas []*jsonSchema
+}
+
+func (p *jsonSchemaProperties) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, name := range p.names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(p.schemas[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// JSONSchemaFiles renders a self-contained JSON Schema document for every type of the model.
+// Files are keyed by their path, <package>/<Type>.json, referenced types are embedded as $defs.
+func JSONSchemaFiles(m *Model) (map[string][]byte, error) {
+	files := make(map[string][]byte, len(m.Types))
+	for _, t := range m.Types {
+		doc := typeJSONSchema(t)
+		doc.Schema = jsonSchemaDraft
+		doc.Title = t.QualifiedName()
+		if deps := m.Dependencies(t); len(deps) > 0 {
+			doc.Defs = make(map[string]*jsonSchema, len(deps))
+			for _, dep := range deps {
+				def := typeJSONSchema(dep)
+				def.Title = dep.QualifiedName()
+				doc.Defs[dep.QualifiedName()] = def
+			}
+		}
+
+		var buf bytes.Buffer
+		e := json.NewEncoder(&buf)
+		e.SetEscapeHTML(false)
+		e.SetIndent("", "  ")
+		if err := e.Encode(doc); err != nil {
+			return nil, err
+		}
+		files[path.Join(t.Package, t.Name+".json")] = buf.Bytes()
+	}
+	return files, nil
+}
+
+func typeJSONSchema(t *Type) *jsonSchema {
+	s := &jsonSchema{Description: t.Doc}
+	switch t.Kind {
+	case KindEnum:
+		s.Type = "string"
+		for _, v := range t.Values {
+			s.Enum = append(s.Enum, v.Value)
+		}
+	case KindStruct:
+		s.Type = "object"
+		s.Properties = &jsonSchemaProperties{}
+		for _, f := range t.Fields {
+			fs := refJSONSchema(f.Type)
+			fs.Description = f.Doc
+			fs.Minimum = f.Min
+			s.Properties.names = append(s.Properties.names, f.JSONName)
+			s.Properties.schemas = append(s.Properties.schemas, fs)
+			if !f.Optional {
+				s.Required = append(s.Required, f.JSONName)
+			}
+		}
+	}
+	return s
+}
+
+func refJSONSchema(ref *TypeRef) *jsonSchema {
+	var s *jsonSchema
+	switch ref.Kind {
+	case RefString:
+		s = &jsonSchema{Type: "string"}
+	case RefInt:
+		s = &jsonSchema{Type: "integer"}
+	case RefBool:
+		s = &jsonSchema{Type: "boolean"}
+	case RefTime:
+		s = &jsonSchema{Type: "string", Format: "date-time"}
+	case RefJSON:
+		s = &jsonSchema{}
+	case RefArray:
+		s = &jsonSchema{Type: "array", Items: refJSONSchema(ref.Elem)}
+	case RefMap:
+		s = &jsonSchema{Type: "object", AdditionalProperties: refJSONSchema(ref.Elem)}
+	case RefNamed:
+		s = &jsonSchema{Ref: "#/$defs/" + ref.Named.QualifiedName()}
+	}
+	if ref.Empty {
+		empty := ""
+		s = &jsonSchema{AnyOf: []*jsonSchema{s, {Const: &empty}}}
+	}
+	if ref.Nullable {
+		if typeName, ok := s.Type.(string); ok {
+			s.Type = []string{typeName, "null"}
+		}
+	}
+	return s
+}
@@ -0,0 +1,457 @@
+// Package schemagen builds a language neutral model of the public schema types and renders it for other ecosystems.
+package schemagen
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const modulePath = "github.com/rudderlabs/rudder-schemas/"
+
+// Kind is the kind of schema type.
+type Kind int
+
+const (
+	KindStruct Kind = iota // an object with fields
+	KindEnum               // a string with a set of allowed values
+)
+
+// Model is the set of schema types, in registration order.
+type Model struct {
+	Types   []*Type
+	MapKeys map[string][]EnumValue // package name -> keys of the property map encoding, see stream.ToMapProperties
+	index   map[string]*Type
+}
+
+// Type is a named schema type.
+type Type struct {
+	Package string      // go package name, e.g. stream
+	Name    string      // go type name, e.g. Message
+	Doc     string      // documentation of the type
+	Kind    Kind        // kind of the type
+	Fields  []*Field    // fields of a struct type, embedded structs are flattened
+	Values  []EnumValue // allowed values of an enum type, in declaration order
+}
+
+// QualifiedName returns the package qualified name of the type, e.g. stream.Message.
+func (t *Type) QualifiedName() string {
+	return t.Package + "." + t.Name
+}
+
+// EnumValue is an allowed value of an enum type, declared as a go constant.
+type EnumValue struct {
+	Name  string // name of the go constant
+	Value string // value of the constant
+	Doc   string // documentation of the constant
+}
+
+// Field is a field of a struct type.
+type Field struct {
+	Name     string   // go field name
+	JSONName string   // name of the field in the JSON encoding
+	Doc      string   // documentation of the field
+	Type     *TypeRef // type of the field
+	Optional bool     // true if the field is omitted from the JSON encoding when empty
+	Min      *int     // minimum value of a numeric field, from a validate:"min=N" tag
+}
+
+// RefKind is the kind of a type reference.
+type RefKind int
+
+const (
+	RefString RefKind = iota // a string
+	RefInt                   // an integer
+	RefBool                  // a boolean
+	RefTime                  // an RFC 3339 timestamp
+	RefJSON                  // any JSON value
+	RefArray                 // an array of Elem
+	RefMap                   // an object with string keys and Elem values
+	RefNamed                 // a reference to the named type Named
+)
+
+// TypeRef is a reference to a type from a field.
+type TypeRef struct {
+	Kind     RefKind
+	Elem     *TypeRef // element of an array or map
+	Named    *Type    // referenced named type
+	Nullable bool     // true if null is a valid JSON value, e.g. for nil slices
+	Empty    bool     // true if the empty string is valid for an enum that has no empty value, e.g. for an unset status
+}
+
+// Type returns the registered type with the given qualified name, or nil.
+func (m *Model) Type(qualifiedName string) *Type {
+	return m.index[qualifiedName]
+}
+
+// Dependencies returns the named types referenced, directly or transitively, by t, sorted by qualified name.
+func (m *Model) Dependencies(t *Type) []*Type {
+	seen := map[string]*Type{}
+	var visitRef func(ref *TypeRef)
+	var visit func(t *Type)
+	visitRef = func(ref *TypeRef) {
+		switch {
+		case ref.Named != nil:
+			if _, ok := seen[ref.Named.QualifiedName()]; !ok {
+				seen[ref.Named.QualifiedName()] = ref.Named
+				visit(ref.Named)
+			}
+		case ref.Elem != nil:
+			visitRef(ref.Elem)
+		}
+	}
+	visit = func(t *Type) {
+		for _, f := range t.Fields {
+			visitRef(f.Type)
+		}
+	}
+	visit(t)
+	delete(seen, t.QualifiedName())
+
+	deps := make([]*Type, 0, len(seen))
+	for _, dep := range seen {
+		deps = append(deps, dep)
+	}
+	slices.SortFunc(deps, func(a, b *Type) int { return strings.Compare(a.QualifiedName(), b.QualifiedName()) })
+	return deps
+}
+
+// Load builds the model of the given types. srcRoot is the directory containing the module's go packages,
+// it is used for reading documentation and enum values from the sources.
+func Load(srcRoot string, types []reflect.Type) (*Model, error) {
+	m := &Model{MapKeys: map[string][]EnumValue{}, index: map[string]*Type{}}
+	sources := map[string]*packageSource{}
+
+	for _, rt := range types {
+		dir, ok := strings.CutPrefix(rt.PkgPath(), modulePath)
+		if !ok {
+			return nil, fmt.Errorf("type %s is not part of module %s", rt, modulePath)
+		}
+		src, ok := sources[dir]
+		if !ok {
+			var err error
+			if src, err = parsePackage(filepath.Join(srcRoot, strings.TrimPrefix(dir, "go/"))); err != nil {
+				return nil, fmt.Errorf("parsing package %s: %w", dir, err)
+			}
+			sources[dir] = src
+			if len(src.mapKeys) > 0 {
+				m.MapKeys[filepath.Base(dir)] = src.mapKeys
+			}
+		}
+		t := &Type{
+			Package: filepath.Base(rt.PkgPath()),
+			Name:    rt.Name(),
+			Doc:     src.typeDocs[rt.Name()],
+		}
+		switch rt.Kind() {
+		case reflect.Struct:
+			t.Kind = KindStruct
+		case reflect.String:
+			t.Kind = KindEnum
+			t.Values = src.enums[rt.Name()]
+		default:
+			return nil, fmt.Errorf("type %s: unsupported kind %s", rt, rt.Kind())
+		}
+		m.Types = append(m.Types, t)
+		m.index[t.QualifiedName()] = t
+	}
+
+	// fields are resolved once all types are known, so that they can reference each other
+	for i, rt := range types {
+		if rt.Kind() != reflect.Struct {
+			continue
+		}
+		fields, err := m.fields(rt, sources)
+		if err != nil {
+			return nil, fmt.Errorf("type %s: %w", rt, err)
+		}
+		m.Types[i].Fields = fields
+	}
+	return m, nil
+}
+
+func (m *Model) fields(rt reflect.Type, sources map[string]*packageSource) ([]*Field, error) {
+	var fields []*Field
+	src := sources[strings.TrimPrefix(rt.PkgPath(), modulePath)]
+	for i := range rt.NumField() {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if sf.Anonymous && name == "" {
+			embedded, err := m.fields(sf.Type, sources)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, embedded...)
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		omitempty := slices.ContainsFunc(strings.Split(opts, ","), func(opt string) bool {
+			return opt == "omitempty" || opt == "omitzero"
+		})
+		validate := strings.Split(sf.Tag.Get("validate"), ",")
+		required := slices.Contains(validate, "required")
+
+		ref, err := m.typeRef(sf.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", sf.Name, err)
+		}
+		if !omitempty {
+			switch {
+			case ref.Kind == RefArray || ref.Kind == RefMap:
+				ref.Nullable = true // nil slices and maps are encoded as null
+			case ref.Kind == RefNamed && ref.Named.Kind == KindEnum && !required:
+				ref.Empty = !slices.ContainsFunc(ref.Named.Values, func(v EnumValue) bool { return v.Value == "" })
+			}
+		}
+		f := &Field{
+			Name:     sf.Name,
+			JSONName: name,
+			Doc:      src.fieldDocs[rt.Name()+"."+sf.Name],
+			Type:     ref,
+			Optional: omitempty && !required,
+		}
+		for _, v := range validate {
+			if minValue, ok := strings.CutPrefix(v, "min="); ok {
+				n, err := strconv.Atoi(minValue)
+				if err != nil {
+					return nil, fmt.Errorf("field %s: parsing validate tag: %w", sf.Name, err)
+				}
+				f.Min = &n
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+)
+
+func (m *Model) typeRef(rt reflect.Type) (*TypeRef, error) {
+	switch rt {
+	case timeType:
+		return &TypeRef{Kind: RefTime}, nil
+	case rawMessageType:
+		return &TypeRef{Kind: RefJSON}, nil
+	}
+	if rt.Name() != "" && rt.PkgPath() != "" {
+		qualifiedName := filepath.Base(rt.PkgPath()) + "." + rt.Name()
+		t := m.Type(qualifiedName)
+		if t == nil {
+			return nil, fmt.Errorf("type %s is referenced but not registered", qualifiedName)
+		}
+		return &TypeRef{Kind: RefNamed, Named: t}, nil
+	}
+	switch rt.Kind() {
+	case reflect.String:
+		return &TypeRef{Kind: RefString}, nil
+	case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &TypeRef{Kind: RefInt}, nil
+	case reflect.Bool:
+		return &TypeRef{Kind: RefBool}, nil
+	case reflect.Pointer:
+		return m.typeRef(rt.Elem())
+	case reflect.Slice:
+		elem, err := m.typeRef(rt.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &TypeRef{Kind: RefArray, Elem: elem}, nil
+	case reflect.Map:
+		if rt.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", rt.Key())
+		}
+		elem, err := m.typeRef(rt.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &TypeRef{Kind: RefMap, Elem: elem}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", rt)
+	}
+}
+
+// packageSource holds what is read from the sources of a package.
+type packageSource struct {
+	typeNames []string               // exported type names, in declaration order
+	typeDocs  map[string]string      // type name -> doc
+	fieldDocs map[string]string      // type name.field name -> doc
+	enums     map[string][]EnumValue // type name -> typed string constants
+	mapKeys   []EnumValue            // property map keys, declared as mapKey<Name> constants
+}
+
+func parsePackage(dir string) (*packageSource, error) {
+	fset := token.NewFileSet()
+	matches, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	src := &packageSource{
+		typeDocs:  map[string]string{},
+		fieldDocs: map[string]string{},
+		enums:     map[string][]EnumValue{},
+	}
+	slices.Sort(matches)
+	for _, filename := range matches {
+		if strings.HasSuffix(filename, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range file.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			switch gd.Tok {
+			case token.TYPE:
+				src.parseTypes(gd)
+			case token.CONST:
+				src.parseConsts(gd)
+			}
+		}
+	}
+	return src, nil
+}
+
+func (src *packageSource) parseTypes(gd *ast.GenDecl) {
+	for _, spec := range gd.Specs {
+		ts := spec.(*ast.TypeSpec)
+		if !ts.Name.IsExported() {
+			continue
+		}
+		src.typeNames = append(src.typeNames, ts.Name.Name)
+		doc := ts.Doc
+		if doc == nil && len(gd.Specs) == 1 {
+			doc = gd.Doc
+		}
+		src.typeDocs[ts.Name.Name] = commentText(doc, ts.Comment)
+
+		st, ok := ts.Type.(*ast.StructType)
+		if !ok {
+			continue
+		}
+		for _, field := range st.Fields.List {
+			for _, name := range field.Names {
+				src.fieldDocs[ts.Name.Name+"."+name.Name] = commentText(field.Doc, field.Comment)
+			}
+		}
+	}
+}
+
+func (src *packageSource) parseConsts(gd *ast.GenDecl) {
+	for _, spec := range gd.Specs {
+		vs := spec.(*ast.ValueSpec)
+		if len(vs.Names) != len(vs.Values) {
+			continue
+		}
+		for i, name := range vs.Names {
+			lit, ok := vs.Values[i].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				continue
+			}
+			value, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				continue
+			}
+			ident, typed := vs.Type.(*ast.Ident)
+			switch {
+			case typed && name.IsExported():
+				src.enums[ident.Name] = append(src.enums[ident.Name], EnumValue{
+					Name:  name.Name,
+					Value: value,
+					Doc:   commentText(vs.Doc, vs.Comment),
+				})
+			case vs.Type == nil && strings.HasPrefix(name.Name, "mapKey"):
+				src.mapKeys = append(src.mapKeys, EnumValue{
+					Name:  strings.TrimPrefix(name.Name, "mapKey"),
+					Value: value,
+				})
+			}
+		}
+	}
+}
+
+// commentText returns the text of the doc comment, or the line comment if there is no doc.
+// Line comments that only mark a field as optional are ignored, since that is already part of the schema.
+func commentText(doc, line *ast.CommentGroup) string {
+	for _, cg := range []*ast.CommentGroup{doc, line} {
+		text := strings.Join(strings.Fields(cg.Text()), " ")
+		if text != "" && text != "optional" {
+			return text
+		}
+	}
+	return ""
+}
+
+// ExportedTypeNames returns the qualified names of all exported types declared in the given package directories.
+func ExportedTypeNames(srcRoot string, dirs ...string) ([]string, error) {
+	var names []string
+	for _, dir := range dirs {
+		src, err := parsePackage(filepath.Join(srcRoot, dir))
+		if err != nil {
+			return nil, fmt.Errorf("parsing package %s: %w", dir, err)
+		}
+		for _, name := range src.typeNames {
+			names = append(names, filepath.Base(dir)+"."+name)
+		}
+	}
+	return names, nil
+}
+
+// PackageNames returns the names of the packages of the model, in registration order.
+func (m *Model) PackageNames() []string {
+	var names []string
+	for _, t := range m.Types {
+		if !slices.Contains(names, t.Package) {
+			names = append(names, t.Package)
+		}
+	}
+	return names
+}
+
+// PackageTypes returns the types of a package, ordered so that every type comes after the types it references.
+func (m *Model) PackageTypes(pkg string) []*Type {
+	var ordered []*Type
+	visited := map[*Type]bool{}
+	var visit func(t *Type)
+	visit = func(t *Type) {
+		if visited[t] {
+			return
+		}
+		visited[t] = true
+		for _, dep := range m.Dependencies(t) {
+			if dep.Package == pkg {
+				visit(dep)
+			}
+		}
+		ordered = append(ordered, t)
+	}
+	for _, t := range m.Types {
+		if t.Package == pkg {
+			visit(t)
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,156 @@
+package schemagen
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// PythonFiles renders a python module of pydantic (v2) models for every package of the model, keyed by <package>.py.
+// Struct types become models whose fields are aliased to their JSON names, enum types become string enums.
+func PythonFiles(m *Model) map[string][]byte {
+	files := map[string][]byte{}
+	for _, pkg := range m.PackageNames() {
+		types := m.PackageTypes(pkg)
+		r := &pythonRenderer{imports: map[string][]string{}}
+
+		var body bytes.Buffer
+		for _, t := range types {
+			body.WriteString("\n\n")
+			switch t.Kind {
+			case KindEnum:
+				r.writeEnum(&body, t.Doc, t.Name, enumMembers(t))
+			case KindStruct:
+				r.writeModel(&body, t)
+			}
+		}
+		if keys := m.MapKeys[pkg]; len(keys) > 0 {
+			body.WriteString("\n\n")
+			r.writeEnum(&body, "Keys of the property map encoding of MessageProperties.", "MessagePropertiesMapKey", keys)
+		}
+
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "# %s\n", generatedHeader)
+		buf.WriteString("from __future__ import annotations\n\n")
+		for _, module := range []string{"datetime", "enum", "typing"} {
+			if names := r.imports[module]; len(names) > 0 {
+				slices.Sort(names)
+				fmt.Fprintf(&buf, "from %s import %s\n", module, strings.Join(names, ", "))
+			}
+		}
+		if names := r.imports["pydantic"]; len(names) > 0 {
+			slices.Sort(names)
+			fmt.Fprintf(&buf, "\nfrom pydantic import %s\n", strings.Join(names, ", "))
+		}
+		if imports := externalReferences(types); len(imports) > 0 {
+			buf.WriteString("\n")
+			for _, pi := range imports {
+				fmt.Fprintf(&buf, "from .%s import %s\n", pi.pkg, strings.Join(pi.names, ", "))
+			}
+		}
+		buf.Write(body.Bytes())
+		files[pkg+".py"] = buf.Bytes()
+	}
+	return files
+}
+
+type pythonRenderer struct {
+	imports map[string][]string // module -> imported names
+}
+
+func (r *pythonRenderer) use(module, name string) string {
+	if !slices.Contains(r.imports[module], name) {
+		r.imports[module] = append(r.imports[module], name)
+	}
+	return name
+}
+
+func writePythonDoc(buf *bytes.Buffer, indent, doc string) {
+	if doc != "" {
+		doc = strings.ReplaceAll(doc, `"""`, `\"\"\"`)
+		if strings.HasSuffix(doc, `"`) {
+			doc += " "
+		}
+		fmt.Fprintf(buf, "%s\"\"\"%s\"\"\"\n", indent, doc)
+	}
+}
+
+func (r *pythonRenderer) writeEnum(buf *bytes.Buffer, doc, name string, members []EnumValue) {
+	fmt.Fprintf(buf, "class %s(str, %s):\n", name, r.use("enum", "Enum"))
+	if doc != "" {
+		writePythonDoc(buf, "    ", doc)
+		buf.WriteString("\n")
+	}
+	for _, v := range members {
+		fmt.Fprintf(buf, "    %s = %s\n", strings.ToUpper(snakeCase(v.Name)), strconv.Quote(v.Value))
+		writePythonDoc(buf, "    ", v.Doc)
+	}
+}
+
+func (r *pythonRenderer) writeModel(buf *bytes.Buffer, t *Type) {
+	fmt.Fprintf(buf, "class %s(%s):\n", t.Name, r.use("pydantic", "BaseModel"))
+	writePythonDoc(buf, "    ", t.Doc)
+	if t.Doc != "" {
+		buf.WriteString("\n")
+	}
+	fmt.Fprintf(buf, "    model_config = %s(populate_by_name=True)\n\n", r.use("pydantic", "ConfigDict"))
+	for _, f := range t.Fields {
+		typ := r.pythonType(f.Type)
+		field := r.use("pydantic", "Field")
+		if f.Optional {
+			fmt.Fprintf(buf, "    %s: %s[%s] = %s(default=None, alias=%s)\n", snakeCase(f.Name), r.use("typing", "Optional"), typ, field, strconv.Quote(f.JSONName))
+		} else {
+			fmt.Fprintf(buf, "    %s: %s = %s(alias=%s)\n", snakeCase(f.Name), typ, field, strconv.Quote(f.JSONName))
+		}
+		writePythonDoc(buf, "    ", f.Doc)
+	}
+}
+
+func (r *pythonRenderer) pythonType(ref *TypeRef) string {
+	var s string
+	switch ref.Kind {
+	case RefString:
+		s = "str"
+	case RefInt:
+		s = "int"
+	case RefBool:
+		s = "bool"
+	case RefTime:
+		s = r.use("datetime", "datetime")
+	case RefJSON:
+		s = r.use("typing", "Any")
+	case RefArray:
+		s = r.use("typing", "List") + "[" + r.pythonType(ref.Elem) + "]"
+	case RefMap:
+		s = r.use("typing", "Dict") + "[str, " + r.pythonType(ref.Elem) + "]"
+	case RefNamed:
+		s = ref.Named.Name
+	}
+	if ref.Empty {
+		s = r.use("typing", "Union") + "[" + s + ", " + r.use("typing", "Literal") + `[""]]`
+	}
+	if ref.Nullable {
+		s = r.use("typing", "Optional") + "[" + s + "]"
+	}
+	return s
+}
+
+// snakeCase converts a go identifier to snake case, keeping acronyms together, e.g. SourceJobRunID -> source_job_run_id.
+func snakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, c := range runes {
+		if i > 0 && unicode.IsUpper(c) {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			acronymEnd := unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || acronymEnd {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(c))
+	}
+	return b.String()
+}
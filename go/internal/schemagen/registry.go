@@ -0,0 +1,72 @@
+package schemagen
+
+import (
+	"reflect"
+
+	"github.com/rudderlabs/rudder-schemas/go/cluster"
+	"github.com/rudderlabs/rudder-schemas/go/stream"
+)
+
+// Packages are the directories, relative to the go source root, of the packages exposing schema types.
+var Packages = []string{"stream", "cluster"}
+
+// Types lists every type that is part of the public schema, in the order they are generated.
+var Types = []reflect.Type{
+	reflect.TypeFor[stream.FileCompression](),
+	reflect.TypeFor[stream.Stage](),
+	reflect.TypeFor[stream.BotAction](),
+	reflect.TypeFor[stream.CompressionAlgorithm](),
+	reflect.TypeFor[stream.EncryptionScheme](),
+	reflect.TypeFor[stream.MessageProperties](),
+	reflect.TypeFor[stream.Message](),
+	reflect.TypeFor[stream.DeadLetterStage](),
+	reflect.TypeFor[stream.DeadLetterConsumer](),
+	reflect.TypeFor[stream.DeadLetter](),
+
+	reflect.TypeFor[cluster.PartitionMigrationStatus](),
+	reflect.TypeFor[cluster.PartitionMigrationJobStatus](),
+	reflect.TypeFor[cluster.PartitionMigrationJobHeader](),
+	reflect.TypeFor[cluster.PartitionMigration](),
+	reflect.TypeFor[cluster.PartitionMigrationAck](),
+	reflect.TypeFor[cluster.ReloadGatewayCommand](),
+	reflect.TypeFor[cluster.ReloadGatewayAck](),
+	reflect.TypeFor[cluster.ReloadSrcRouterCommand](),
+	reflect.TypeFor[cluster.ReloadSrcRouterAck](),
+	reflect.TypeFor[cluster.PartitionMigrationJob](),
+	reflect.TypeFor[cluster.PartitionMigrationInfo](),
+	reflect.TypeFor[cluster.PartitionMigrationLimits](),
+	reflect.TypeFor[cluster.PartitionMigrationWave](),
+	reflect.TypeFor[cluster.PartitionMigrationPlan](),
+	reflect.TypeFor[cluster.PartitionMigrationEventType](),
+	reflect.TypeFor[cluster.PartitionMigrationCommand](),
+	reflect.TypeFor[cluster.PartitionMigrationEvent](),
+}
+
+// IgnoredTypes lists the exported types of Packages that are not data schemas, e.g. encoders or errors.
+var IgnoredTypes = []string{
+	"stream.LineError",
+	"stream.Encoder",
+	"stream.Decoder",
+	"stream.MessageBuilder",
+	"cluster.Store",
+	"cluster.KeyValue",
+	"cluster.WatchEventType",
+	"cluster.WatchEvent",
+	"cluster.MemoryStore",
+	"cluster.KeyKind",
+	"cluster.KeyLayout",
+	"cluster.ParsedKey",
+	"cluster.Coordinator",
+	"cluster.Agent",
+	"cluster.MigrationScope",
+	"cluster.MigrationConflictKind",
+	"cluster.MigrationConflict",
+	"cluster.MigrationPolicy",
+	"cluster.PartitionLoad",
+	"cluster.PartitionBalancer",
+	"cluster.PartitionBalance",
+	"cluster.PartitionMigrationProgress",
+	"cluster.PartitionMigrationCounts",
+	"cluster.PartitionMigrationNodeProgress",
+	"cluster.PartitionMigrationJobProgress",
+}
@@ -0,0 +1,140 @@
+package schemagen_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-schemas/go/internal/schemagen"
+)
+
+const (
+	srcRoot = "../.."
+	outDir  = "../../../" + schemagen.OutputDir
+)
+
+func TestGeneratedFilesUpToDate(t *testing.T) {
+	files, err := schemagen.Generate(srcRoot)
+	require.NoError(t, err)
+
+	checkedIn := map[string][]byte{}
+	err = filepath.WalkDir(outDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		name, err := filepath.Rel(outDir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		checkedIn[filepath.ToSlash(name)] = data
+		return err
+	})
+	require.NoError(t, err)
+
+	require.ElementsMatch(t, lo.Keys(files), lo.Keys(checkedIn), "generated files are out of date, run make generate")
+	for name, data := range files {
+		require.Equal(t, string(data), string(checkedIn[name]), "%s is out of date, run make generate", name)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	names, err := schemagen.ExportedTypeNames(srcRoot, schemagen.Packages...)
+	require.NoError(t, err)
+
+	m, err := schemagen.Load(srcRoot, schemagen.Types)
+	require.NoError(t, err)
+	registered := lo.Map(m.Types, func(t *schemagen.Type, _ int) string { return t.QualifiedName() })
+
+	unregistered, _ := lo.Difference(names, append(registered, schemagen.IgnoredTypes...))
+	require.Empty(t, unregistered, "exported types must be registered in schemagen.Types or schemagen.IgnoredTypes")
+}
+
+func TestModel(t *testing.T) {
+	m, err := schemagen.Load(srcRoot, schemagen.Types)
+	require.NoError(t, err)
+
+	t.Run("required and optional fields", func(t *testing.T) {
+		properties := m.Type("stream.MessageProperties")
+		require.NotNil(t, properties)
+
+		fields := lo.SliceToMap(properties.Fields, func(f *schemagen.Field) (string, *schemagen.Field) { return f.JSONName, f })
+		require.False(t, fields["workspaceID"].Optional)
+		require.False(t, fields["receivedAt"].Optional)
+		require.Equal(t, schemagen.RefTime, fields["receivedAt"].Type.Kind)
+		require.True(t, fields["userID"].Optional)
+		require.True(t, fields["isBot"].Optional)
+		require.Equal(t, "BotName is the name of the bot that sent the event", fields["botName"].Doc)
+	})
+
+	t.Run("enum values", func(t *testing.T) {
+		status := m.Type("cluster.PartitionMigrationStatus")
+		require.NotNil(t, status)
+		require.Equal(t, schemagen.KindEnum, status.Kind)
+		require.Equal(t, []string{"new", "reloading-gw", "reloading-srcrouter", "migrating", "completed"},
+			lo.Map(status.Values, func(v schemagen.EnumValue, _ int) string { return v.Value }),
+		)
+		require.Equal(t, "initial state", status.Values[0].Doc)
+	})
+
+	t.Run("embedded fields are flattened", func(t *testing.T) {
+		job := m.Type("cluster.PartitionMigrationJob")
+		require.NotNil(t, job)
+		require.Equal(t, []string{"jobId", "sourceNode", "targetNode", "partitions", "migrationId", "status", "startTime"},
+			lo.Map(job.Fields, func(f *schemagen.Field, _ int) string { return f.JSONName }),
+		)
+		require.True(t, job.Fields[3].Type.Nullable, "nil slices are encoded as null")
+		require.True(t, job.Fields[5].Type.Empty, "unset status is encoded as empty string")
+	})
+
+	t.Run("dependencies", func(t *testing.T) {
+		deps := m.Dependencies(m.Type("cluster.PartitionMigrationInfo"))
+		require.Equal(t, []string{"cluster.PartitionMigrationJob", "cluster.PartitionMigrationJobStatus", "cluster.PartitionMigrationStatus"},
+			lo.Map(deps, func(t *schemagen.Type, _ int) string { return t.QualifiedName() }),
+		)
+	})
+}
+
+func TestTypeScript(t *testing.T) {
+	m, err := schemagen.Load(srcRoot, schemagen.Types)
+	require.NoError(t, err)
+	files := schemagen.TypeScriptFiles(m)
+	require.ElementsMatch(t, []string{"stream.ts", "cluster.ts"}, lo.Keys(files))
+
+	stream := string(files["stream.ts"])
+	require.Contains(t, stream, "export interface MessageProperties {\n  requestType: string;\n")
+	require.Contains(t, stream, "  destinationID?: string;\n")
+	require.Contains(t, stream, "  receivedAt: string;\n")
+	require.Contains(t, stream, "  payload: unknown;\n")
+	require.Contains(t, stream, "  WorkspaceID: \"workspaceID\",\n")
+
+	cluster := string(files["cluster.ts"])
+	require.Contains(t, cluster, "  /** initial state */\n  New: \"new\",\n")
+	require.Contains(t, cluster, "export type PartitionMigrationStatus = (typeof PartitionMigrationStatus)[keyof typeof PartitionMigrationStatus];\n")
+	require.Contains(t, cluster, "  previousStatus: PartitionMigrationStatus | \"\";\n")
+	require.Contains(t, cluster, "  jobs: PartitionMigrationJob[] | null;\n")
+}
+
+func TestPython(t *testing.T) {
+	m, err := schemagen.Load(srcRoot, schemagen.Types)
+	require.NoError(t, err)
+	files := schemagen.PythonFiles(m)
+	require.ElementsMatch(t, []string{"stream.py", "cluster.py"}, lo.Keys(files))
+
+	stream := string(files["stream.py"])
+	require.Contains(t, stream, "from pydantic import BaseModel, ConfigDict, Field\n")
+	require.Contains(t, stream, "    workspace_id: str = Field(alias=\"workspaceID\")\n")
+	require.Contains(t, stream, "    source_job_run_id: Optional[str] = Field(default=None, alias=\"sourceJobRunID\")\n")
+	require.Contains(t, stream, "    received_at: datetime = Field(alias=\"receivedAt\")\n")
+	require.Contains(t, stream, "    BOT_IS_INVALID_BROWSER = \"botIsInvalidBrowser\"\n")
+
+	cluster := string(files["cluster.py"])
+	require.Contains(t, cluster, "class PartitionMigrationStatus(str, Enum):\n")
+	require.Contains(t, cluster, "    RELOADING_GW = \"reloading-gw\"\n    \"\"\"reloading gateway nodes\"\"\"\n")
+	require.Contains(t, cluster, "    previous_status: Union[PartitionMigrationStatus, Literal[\"\"]] = Field(alias=\"previousStatus\")\n")
+	require.Contains(t, cluster, "    jobs: Optional[List[PartitionMigrationJob]] = Field(alias=\"jobs\")\n")
+}
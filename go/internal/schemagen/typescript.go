@@ -0,0 +1,155 @@
+package schemagen
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const generatedHeader = "Code generated by schemagen. DO NOT EDIT."
+
+// TypeScriptFiles renders a TypeScript module for every package of the model, keyed by <package>.ts.
+// Struct types become interfaces, enum types become a const object together with a union type of its values.
+func TypeScriptFiles(m *Model) map[string][]byte {
+	files := map[string][]byte{}
+	for _, pkg := range m.PackageNames() {
+		types := m.PackageTypes(pkg)
+
+		var body bytes.Buffer
+		for _, t := range types {
+			body.WriteString("\n")
+			switch t.Kind {
+			case KindEnum:
+				writeTSEnum(&body, t.Doc, t.Name, enumMembers(t))
+			case KindStruct:
+				writeTSInterface(&body, t)
+			}
+		}
+		if keys := m.MapKeys[pkg]; len(keys) > 0 {
+			body.WriteString("\n")
+			writeTSEnum(&body, "Keys of the property map encoding of MessageProperties.", "MessagePropertiesMapKey", keys)
+		}
+
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "// %s\n", generatedHeader)
+		if imports := externalReferences(types); len(imports) > 0 {
+			buf.WriteString("\n")
+			for _, pi := range imports {
+				fmt.Fprintf(&buf, "import type { %s } from \"./%s\";\n", strings.Join(pi.names, ", "), pi.pkg)
+			}
+		}
+		buf.Write(body.Bytes())
+		files[pkg+".ts"] = buf.Bytes()
+	}
+	return files
+}
+
+func writeTSDoc(buf *bytes.Buffer, indent, doc string) {
+	if doc != "" {
+		fmt.Fprintf(buf, "%s/** %s */\n", indent, doc)
+	}
+}
+
+func writeTSEnum(buf *bytes.Buffer, doc, name string, members []EnumValue) {
+	writeTSDoc(buf, "", doc)
+	fmt.Fprintf(buf, "export const %s = {\n", name)
+	for _, v := range members {
+		writeTSDoc(buf, "  ", v.Doc)
+		fmt.Fprintf(buf, "  %s: %s,\n", v.Name, strconv.Quote(v.Value))
+	}
+	buf.WriteString("} as const;\n")
+	fmt.Fprintf(buf, "export type %s = (typeof %s)[keyof typeof %s];\n", name, name, name)
+}
+
+func writeTSInterface(buf *bytes.Buffer, t *Type) {
+	writeTSDoc(buf, "", t.Doc)
+	fmt.Fprintf(buf, "export interface %s {\n", t.Name)
+	for _, f := range t.Fields {
+		writeTSDoc(buf, "  ", f.Doc)
+		optional := ""
+		if f.Optional {
+			optional = "?"
+		}
+		fmt.Fprintf(buf, "  %s%s: %s;\n", f.JSONName, optional, tsType(f.Type))
+	}
+	buf.WriteString("}\n")
+}
+
+func tsType(ref *TypeRef) string {
+	var s string
+	switch ref.Kind {
+	case RefString, RefTime:
+		s = "string"
+	case RefInt:
+		s = "number"
+	case RefBool:
+		s = "boolean"
+	case RefJSON:
+		s = "unknown"
+	case RefArray:
+		s = tsType(ref.Elem) + "[]"
+	case RefMap:
+		s = "Record<string, " + tsType(ref.Elem) + ">"
+	case RefNamed:
+		s = ref.Named.Name
+	}
+	if ref.Empty {
+		s += ` | ""`
+	}
+	if ref.Nullable {
+		s += " | null"
+	}
+	return s
+}
+
+// enumMembers returns the values of an enum type, named after their go constant without the type name prefix.
+func enumMembers(t *Type) []EnumValue {
+	members := make([]EnumValue, 0, len(t.Values))
+	for _, v := range t.Values {
+		name := strings.TrimPrefix(v.Name, t.Name)
+		if name == "" {
+			name = v.Name
+		}
+		members = append(members, EnumValue{Name: name, Value: v.Value, Doc: v.Doc})
+	}
+	return members
+}
+
+type packageImport struct {
+	pkg   string
+	names []string
+}
+
+// externalReferences returns the types of other packages directly referenced by the fields of types, grouped by package.
+func externalReferences(types []*Type) []packageImport {
+	var imports []packageImport
+	var visit func(pkg string, ref *TypeRef)
+	visit = func(pkg string, ref *TypeRef) {
+		if ref.Elem != nil {
+			visit(pkg, ref.Elem)
+		}
+		if ref.Named == nil || ref.Named.Package == pkg {
+			return
+		}
+		i := slices.IndexFunc(imports, func(pi packageImport) bool { return pi.pkg == ref.Named.Package })
+		if i < 0 {
+			imports = append(imports, packageImport{pkg: ref.Named.Package})
+			i = len(imports) - 1
+		}
+		if !slices.Contains(imports[i].names, ref.Named.Name) {
+			imports[i].names = append(imports[i].names, ref.Named.Name)
+		}
+	}
+	for _, t := range types {
+		for _, f := range t.Fields {
+			visit(t.Package, f.Type)
+		}
+	}
+	for i := range imports {
+		slices.Sort(imports[i].names)
+	}
+	slices.SortFunc(imports, func(a, b packageImport) int { return strings.Compare(a.pkg, b.pkg) })
+	return imports
+}
@@ -0,0 +1,164 @@
+package schemastest
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/rudderlabs/rudder-schemas/go/cluster"
+	"github.com/rudderlabs/rudder-schemas/go/stream"
+)
+
+// FixtureTime is the time used by all fixtures.
+var FixtureTime = time.Date(2024, 8, 1, 2, 30, 50, 200, time.UTC)
+
+// MinimalMessageProperties returns message properties with only the required properties set.
+func MinimalMessageProperties() stream.MessageProperties {
+	return stream.MessageProperties{
+		RequestType: "track",
+		RoutingKey:  "routingKey",
+		WorkspaceID: "workspaceID",
+		SourceID:    "sourceID",
+		ReceivedAt:  FixtureTime,
+		RequestIP:   "10.29.13.20",
+	}
+}
+
+// WebhookMessageProperties returns the properties of a message produced by the webhook stage,
+// after failing to be processed by the source.
+func WebhookMessageProperties() stream.MessageProperties {
+	p := MinimalMessageProperties()
+	p.Stage = stream.StageWebhook
+	p.SourceType = "shopify"
+	p.WebhookFailureReason = "invalid payload"
+	return p
+}
+
+// RetryMessageProperties returns the properties of a message redelivered for the second time by the retry stage.
+func RetryMessageProperties() stream.MessageProperties {
+	p := MinimalMessageProperties()
+	p.Stage = stream.StageRetry
+	p.RetryAttempt = 2
+	p.RetryLastError = "context deadline exceeded"
+	return p
+}
+
+// ReplayMessageProperties returns the properties of a message replayed from an archive, received a day earlier.
+func ReplayMessageProperties() stream.MessageProperties {
+	p := MinimalMessageProperties()
+	p.Stage = stream.StageReplay
+	p.ReplayJobID = "replayJobID"
+	p.ReplayOriginalReceivedAt = FixtureTime.Add(-24 * time.Hour)
+	return p
+}
+
+// TransformationMessageProperties returns the properties of a message produced by a transformer.
+func TransformationMessageProperties() stream.MessageProperties {
+	p := MinimalMessageProperties()
+	p.Stage = stream.StageTransformation
+	p.TransformerVersion = "v1.2.3"
+	return p
+}
+
+// BotMessageProperties returns the properties of a message sent by a bot with an invalid browser, to be flagged.
+func BotMessageProperties() stream.MessageProperties {
+	p := MinimalMessageProperties()
+	p.IsBot = true
+	p.BotName = "Googlebot"
+	p.BotURL = "https://developers.google.com/search/docs/crawling-indexing/googlebot"
+	p.BotIsInvalidBrowser = true
+	p.BotAction = stream.BotActionFlag
+	return p
+}
+
+// EncryptedMessageProperties returns the properties of a message with a compressed and encrypted payload.
+func EncryptedMessageProperties() stream.MessageProperties {
+	p := MinimalMessageProperties()
+	p.Compression = "1:3"
+	p.Encryption = "aes-gcm:256"
+	p.EncryptionKeyID = "encryptionKeyID"
+	return p
+}
+
+// FullMessageProperties returns message properties with every property set, apart from the stage specific ones.
+func FullMessageProperties() stream.MessageProperties {
+	p := BotMessageProperties()
+	p.DestinationID = "destinationID"
+	p.UserID = "userID"
+	p.SourceJobRunID = "sourceJobRunID"
+	p.SourceTaskRunID = "sourceTaskRunID"
+	p.TraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	p.Compression = "1:3"
+	p.Encryption = "aes-gcm:256"
+	p.EncryptionKeyID = "encryptionKeyID"
+	p.PartitionID = "workspaceID-1"
+	p.Signature = "c2lnbmF0dXJl"
+	p.SignatureKeyID = "signatureKeyID"
+	return p
+}
+
+// MessagePropertiesFixtures returns all message properties fixtures by name, e.g. for table driven tests.
+func MessagePropertiesFixtures() map[string]stream.MessageProperties {
+	return map[string]stream.MessageProperties{
+		"minimal":        MinimalMessageProperties(),
+		"webhook":        WebhookMessageProperties(),
+		"retry":          RetryMessageProperties(),
+		"replay":         ReplayMessageProperties(),
+		"transformation": TransformationMessageProperties(),
+		"bot":            BotMessageProperties(),
+		"encrypted":      EncryptedMessageProperties(),
+		"full":           FullMessageProperties(),
+	}
+}
+
+// NewMessage returns a message with the given properties and a track event as payload.
+func NewMessage(properties stream.MessageProperties) stream.Message {
+	return stream.Message{
+		Properties: properties,
+		Payload:    json.RawMessage(`{"type":"track","event":"Order Completed","messageId":"messageID","userId":"userID"}`),
+	}
+}
+
+// NewPartitionMigration returns a new migration moving partitions of a workspace from node 0 to nodes 1 and 2.
+func NewPartitionMigration() *cluster.PartitionMigration {
+	return &cluster.PartitionMigration{
+		ID:     "migration-1",
+		Status: cluster.PartitionMigrationStatusNew,
+		Jobs: []*cluster.PartitionMigrationJobHeader{
+			{JobID: "job-1", SourceNode: 0, TargetNode: 1, Partitions: []string{"workspaceID-0", "workspaceID-1"}},
+			{JobID: "job-2", SourceNode: 0, TargetNode: 2, Partitions: []string{"workspaceID-2"}},
+			{JobID: "job-3", SourceNode: 0, TargetNode: 2, Partitions: []string{"workspaceID-3"}},
+		},
+		StartTime:    FixtureTime,
+		AckKeyPrefix: "/migrations/migration-1/ack",
+	}
+}
+
+// MidFlightPartitionMigration returns the migration of NewPartitionMigration while migrating,
+// with a completed, a moved and a new job.
+func MidFlightPartitionMigration() *cluster.PartitionMigrationInfo {
+	pm := NewPartitionMigration()
+	pm.Status = cluster.PartitionMigrationStatusMigrating
+	pm.PreviousStatus = cluster.PartitionMigrationStatusReloadingSrcRouter
+
+	var pmi cluster.PartitionMigrationInfo
+	pmi.FromPartitionMigration(*pm, map[string]cluster.PartitionMigrationJobStatus{
+		"job-1": cluster.PartitionMigrationJobStatusCompleted,
+		"job-2": cluster.PartitionMigrationJobStatusMoved,
+		"job-3": cluster.PartitionMigrationJobStatusNew,
+	})
+	pmi.Jobs[0].StartTime = FixtureTime.Add(time.Minute)
+	pmi.Jobs[1].StartTime = FixtureTime.Add(2 * time.Minute)
+	return &pmi
+}
+
+// CompletedPartitionMigration returns the migration of NewPartitionMigration once completed.
+func CompletedPartitionMigration() *cluster.PartitionMigrationInfo {
+	pmi := MidFlightPartitionMigration()
+	pmi.PreviousStatus = cluster.PartitionMigrationStatusMigrating
+	pmi.Status = cluster.PartitionMigrationStatusCompleted
+	for i, job := range pmi.Jobs {
+		job.Status = cluster.PartitionMigrationJobStatusCompleted
+		job.StartTime = FixtureTime.Add(time.Duration(i+1) * time.Minute)
+	}
+	return pmi
+}
@@ -0,0 +1,63 @@
+package schemastest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-schemas/go/cluster"
+	"github.com/rudderlabs/rudder-schemas/go/schemastest"
+	"github.com/rudderlabs/rudder-schemas/go/stream"
+)
+
+func TestFixtures(t *testing.T) {
+	t.Run("message properties", func(t *testing.T) {
+		validate := stream.NewMessageValidator()
+		validateProperties := stream.NewMessagePropertiesValidator(stream.WithEncryptionPropertiesValidator())
+		for name, properties := range schemastest.MessagePropertiesFixtures() {
+			t.Run(name, func(t *testing.T) {
+				msg := schemastest.NewMessage(properties)
+				require.NoError(t, validate(&msg))
+				require.NoError(t, validateProperties(&msg.Properties))
+
+				decoded, err := stream.FromMapProperties(stream.ToMapProperties(properties))
+				require.NoError(t, err)
+				require.Equal(t, properties, decoded)
+			})
+		}
+	})
+
+	t.Run("fixtures are not shared", func(t *testing.T) {
+		p := schemastest.WebhookMessageProperties()
+		p.SourceType = "changed"
+		require.Equal(t, "shopify", schemastest.WebhookMessageProperties().SourceType)
+
+		pmi := schemastest.MidFlightPartitionMigration()
+		pmi.Jobs[0].Partitions[0] = "changed"
+		require.Equal(t, "workspaceID-0", schemastest.MidFlightPartitionMigration().Jobs[0].Partitions[0])
+	})
+
+	t.Run("migrations", func(t *testing.T) {
+		pm := schemastest.NewPartitionMigration()
+		require.Equal(t, cluster.PartitionMigrationStatusNew, pm.Status)
+		require.ElementsMatch(t, []int{0}, pm.SourceNodes())
+		require.ElementsMatch(t, []int{1, 2}, pm.TargetNodes())
+
+		pmi := schemastest.MidFlightPartitionMigration()
+		require.Equal(t, cluster.PartitionMigrationStatusMigrating, pmi.Status)
+		require.Equal(t, []cluster.PartitionMigrationJobStatus{
+			cluster.PartitionMigrationJobStatusCompleted,
+			cluster.PartitionMigrationJobStatusMoved,
+			cluster.PartitionMigrationJobStatusNew,
+		}, []cluster.PartitionMigrationJobStatus{pmi.Jobs[0].Status, pmi.Jobs[1].Status, pmi.Jobs[2].Status})
+		require.True(t, pmi.Jobs[2].StartTime.IsZero())
+
+		pmi = schemastest.CompletedPartitionMigration()
+		require.Equal(t, cluster.PartitionMigrationStatusCompleted, pmi.Status)
+		require.Equal(t, cluster.PartitionMigrationStatusMigrating, pmi.PreviousStatus)
+		for _, job := range pmi.Jobs {
+			require.Equal(t, cluster.PartitionMigrationJobStatusCompleted, job.Status)
+			require.False(t, job.StartTime.IsZero())
+		}
+	})
+}
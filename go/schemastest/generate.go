@@ -0,0 +1,242 @@
+// Package schemastest provides random generators and fixtures of the stream and cluster types, for testing their consumers.
+//
+// Generators produce values in their canonical shape: messages are valid according to stream.NewMessageValidator
+// and survive a property map round trip, migrations only contain statuses that are consistent with each other.
+// They are deterministic for a given source of randomness, see NewRand, and can be used with testing/quick through
+// the Quick* types, or with native fuzzing by deriving the source from a fuzzed seed.
+package schemastest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand" // testing/quick generators require math/rand
+	"net"
+	"reflect"
+	"time"
+
+	"github.com/rudderlabs/rudder-schemas/go/cluster"
+	"github.com/rudderlabs/rudder-schemas/go/stream"
+)
+
+// NewRand returns a deterministic source of randomness for the generators.
+func NewRand(seed int64) *rand.Rand {
+	return rand.New(rand.NewSource(seed)) // #nosec G404 -- test data, not security sensitive
+}
+
+var (
+	requestTypes        = []string{"track", "identify", "page", "screen", "group", "alias", "batch"}
+	sourceTypes         = []string{"shopify", "stripe", "segment", "webhook", "auth0"}
+	botNames            = []string{"Googlebot", "Bingbot", "AhrefsBot", "facebookexternalhit", "curl"}
+	botActions          = []stream.BotAction{stream.BotActionFlag, stream.BotActionDisable}
+	stages              = []stream.Stage{"", stream.StageWebhook, stream.StageRetry, stream.StageReplay, stream.StageTransformation}
+	compressionSettings = []string{"1:1", "1:2", "1:3", "2:1", "2:4"}
+	encryptionSettings  = []string{"aes-gcm:128", "aes-gcm:192", "aes-gcm:256"}
+	migrationStatuses   = []cluster.PartitionMigrationStatus{
+		cluster.PartitionMigrationStatusNew,
+		cluster.PartitionMigrationStatusReloadingGW,
+		cluster.PartitionMigrationStatusReloadingSrcRouter,
+		cluster.PartitionMigrationStatusMigrating,
+		cluster.PartitionMigrationStatusCompleted,
+	}
+	migrationJobStatuses = []cluster.PartitionMigrationJobStatus{
+		cluster.PartitionMigrationJobStatusNew,
+		cluster.PartitionMigrationJobStatusMoved,
+		cluster.PartitionMigrationJobStatusCompleted,
+	}
+)
+
+// epoch is the start of the time range of generated times.
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const alphanumeric = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// ID returns a random identifier, with the same length and alphabet as the KSUIDs used for rudder resources.
+func ID(r *rand.Rand) string {
+	b := make([]byte, 27)
+	for i := range b {
+		b[i] = alphanumeric[r.Intn(len(alphanumeric))]
+	}
+	return string(b)
+}
+
+// Time returns a random UTC time with nanosecond precision, within a year from 2024-01-01.
+func Time(r *rand.Rand) time.Time {
+	return epoch.Add(time.Duration(r.Int63n(int64(365 * 24 * time.Hour))))
+}
+
+// MessageProperties returns random message properties. Every optional property and property group,
+// i.e. stage, bot, compression and encryption, is present with a probability of one half.
+// Signatures are never generated, since they depend on the payload, see stream.NewHMACSigner.
+func MessageProperties(r *rand.Rand) stream.MessageProperties {
+	p := stream.MessageProperties{
+		RequestType: pick(r, requestTypes),
+		RoutingKey:  ID(r),
+		WorkspaceID: ID(r),
+		SourceID:    ID(r),
+		ReceivedAt:  Time(r),
+		RequestIP:   net.IPv4(byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256))).String(), // #nosec G115 -- within range
+	}
+	p.PartitionID = optional(r, fmt.Sprintf("%s-%d", p.WorkspaceID, r.Intn(64)))
+	p.DestinationID = optional(r, ID(r))
+	p.UserID = optional(r, ID(r))
+	if r.Intn(2) == 0 {
+		p.SourceJobRunID = ID(r)
+		p.SourceTaskRunID = ID(r)
+	}
+	p.TraceID = optional(r, fmt.Sprintf("%016x%016x", r.Uint64(), r.Uint64()))
+	p.Compression = optional(r, pick(r, compressionSettings))
+	if r.Intn(2) == 0 {
+		p.Encryption = pick(r, encryptionSettings)
+		p.EncryptionKeyID = ID(r)
+	}
+	if r.Intn(2) == 0 {
+		p.IsBot = true
+		p.BotName = pick(r, botNames)
+		p.BotURL = optional(r, "https://bots.example.com/"+p.BotName)
+		p.BotIsInvalidBrowser = r.Intn(2) == 0
+		p.BotAction = pick(r, botActions)
+	}
+
+	p.Stage = pick(r, stages)
+	switch p.Stage {
+	case stream.StageWebhook:
+		p.SourceType = pick(r, sourceTypes)
+		p.WebhookFailureReason = optional(r, "invalid payload")
+	case stream.StageRetry:
+		p.RetryAttempt = 1 + r.Intn(10)
+		p.RetryLastError = optional(r, "context deadline exceeded")
+	case stream.StageReplay:
+		p.ReplayJobID = ID(r)
+		p.ReplayOriginalReceivedAt = p.ReceivedAt.Add(-time.Duration(1 + r.Int63n(int64(30*24*time.Hour))))
+	case stream.StageTransformation:
+		p.TransformerVersion = fmt.Sprintf("v%d.%d.%d", r.Intn(3), r.Intn(100), r.Intn(10))
+	}
+	return p
+}
+
+// Message returns a random message, with random properties and an event payload matching its request type.
+func Message(r *rand.Rand) stream.Message {
+	properties := MessageProperties(r)
+	event := map[string]any{
+		"type":        properties.RequestType,
+		"messageId":   ID(r),
+		"anonymousId": ID(r),
+		"userId":      properties.UserID,
+		"sentAt":      properties.ReceivedAt.Add(-time.Duration(r.Int63n(int64(time.Second)))).Format(time.RFC3339Nano),
+	}
+	traits := make(map[string]any)
+	for i := range r.Intn(5) {
+		traits[fmt.Sprintf("key%d", i)] = r.Intn(1000)
+	}
+	if len(traits) > 0 {
+		event["properties"] = traits
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		panic(fmt.Errorf("marshalling payload: %w", err))
+	}
+	return stream.Message{Properties: properties, Payload: payload}
+}
+
+// PartitionMigration returns a random migration of a random status, with up to 5 jobs between up to 6 nodes,
+// each one moving distinct partitions of a single workspace.
+func PartitionMigration(r *rand.Rand) *cluster.PartitionMigration {
+	id := ID(r)
+	statusIndex := r.Intn(len(migrationStatuses))
+	pm := &cluster.PartitionMigration{
+		ID:           id,
+		Status:       migrationStatuses[statusIndex],
+		StartTime:    Time(r),
+		AckKeyPrefix: "/migrations/" + id + "/ack",
+	}
+	if statusIndex > 0 {
+		pm.PreviousStatus = migrationStatuses[statusIndex-1]
+	}
+
+	workspaceID := ID(r)
+	partitions := r.Perm(64)
+	nodes := 2 + r.Intn(5)
+	for i := range 1 + r.Intn(5) {
+		source := r.Intn(nodes)
+		target := (source + 1 + r.Intn(nodes-1)) % nodes
+		job := &cluster.PartitionMigrationJobHeader{
+			JobID:      fmt.Sprintf("%s-%d", id, i),
+			SourceNode: source,
+			TargetNode: target,
+		}
+		for range 1 + r.Intn(3) {
+			job.Partitions = append(job.Partitions, fmt.Sprintf("%s-%d", workspaceID, partitions[0]))
+			partitions = partitions[1:]
+		}
+		pm.Jobs = append(pm.Jobs, job)
+	}
+	return pm
+}
+
+// PartitionMigrationInfo returns a random migration along with the status of its jobs:
+// jobs are new until the migration is migrating, and completed once it is completed.
+func PartitionMigrationInfo(r *rand.Rand) *cluster.PartitionMigrationInfo {
+	pm := PartitionMigration(r)
+	statuses := make(map[string]cluster.PartitionMigrationJobStatus, len(pm.Jobs))
+	for _, job := range pm.Jobs {
+		switch pm.Status {
+		case cluster.PartitionMigrationStatusMigrating:
+			statuses[job.JobID] = pick(r, migrationJobStatuses)
+		case cluster.PartitionMigrationStatusCompleted:
+			statuses[job.JobID] = cluster.PartitionMigrationJobStatusCompleted
+		default:
+			statuses[job.JobID] = cluster.PartitionMigrationJobStatusNew
+		}
+	}
+	var pmi cluster.PartitionMigrationInfo
+	pmi.FromPartitionMigration(*pm, statuses)
+	for _, job := range pmi.Jobs {
+		if job.Status != cluster.PartitionMigrationJobStatusNew {
+			job.StartTime = pm.StartTime.Add(time.Duration(r.Int63n(int64(time.Hour))))
+		}
+	}
+	return &pmi
+}
+
+// QuickMessageProperties implements quick.Generator for MessageProperties.
+type QuickMessageProperties struct{ stream.MessageProperties }
+
+func (QuickMessageProperties) Generate(r *rand.Rand, _ int) reflect.Value {
+	return reflect.ValueOf(QuickMessageProperties{MessageProperties(r)})
+}
+
+// QuickMessage implements quick.Generator for Message.
+type QuickMessage struct{ stream.Message }
+
+func (QuickMessage) Generate(r *rand.Rand, _ int) reflect.Value {
+	return reflect.ValueOf(QuickMessage{Message(r)})
+}
+
+// QuickPartitionMigration implements quick.Generator for PartitionMigration.
+type QuickPartitionMigration struct{ *cluster.PartitionMigration }
+
+func (QuickPartitionMigration) Generate(r *rand.Rand, _ int) reflect.Value {
+	return reflect.ValueOf(QuickPartitionMigration{PartitionMigration(r)})
+}
+
+// QuickPartitionMigrationInfo implements quick.Generator for PartitionMigrationInfo.
+type QuickPartitionMigrationInfo struct {
+	*cluster.PartitionMigrationInfo
+}
+
+func (QuickPartitionMigrationInfo) Generate(r *rand.Rand, _ int) reflect.Value {
+	return reflect.ValueOf(QuickPartitionMigrationInfo{PartitionMigrationInfo(r)})
+}
+
+func pick[T any](r *rand.Rand, values []T) T {
+	return values[r.Intn(len(values))]
+}
+
+// optional returns the value or the zero value, with a probability of one half.
+func optional[T any](r *rand.Rand, value T) T {
+	if r.Intn(2) == 0 {
+		var zero T
+		return zero
+	}
+	return value
+}
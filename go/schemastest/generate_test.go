@@ -0,0 +1,88 @@
+package schemastest_test
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-schemas/go/cluster"
+	"github.com/rudderlabs/rudder-schemas/go/schemastest"
+	"github.com/rudderlabs/rudder-schemas/go/stream"
+)
+
+func TestGenerators(t *testing.T) {
+	t.Run("deterministic", func(t *testing.T) {
+		require.Equal(t, schemastest.Message(schemastest.NewRand(42)), schemastest.Message(schemastest.NewRand(42)))
+		require.Equal(t, schemastest.PartitionMigrationInfo(schemastest.NewRand(42)), schemastest.PartitionMigrationInfo(schemastest.NewRand(42)))
+		require.NotEqual(t, schemastest.Message(schemastest.NewRand(42)), schemastest.Message(schemastest.NewRand(43)))
+	})
+
+	t.Run("messages are valid and in canonical shape", func(t *testing.T) {
+		validateMessage := stream.NewMessageValidator()
+		validateProperties := stream.NewMessagePropertiesValidator(stream.WithEncryptionPropertiesValidator())
+		stages := make(map[stream.Stage]int)
+		r := schemastest.NewRand(1)
+		for range 500 {
+			msg := schemastest.Message(r)
+			require.NoError(t, validateMessage(&msg))
+			require.NoError(t, validateProperties(&msg.Properties))
+			require.True(t, json.Valid(msg.Payload))
+
+			properties, err := stream.FromMapProperties(stream.ToMapProperties(msg.Properties))
+			require.NoError(t, err)
+			require.Equal(t, msg.Properties, properties)
+			stages[msg.Properties.Stage]++
+		}
+		require.Len(t, stages, 5, "all stages are generated")
+	})
+
+	t.Run("migrations are consistent", func(t *testing.T) {
+		r := schemastest.NewRand(1)
+		for range 500 {
+			pmi := schemastest.PartitionMigrationInfo(r)
+			require.NotEmpty(t, pmi.Jobs)
+			var partitions []string
+			for _, job := range pmi.Jobs {
+				require.NotEqual(t, job.SourceNode, job.TargetNode)
+				require.Equal(t, pmi.ID, job.MigrationID)
+				require.NotEmpty(t, job.Partitions)
+				partitions = append(partitions, job.Partitions...)
+				switch pmi.Status {
+				case cluster.PartitionMigrationStatusMigrating:
+				case cluster.PartitionMigrationStatusCompleted:
+					require.Equal(t, cluster.PartitionMigrationJobStatusCompleted, job.Status)
+				default:
+					require.Equal(t, cluster.PartitionMigrationJobStatusNew, job.Status)
+					require.True(t, job.StartTime.IsZero())
+				}
+			}
+			slices.Sort(partitions)
+			require.Len(t, slices.Compact(partitions), len(partitions), "partitions are moved by a single job")
+		}
+	})
+
+	t.Run("testing/quick", func(t *testing.T) {
+		require.NoError(t, quick.Check(func(m schemastest.QuickMessage) bool {
+			properties, err := stream.FromMapProperties(stream.ToMapProperties(m.Properties))
+			return err == nil && properties == m.Properties
+		}, nil))
+
+		require.NoError(t, quick.Check(func(pm schemastest.QuickPartitionMigration, pmi schemastest.QuickPartitionMigrationInfo, p schemastest.QuickMessageProperties) bool {
+			return pm.Clone().ID == pm.ID && pmi.Clone().ID == pmi.ID && p.WorkspaceID != ""
+		}, nil))
+	})
+}
+
+func FuzzMessageProperties(f *testing.F) {
+	f.Add(int64(0))
+	f.Add(int64(42))
+	f.Fuzz(func(t *testing.T, seed int64) {
+		properties := schemastest.MessageProperties(schemastest.NewRand(seed))
+		decoded, err := stream.FromMapProperties(stream.ToMapProperties(properties))
+		require.NoError(t, err)
+		require.Equal(t, properties, decoded)
+	})
+}
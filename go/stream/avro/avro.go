@@ -0,0 +1,310 @@
+// Package avro encodes stream messages with Avro, keeping its dependencies out of the stream package.
+package avro
+
+import (
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	hamba "github.com/hamba/avro/v2"
+	"github.com/hamba/avro/v2/soe"
+
+	"github.com/rudderlabs/rudder-schemas/go/stream"
+)
+
+// schemaFiles holds every released version of the Avro schema of stream.Message, named message.v<version>.avsc.
+// A schema file must never be changed once released: a new field of stream.MessageProperties requires a new version,
+// in which the field is optional with a null default, so that data written with older versions can still be read.
+//
+//go:embed *.avsc
+var schemaFiles embed.FS
+
+// ErrUnknownSchema is returned when decoding single-object encoded data written with an unknown schema.
+var ErrUnknownSchema = errors.New("unknown avro schema fingerprint")
+
+var (
+	schemaVersions = mustLoadSchemas()
+	currentSchema  = schemaVersions[len(schemaVersions)-1]
+	// resolvedSchemas contains, by writer fingerprint, the schemas for reading data of every version as the current one.
+	resolvedSchemas = mustResolveSchemas()
+)
+
+type schemaVersion struct {
+	version     int
+	text        string
+	schema      hamba.Schema
+	fingerprint []byte
+}
+
+// Schema returns the current Avro schema of stream.Message.
+func Schema() string {
+	return currentSchema.text
+}
+
+// Fingerprint returns the CRC-64-AVRO fingerprint of the current Avro schema of stream.Message, in little-endian order.
+func Fingerprint() []byte {
+	return slices.Clone(currentSchema.fingerprint)
+}
+
+// Marshal encodes a message in Avro binary encoding, using the current schema.
+// ReceivedAt is encoded as nanoseconds since the Unix epoch, optional fields are encoded as null when empty.
+func Marshal(msg *stream.Message) ([]byte, error) {
+	data, err := hamba.Marshal(currentSchema.schema, fromMessage(msg))
+	if err != nil {
+		return nil, fmt.Errorf("marshalling avro message: %w", err)
+	}
+	return data, nil
+}
+
+// Unmarshal decodes a message in Avro binary encoding, written with the current schema.
+// ReceivedAt is returned in UTC.
+func Unmarshal(data []byte, msg *stream.Message) error {
+	return unmarshal(currentSchema.schema, data, msg)
+}
+
+// MarshalSingleObject encodes a message using the Avro single-object encoding:
+// a two-byte marker, followed by the schema fingerprint and the Avro binary encoding of the message.
+func MarshalSingleObject(msg *stream.Message) ([]byte, error) {
+	header, err := soe.BuildHeaderForFingerprint(currentSchema.fingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("building avro header: %w", err)
+	}
+	data, err := Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return append(header, data...), nil
+}
+
+// UnmarshalSingleObject decodes a message in Avro single-object encoding, written with any released version of the schema.
+func UnmarshalSingleObject(data []byte, msg *stream.Message) error {
+	fingerprint, body, err := soe.ParseHeader(data)
+	if err != nil {
+		return fmt.Errorf("parsing avro header: %w", err)
+	}
+	schema, ok := resolvedSchemas[hex.EncodeToString(fingerprint)]
+	if !ok {
+		return fmt.Errorf("%w: %x", ErrUnknownSchema, fingerprint)
+	}
+	return unmarshal(schema, body, msg)
+}
+
+func unmarshal(schema hamba.Schema, data []byte, msg *stream.Message) error {
+	var am message
+	if err := hamba.Unmarshal(schema, data, &am); err != nil {
+		return fmt.Errorf("unmarshalling avro message: %w", err)
+	}
+	*msg = am.toMessage()
+	return nil
+}
+
+func mustLoadSchemas() []*schemaVersion {
+	var versions []*schemaVersion
+	err := fs.WalkDir(schemaFiles, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		v, ok := strings.CutPrefix(strings.TrimSuffix(path.Base(name), ".avsc"), "message.v")
+		if !ok {
+			return fmt.Errorf("unexpected avro schema file %q", name)
+		}
+		version, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("parsing version of avro schema file %q: %w", name, err)
+		}
+		text, err := fs.ReadFile(schemaFiles, name)
+		if err != nil {
+			return err
+		}
+		schema, err := hamba.ParseBytes(text)
+		if err != nil {
+			return fmt.Errorf("parsing avro schema file %q: %w", name, err)
+		}
+		fingerprint, err := soe.ComputeFingerprint(schema)
+		if err != nil {
+			return fmt.Errorf("computing fingerprint of avro schema file %q: %w", name, err)
+		}
+		versions = append(versions, &schemaVersion{version: version, text: string(text), schema: schema, fingerprint: fingerprint})
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+	slices.SortFunc(versions, func(a, b *schemaVersion) int { return a.version - b.version })
+	return versions
+}
+
+func mustResolveSchemas() map[string]hamba.Schema {
+	compatibility := hamba.NewSchemaCompatibility()
+	resolved := make(map[string]hamba.Schema, len(schemaVersions))
+	for _, v := range schemaVersions {
+		schema, err := compatibility.Resolve(currentSchema.schema, v.schema)
+		if err != nil {
+			panic(fmt.Errorf("avro schema v%d cannot be read with v%d: %w", v.version, currentSchema.version, err))
+		}
+		resolved[hex.EncodeToString(v.fingerprint)] = schema
+	}
+	return resolved
+}
+
+// message is the Go representation of the Avro schema of stream.Message.
+type message struct {
+	Properties messageProperties `avro:"properties"`
+	Payload    []byte            `avro:"payload"`
+}
+
+type messageProperties struct {
+	RequestType          string  `avro:"requestType"`
+	RoutingKey           string  `avro:"routingKey"`
+	WorkspaceID          string  `avro:"workspaceID"`
+	SourceID             string  `avro:"sourceID"`
+	ReceivedAt           int64   `avro:"receivedAt"`
+	RequestIP            string  `avro:"requestIP"`
+	DestinationID        *string `avro:"destinationID"`
+	UserID               *string `avro:"userID"`
+	SourceJobRunID       *string `avro:"sourceJobRunID"`
+	SourceTaskRunID      *string `avro:"sourceTaskRunID"`
+	TraceID              *string `avro:"traceID"`
+	SourceType           *string `avro:"sourceType"`
+	WebhookFailureReason *string `avro:"webhookFailureReason"`
+	Stage                *string `avro:"stage"`
+	Compression          *string `avro:"compression"`
+	Encryption           *string `avro:"encryption"`
+	EncryptionKeyID      *string `avro:"encryptionKeyID"`
+	IsBot                *bool   `avro:"isBot"`
+	BotName              *string `avro:"botName"`
+	BotURL               *string `avro:"botURL"`
+	BotIsInvalidBrowser  *bool   `avro:"botIsInvalidBrowser"`
+	BotAction            *string `avro:"botAction"`
+	PartitionID          *string `avro:"partitionID"`
+	Signature            *string `avro:"signature"`
+	SignatureKeyID       *string `avro:"signatureKeyID"`
+
+	RetryAttempt             *int64  `avro:"retryAttempt"`
+	RetryLastError           *string `avro:"retryLastError"`
+	ReplayJobID              *string `avro:"replayJobID"`
+	ReplayOriginalReceivedAt *int64  `avro:"replayOriginalReceivedAt"`
+	TransformerVersion       *string `avro:"transformerVersion"`
+}
+
+func fromMessage(msg *stream.Message) *message {
+	p := msg.Properties
+	return &message{
+		Properties: messageProperties{
+			RequestType:          p.RequestType,
+			RoutingKey:           p.RoutingKey,
+			WorkspaceID:          p.WorkspaceID,
+			SourceID:             p.SourceID,
+			ReceivedAt:           p.ReceivedAt.UnixNano(),
+			RequestIP:            p.RequestIP,
+			DestinationID:        optional(p.DestinationID),
+			UserID:               optional(p.UserID),
+			SourceJobRunID:       optional(p.SourceJobRunID),
+			SourceTaskRunID:      optional(p.SourceTaskRunID),
+			TraceID:              optional(p.TraceID),
+			SourceType:           optional(p.SourceType),
+			WebhookFailureReason: optional(p.WebhookFailureReason),
+			Stage:                optional(string(p.Stage)),
+			Compression:          optional(p.Compression),
+			Encryption:           optional(p.Encryption),
+			EncryptionKeyID:      optional(p.EncryptionKeyID),
+			IsBot:                optional(p.IsBot),
+			BotName:              optional(p.BotName),
+			BotURL:               optional(p.BotURL),
+			BotIsInvalidBrowser:  optional(p.BotIsInvalidBrowser),
+			BotAction:            optional(string(p.BotAction)),
+			PartitionID:          optional(p.PartitionID),
+			Signature:            optional(p.Signature),
+			SignatureKeyID:       optional(p.SignatureKeyID),
+
+			RetryAttempt:             optional(int64(p.RetryAttempt)),
+			RetryLastError:           optional(p.RetryLastError),
+			ReplayJobID:              optional(p.ReplayJobID),
+			ReplayOriginalReceivedAt: optionalTime(p.ReplayOriginalReceivedAt),
+			TransformerVersion:       optional(p.TransformerVersion),
+		},
+		Payload: msg.Payload,
+	}
+}
+
+func (am *message) toMessage() stream.Message {
+	p := am.Properties
+	return stream.Message{
+		Properties: stream.MessageProperties{
+			RequestType:          p.RequestType,
+			RoutingKey:           p.RoutingKey,
+			WorkspaceID:          p.WorkspaceID,
+			SourceID:             p.SourceID,
+			ReceivedAt:           time.Unix(0, p.ReceivedAt).UTC(),
+			RequestIP:            p.RequestIP,
+			DestinationID:        value(p.DestinationID),
+			UserID:               value(p.UserID),
+			SourceJobRunID:       value(p.SourceJobRunID),
+			SourceTaskRunID:      value(p.SourceTaskRunID),
+			TraceID:              value(p.TraceID),
+			SourceType:           value(p.SourceType),
+			WebhookFailureReason: value(p.WebhookFailureReason),
+			Stage:                stream.Stage(value(p.Stage)),
+			Compression:          value(p.Compression),
+			Encryption:           value(p.Encryption),
+			EncryptionKeyID:      value(p.EncryptionKeyID),
+			IsBot:                value(p.IsBot),
+			BotName:              value(p.BotName),
+			BotURL:               value(p.BotURL),
+			BotIsInvalidBrowser:  value(p.BotIsInvalidBrowser),
+			BotAction:            stream.BotAction(value(p.BotAction)),
+			PartitionID:          value(p.PartitionID),
+			Signature:            value(p.Signature),
+			SignatureKeyID:       value(p.SignatureKeyID),
+
+			RetryAttempt:             int(value(p.RetryAttempt)),
+			RetryLastError:           value(p.RetryLastError),
+			ReplayJobID:              value(p.ReplayJobID),
+			ReplayOriginalReceivedAt: timeValue(p.ReplayOriginalReceivedAt),
+			TransformerVersion:       value(p.TransformerVersion),
+		},
+		Payload: am.Payload,
+	}
+}
+
+// optional returns nil for the zero value, which is encoded as null.
+func optional[T comparable](v T) *T {
+	var zero T
+	if v == zero {
+		return nil
+	}
+	return &v
+}
+
+// value returns the zero value for nil.
+func value[T any](v *T) T {
+	var zero T
+	if v == nil {
+		return zero
+	}
+	return *v
+}
+
+// optionalTime returns nil for the zero time, otherwise the nanoseconds since the Unix epoch.
+func optionalTime(t time.Time) *int64 {
+	if t.IsZero() {
+		return nil
+	}
+	nanos := t.UnixNano()
+	return &nanos
+}
+
+// timeValue returns the zero time for nil, otherwise the time in UTC.
+func timeValue(nanos *int64) time.Time {
+	if nanos == nil {
+		return time.Time{}
+	}
+	return time.Unix(0, *nanos).UTC()
+}
@@ -0,0 +1,239 @@
+package avro_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	hamba "github.com/hamba/avro/v2"
+	"github.com/hamba/avro/v2/soe"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-schemas/go/stream"
+	"github.com/rudderlabs/rudder-schemas/go/stream/avro"
+)
+
+func TestAvro(t *testing.T) {
+	msg := stream.Message{
+		Properties: stream.MessageProperties{
+			RequestType:          "requestType",
+			RoutingKey:           "routingKey",
+			WorkspaceID:          "workspaceID",
+			UserID:               "userID",
+			SourceID:             "sourceID",
+			DestinationID:        "destinationID",
+			RequestIP:            "10.29.13.20",
+			ReceivedAt:           time.Date(2024, 8, 1, 0o2, 30, 50, 200, time.UTC),
+			SourceJobRunID:       "sourceJobRunID",
+			SourceTaskRunID:      "sourceTaskRunID",
+			TraceID:              "traceID",
+			SourceType:           "sourceType",
+			WebhookFailureReason: "webhookFailureReason",
+			Stage:                stream.StageWebhook,
+			Compression:          "some-serialized-compression-settings",
+			Encryption:           "some-serialized-encryption-settings",
+			EncryptionKeyID:      "encryptionKeyID",
+			IsBot:                true,
+			BotName:              "TestBot",
+			BotURL:               "https://testbot.com",
+			BotIsInvalidBrowser:  true,
+			BotAction:            "flag",
+			PartitionID:          "workspaceID-0",
+			Signature:            "c2lnbmF0dXJl",
+			SignatureKeyID:       "key-1",
+		},
+		Payload: json.RawMessage(`{"key":"value"}`),
+	}
+
+	t.Run("marshal unmarshal", func(t *testing.T) {
+		data, err := avro.Marshal(&msg)
+		require.NoError(t, err)
+
+		var unmarshaled stream.Message
+		require.NoError(t, avro.Unmarshal(data, &unmarshaled))
+		require.Equal(t, msg, unmarshaled)
+	})
+
+	t.Run("marshal unmarshal: optional fields", func(t *testing.T) {
+		msg := stream.Message{
+			Properties: stream.MessageProperties{
+				RequestType: "requestType",
+				RoutingKey:  "routingKey",
+				WorkspaceID: "workspaceID",
+				SourceID:    "sourceID",
+				RequestIP:   "10.29.13.20",
+				ReceivedAt:  time.Date(2024, 8, 1, 0o4, 30, 50, 200, time.FixedZone("UTC+2", 2*60*60)),
+			},
+			Payload: json.RawMessage(`{}`),
+		}
+		data, err := avro.Marshal(&msg)
+		require.NoError(t, err)
+
+		var unmarshaled stream.Message
+		require.NoError(t, avro.Unmarshal(data, &unmarshaled))
+		require.True(t, msg.Properties.ReceivedAt.Equal(unmarshaled.Properties.ReceivedAt))
+		require.Equal(t, time.UTC, unmarshaled.Properties.ReceivedAt.Location())
+		unmarshaled.Properties.ReceivedAt = msg.Properties.ReceivedAt
+		require.Equal(t, msg, unmarshaled)
+
+		var native map[string]any
+		require.NoError(t, hamba.Unmarshal(hamba.MustParse(avro.Schema()), data, &native))
+		properties := native["properties"].(map[string]any)
+		require.Nil(t, properties["userID"], "empty optional fields are encoded as null")
+		require.Nil(t, properties["isBot"], "empty optional fields are encoded as null")
+	})
+
+	t.Run("marshal unmarshal: stage properties", func(t *testing.T) {
+		for _, properties := range []stream.MessageProperties{
+			{Stage: stream.StageRetry, RetryAttempt: 3, RetryLastError: "timeout"},
+			{Stage: stream.StageReplay, ReplayJobID: "replay-1", ReplayOriginalReceivedAt: time.Date(2024, 7, 1, 0o2, 30, 50, 100, time.UTC)},
+			{Stage: stream.StageTransformation, TransformerVersion: "v1.2.3"},
+		} {
+			t.Run(string(properties.Stage), func(t *testing.T) {
+				msg := msg
+				msg.Properties.Stage = properties.Stage
+				msg.Properties.SourceType = ""
+				msg.Properties.WebhookFailureReason = ""
+				msg.Properties.RetryAttempt = properties.RetryAttempt
+				msg.Properties.RetryLastError = properties.RetryLastError
+				msg.Properties.ReplayJobID = properties.ReplayJobID
+				msg.Properties.ReplayOriginalReceivedAt = properties.ReplayOriginalReceivedAt
+				msg.Properties.TransformerVersion = properties.TransformerVersion
+
+				data, err := avro.Marshal(&msg)
+				require.NoError(t, err)
+				var unmarshaled stream.Message
+				require.NoError(t, avro.Unmarshal(data, &unmarshaled))
+				require.Equal(t, msg, unmarshaled)
+			})
+		}
+	})
+
+	t.Run("single object encoding", func(t *testing.T) {
+		data, err := avro.MarshalSingleObject(&msg)
+		require.NoError(t, err)
+		require.Equal(t, []byte{0xc3, 0x01}, data[:2])
+		require.Equal(t, avro.Fingerprint(), data[2:10])
+
+		var unmarshaled stream.Message
+		require.NoError(t, avro.UnmarshalSingleObject(data, &unmarshaled))
+		require.Equal(t, msg, unmarshaled)
+
+		t.Run("older schema version", func(t *testing.T) {
+			v1, err := os.ReadFile("message.v1.avsc")
+			require.NoError(t, err)
+			schema := hamba.MustParse(string(v1))
+			type properties struct {
+				RequestType string `avro:"requestType"`
+				RoutingKey  string `avro:"routingKey"`
+				WorkspaceID string `avro:"workspaceID"`
+				SourceID    string `avro:"sourceID"`
+				ReceivedAt  int64  `avro:"receivedAt"`
+				RequestIP   string `avro:"requestIP"`
+			}
+			type message struct {
+				Properties properties `avro:"properties"`
+				Payload    []byte     `avro:"payload"`
+			}
+			body, err := hamba.Marshal(schema, message{
+				Properties: properties{
+					RequestType: "requestType",
+					RoutingKey:  "routingKey",
+					WorkspaceID: "workspaceID",
+					SourceID:    "sourceID",
+					ReceivedAt:  msg.Properties.ReceivedAt.UnixNano(),
+					RequestIP:   "10.29.13.20",
+				},
+				Payload: []byte(`{}`),
+			})
+			require.NoError(t, err)
+			fingerprint, err := soe.ComputeFingerprint(schema)
+			require.NoError(t, err)
+			header, err := soe.BuildHeaderForFingerprint(fingerprint)
+			require.NoError(t, err)
+
+			var unmarshaled stream.Message
+			require.NoError(t, avro.UnmarshalSingleObject(append(header, body...), &unmarshaled))
+			require.Equal(t, stream.Message{
+				Properties: stream.MessageProperties{
+					RequestType: "requestType",
+					RoutingKey:  "routingKey",
+					WorkspaceID: "workspaceID",
+					SourceID:    "sourceID",
+					ReceivedAt:  msg.Properties.ReceivedAt,
+					RequestIP:   "10.29.13.20",
+				},
+				Payload: json.RawMessage(`{}`),
+			}, unmarshaled)
+		})
+
+		t.Run("unknown fingerprint", func(t *testing.T) {
+			unknown := append([]byte{0xc3, 0x01, 1, 2, 3, 4, 5, 6, 7, 8}, data[10:]...)
+			err := avro.UnmarshalSingleObject(unknown, &unmarshaled)
+			require.ErrorIs(t, err, avro.ErrUnknownSchema)
+			require.EqualError(t, err, "unknown avro schema fingerprint: 0102030405060708")
+		})
+
+		t.Run("invalid header", func(t *testing.T) {
+			err := avro.UnmarshalSingleObject([]byte{0x00, 0x01, 2, 3}, &unmarshaled)
+			require.ErrorContains(t, err, "parsing avro header")
+		})
+	})
+
+	t.Run("compatibility", func(t *testing.T) {
+		current := hamba.MustParse(avro.Schema())
+		files, err := filepath.Glob("message.v*.avsc")
+		require.NoError(t, err)
+		require.NotEmpty(t, files)
+
+		// released schemas must never change, add a new version instead
+		released := map[string]string{
+			"message.v1.avsc": "b3d0647e4823e660",
+			"message.v2.avsc": "358efb38662427b1",
+		}
+
+		for _, file := range files {
+			t.Run(filepath.Base(file), func(t *testing.T) {
+				data, err := os.ReadFile(file)
+				require.NoError(t, err)
+				writer, err := hamba.ParseBytes(data)
+				require.NoError(t, err)
+
+				fingerprint, err := soe.ComputeFingerprint(writer)
+				require.NoError(t, err)
+				expected, ok := released[filepath.Base(file)]
+				require.True(t, ok, "fingerprint of new avro schema versions must be added to the released schemas")
+				require.Equal(t, expected, hex.EncodeToString(fingerprint), "released avro schema has been modified")
+
+				require.NoError(t, hamba.NewSchemaCompatibility().Compatible(current, writer),
+					"current avro schema cannot read data written with %s", filepath.Base(file))
+			})
+		}
+	})
+
+	t.Run("schema covers all message properties", func(t *testing.T) {
+		current := hamba.MustParse(avro.Schema()).(*hamba.RecordSchema)
+		var properties *hamba.RecordSchema
+		for _, f := range current.Fields() {
+			if f.Name() == "properties" {
+				properties = f.Type().(*hamba.RecordSchema)
+			}
+		}
+		require.NotNil(t, properties)
+
+		fields := map[string]bool{}
+		for _, f := range properties.Fields() {
+			fields[f.Name()] = true
+		}
+		rt := reflect.TypeFor[stream.MessageProperties]()
+		for i := range rt.NumField() {
+			name, _, _ := strings.Cut(rt.Field(i).Tag.Get("json"), ",")
+			require.True(t, fields[name], "field %s of MessageProperties is missing from the avro schema", name)
+		}
+	})
+}
@@ -0,0 +1,239 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"slices"
+	"time"
+)
+
+// MessageBuilder builds valid messages, setting defaults for the properties that can be derived.
+// A builder can be used as a template: Build does not modify it, so it can be called multiple times.
+type MessageBuilder struct {
+	properties MessageProperties
+	payload    json.RawMessage
+
+	now         func() time.Time
+	routingKey  func(properties MessageProperties) string
+	partitionID func(properties MessageProperties) string
+
+	validateProperties func(properties *MessageProperties) error
+	validateMessage    func(msg *Message) error
+}
+
+// NewMessageBuilder returns a MessageBuilder. By default ReceivedAt is set to the current time,
+// and messages are validated with NewMessagePropertiesValidator and WithEncryptionPropertiesValidator.
+func NewMessageBuilder(opt ...func(b *MessageBuilder)) *MessageBuilder {
+	b := &MessageBuilder{
+		now:                time.Now,
+		validateProperties: NewMessagePropertiesValidator(WithEncryptionPropertiesValidator()),
+		validateMessage:    NewMessageValidator(),
+	}
+	for _, o := range opt {
+		o(b)
+	}
+	return b
+}
+
+// WithBuilderClock sets the clock used for defaulting ReceivedAt.
+func WithBuilderClock(now func() time.Time) func(b *MessageBuilder) {
+	return func(b *MessageBuilder) {
+		b.now = now
+	}
+}
+
+// WithBuilderRoutingKeyStrategy sets the strategy used for deriving RoutingKey, when not set explicitly.
+func WithBuilderRoutingKeyStrategy(strategy func(properties MessageProperties) string) func(b *MessageBuilder) {
+	return func(b *MessageBuilder) {
+		b.routingKey = strategy
+	}
+}
+
+// WithBuilderPartitionIDStrategy sets the strategy used for deriving PartitionID, when not set explicitly.
+// It is applied after RoutingKey has been derived.
+func WithBuilderPartitionIDStrategy(strategy func(properties MessageProperties) string) func(b *MessageBuilder) {
+	return func(b *MessageBuilder) {
+		b.partitionID = strategy
+	}
+}
+
+// WithBuilderPropertiesValidators replaces the additional validators of the message properties,
+// see NewMessagePropertiesValidator.
+func WithBuilderPropertiesValidators(opt ...func(properties *MessageProperties) error) func(b *MessageBuilder) {
+	return func(b *MessageBuilder) {
+		b.validateProperties = NewMessagePropertiesValidator(opt...)
+	}
+}
+
+// HashPartitionIDStrategy returns a partition ID strategy assigning messages to one of the given number of partitions
+// of their workspace, by hashing the routing key. Partition IDs have the form "<workspaceID>-<partition index>".
+// It panics if the number of partitions is not positive, or doesn't fit in 32 bits.
+func HashPartitionIDStrategy(partitions int) func(properties MessageProperties) string {
+	if partitions <= 0 || uint64(partitions) > math.MaxUint32 {
+		panic(fmt.Errorf("hash partition ID strategy: invalid number of partitions %d", partitions))
+	}
+	n := uint32(partitions)
+	return func(properties MessageProperties) string {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(properties.RoutingKey))
+		return fmt.Sprintf("%s-%d", properties.WorkspaceID, h.Sum32()%n)
+	}
+}
+
+// Properties replaces all properties of the builder.
+func (b *MessageBuilder) Properties(properties MessageProperties) *MessageBuilder {
+	b.properties = properties
+	return b
+}
+
+func (b *MessageBuilder) RequestType(requestType string) *MessageBuilder {
+	b.properties.RequestType = requestType
+	return b
+}
+
+func (b *MessageBuilder) RoutingKey(routingKey string) *MessageBuilder {
+	b.properties.RoutingKey = routingKey
+	return b
+}
+
+func (b *MessageBuilder) WorkspaceID(workspaceID string) *MessageBuilder {
+	b.properties.WorkspaceID = workspaceID
+	return b
+}
+
+func (b *MessageBuilder) SourceID(sourceID string) *MessageBuilder {
+	b.properties.SourceID = sourceID
+	return b
+}
+
+func (b *MessageBuilder) DestinationID(destinationID string) *MessageBuilder {
+	b.properties.DestinationID = destinationID
+	return b
+}
+
+func (b *MessageBuilder) UserID(userID string) *MessageBuilder {
+	b.properties.UserID = userID
+	return b
+}
+
+func (b *MessageBuilder) RequestIP(requestIP string) *MessageBuilder {
+	b.properties.RequestIP = requestIP
+	return b
+}
+
+// ReceivedAt sets the time at which the message was received, instead of the time of the builder's clock.
+func (b *MessageBuilder) ReceivedAt(receivedAt time.Time) *MessageBuilder {
+	b.properties.ReceivedAt = receivedAt
+	return b
+}
+
+// SourceJobRun sets the IDs of the source job and task runs.
+func (b *MessageBuilder) SourceJobRun(jobRunID, taskRunID string) *MessageBuilder {
+	b.properties.SourceJobRunID = jobRunID
+	b.properties.SourceTaskRunID = taskRunID
+	return b
+}
+
+func (b *MessageBuilder) TraceID(traceID string) *MessageBuilder {
+	b.properties.TraceID = traceID
+	return b
+}
+
+func (b *MessageBuilder) PartitionID(partitionID string) *MessageBuilder {
+	b.properties.PartitionID = partitionID
+	return b
+}
+
+// Compression sets the serialized compression settings of the payload.
+func (b *MessageBuilder) Compression(settings string) *MessageBuilder {
+	b.properties.Compression = settings
+	return b
+}
+
+// Encryption sets the serialized encryption settings of the payload, along with the ID of the key used.
+func (b *MessageBuilder) Encryption(settings, keyID string) *MessageBuilder {
+	b.properties.Encryption = settings
+	b.properties.EncryptionKeyID = keyID
+	return b
+}
+
+// Bot marks the message as sent by a bot.
+func (b *MessageBuilder) Bot(name, url string, invalidBrowser bool, action BotAction) *MessageBuilder {
+	b.properties.IsBot = true
+	b.properties.BotName = name
+	b.properties.BotURL = url
+	b.properties.BotIsInvalidBrowser = invalidBrowser
+	b.properties.BotAction = action
+	return b
+}
+
+// Webhook sets the webhook stage, along with its properties.
+func (b *MessageBuilder) Webhook(sourceType, failureReason string) *MessageBuilder {
+	b.properties.Stage = StageWebhook
+	b.properties.SourceType = sourceType
+	b.properties.WebhookFailureReason = failureReason
+	return b
+}
+
+// Retry sets the retry stage, along with its properties.
+func (b *MessageBuilder) Retry(attempt int, lastError string) *MessageBuilder {
+	b.properties.Stage = StageRetry
+	b.properties.RetryAttempt = attempt
+	b.properties.RetryLastError = lastError
+	return b
+}
+
+// Replay sets the replay stage, along with its properties.
+func (b *MessageBuilder) Replay(jobID string, originalReceivedAt time.Time) *MessageBuilder {
+	b.properties.Stage = StageReplay
+	b.properties.ReplayJobID = jobID
+	b.properties.ReplayOriginalReceivedAt = originalReceivedAt
+	return b
+}
+
+// Transformation sets the transformation stage, along with its properties.
+func (b *MessageBuilder) Transformation(transformerVersion string) *MessageBuilder {
+	b.properties.Stage = StageTransformation
+	b.properties.TransformerVersion = transformerVersion
+	return b
+}
+
+// Payload sets the JSON payload of the message.
+func (b *MessageBuilder) Payload(payload json.RawMessage) *MessageBuilder {
+	b.payload = payload
+	return b
+}
+
+// Build returns a new message after setting the defaults of the properties that are not set and validating it.
+func (b *MessageBuilder) Build() (Message, error) {
+	properties := b.properties
+	if properties.ReceivedAt.IsZero() {
+		properties.ReceivedAt = b.now().UTC()
+	}
+	if properties.RoutingKey == "" && b.routingKey != nil {
+		properties.RoutingKey = b.routingKey(properties)
+	}
+	if properties.PartitionID == "" && b.partitionID != nil {
+		properties.PartitionID = b.partitionID(properties)
+	}
+
+	msg := Message{Properties: properties, Payload: slices.Clone(b.payload)}
+	if err := b.validateProperties(&msg.Properties); err != nil {
+		return Message{}, fmt.Errorf("invalid message properties: %w", err)
+	}
+	if err := b.validateMessage(&msg); err != nil {
+		return Message{}, fmt.Errorf("invalid message: %w", err)
+	}
+	return msg, nil
+}
+
+// MustBuild is like Build but panics if the message is invalid, e.g. for building fixtures in tests.
+func (b *MessageBuilder) MustBuild() Message {
+	msg, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+	return msg
+}
@@ -0,0 +1,185 @@
+package stream_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-schemas/go/stream"
+)
+
+func TestMessageBuilder(t *testing.T) {
+	now := time.Date(2024, 8, 1, 0o2, 30, 50, 200, time.FixedZone("CEST", 2*60*60))
+	clock := func() time.Time { return now }
+
+	newBuilder := func(opt ...func(b *stream.MessageBuilder)) *stream.MessageBuilder {
+		return stream.NewMessageBuilder(append([]func(b *stream.MessageBuilder){stream.WithBuilderClock(clock)}, opt...)...).
+			RequestType("track").
+			RoutingKey("routingKey").
+			WorkspaceID("workspaceID").
+			SourceID("sourceID").
+			RequestIP("10.29.13.20").
+			Payload(json.RawMessage(`{"event":"test"}`))
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		msg, err := newBuilder().Build()
+		require.NoError(t, err)
+		require.Equal(t, stream.Message{
+			Properties: stream.MessageProperties{
+				RequestType: "track",
+				RoutingKey:  "routingKey",
+				WorkspaceID: "workspaceID",
+				SourceID:    "sourceID",
+				RequestIP:   "10.29.13.20",
+				ReceivedAt:  now.UTC(),
+			},
+			Payload: json.RawMessage(`{"event":"test"}`),
+		}, msg)
+		require.Equal(t, time.UTC, msg.Properties.ReceivedAt.Location())
+
+		t.Run("survives a property map round trip", func(t *testing.T) {
+			properties, err := stream.FromMapProperties(stream.ToMapProperties(msg.Properties))
+			require.NoError(t, err)
+			require.Equal(t, msg.Properties, properties)
+		})
+	})
+
+	t.Run("explicit values are not overridden", func(t *testing.T) {
+		receivedAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		msg, err := newBuilder(
+			stream.WithBuilderRoutingKeyStrategy(func(stream.MessageProperties) string { return "derived" }),
+			stream.WithBuilderPartitionIDStrategy(func(stream.MessageProperties) string { return "derived" }),
+		).ReceivedAt(receivedAt).PartitionID("workspaceID-1").Build()
+		require.NoError(t, err)
+		require.Equal(t, receivedAt, msg.Properties.ReceivedAt)
+		require.Equal(t, "routingKey", msg.Properties.RoutingKey)
+		require.Equal(t, "workspaceID-1", msg.Properties.PartitionID)
+	})
+
+	t.Run("strategies", func(t *testing.T) {
+		b := newBuilder(
+			stream.WithBuilderRoutingKeyStrategy(func(p stream.MessageProperties) string {
+				return p.SourceID + ":" + p.UserID
+			}),
+			stream.WithBuilderPartitionIDStrategy(stream.HashPartitionIDStrategy(4)),
+		).RoutingKey("")
+
+		msg1 := b.UserID("user-1").MustBuild()
+		require.Equal(t, "sourceID:user-1", msg1.Properties.RoutingKey)
+		require.Regexp(t, `^workspaceID-[0-3]$`, msg1.Properties.PartitionID)
+		require.Equal(t, msg1, b.MustBuild(), "strategies are deterministic")
+
+		partitions := map[string]struct{}{}
+		for _, userID := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+			partitions[b.UserID(userID).MustBuild().Properties.PartitionID] = struct{}{}
+		}
+		require.Greater(t, len(partitions), 1, "routing keys are spread across partitions")
+
+		require.PanicsWithError(t, "hash partition ID strategy: invalid number of partitions 0", func() { stream.HashPartitionIDStrategy(0) })
+		require.PanicsWithError(t, "hash partition ID strategy: invalid number of partitions -1", func() { stream.HashPartitionIDStrategy(-1) })
+	})
+
+	t.Run("stages", func(t *testing.T) {
+		originalReceivedAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		for _, tc := range []struct {
+			name     string
+			build    func(b *stream.MessageBuilder) *stream.MessageBuilder
+			expected func(p *stream.MessageProperties)
+		}{
+			{
+				name:  "webhook",
+				build: func(b *stream.MessageBuilder) *stream.MessageBuilder { return b.Webhook("shopify", "reason") },
+				expected: func(p *stream.MessageProperties) {
+					p.Stage, p.SourceType, p.WebhookFailureReason = stream.StageWebhook, "shopify", "reason"
+				},
+			},
+			{
+				name:  "retry",
+				build: func(b *stream.MessageBuilder) *stream.MessageBuilder { return b.Retry(2, "timeout") },
+				expected: func(p *stream.MessageProperties) {
+					p.Stage, p.RetryAttempt, p.RetryLastError = stream.StageRetry, 2, "timeout"
+				},
+			},
+			{
+				name:  "replay",
+				build: func(b *stream.MessageBuilder) *stream.MessageBuilder { return b.Replay("job", originalReceivedAt) },
+				expected: func(p *stream.MessageProperties) {
+					p.Stage, p.ReplayJobID, p.ReplayOriginalReceivedAt = stream.StageReplay, "job", originalReceivedAt
+				},
+			},
+			{
+				name:  "transformation",
+				build: func(b *stream.MessageBuilder) *stream.MessageBuilder { return b.Transformation("v1.2.3") },
+				expected: func(p *stream.MessageProperties) {
+					p.Stage, p.TransformerVersion = stream.StageTransformation, "v1.2.3"
+				},
+			},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				expected := newBuilder().MustBuild().Properties
+				tc.expected(&expected)
+				msg, err := tc.build(newBuilder()).Build()
+				require.NoError(t, err)
+				require.Equal(t, expected, msg.Properties)
+			})
+		}
+	})
+
+	t.Run("builder is a template", func(t *testing.T) {
+		b := newBuilder()
+		msg := b.MustBuild()
+		msg.Payload[0] = '['
+
+		_ = b.Bot("bot", "https://bot.example.com", false, stream.BotActionFlag).MustBuild()
+		require.Equal(t, json.RawMessage(`{"event":"test"}`), b.MustBuild().Payload, "built messages don't share the payload")
+		require.True(t, b.MustBuild().Properties.IsBot, "setters after Build apply to later messages")
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		t.Run("required properties", func(t *testing.T) {
+			_, err := newBuilder().WorkspaceID("").Build()
+			require.EqualError(t, err, "invalid message properties: Key: 'MessageProperties.WorkspaceID' Error:Field validation for 'WorkspaceID' failed on the 'required' tag")
+		})
+
+		t.Run("required payload", func(t *testing.T) {
+			_, err := newBuilder().Payload(nil).Build()
+			require.EqualError(t, err, "invalid message: Key: 'Message.Payload' Error:Field validation for 'Payload' failed on the 'required' tag")
+		})
+
+		t.Run("encryption requires a key ID", func(t *testing.T) {
+			_, err := newBuilder().Encryption("aes-gcm:256", "").Build()
+			require.EqualError(t, err, "invalid message properties: encryption key ID is required when encryption is set")
+
+			_, err = newBuilder().Encryption("aes-gcm:256", "keyID").Build()
+			require.NoError(t, err)
+		})
+
+		t.Run("stage properties", func(t *testing.T) {
+			_, err := newBuilder().Replay("", time.Time{}).Build()
+			require.Error(t, err)
+		})
+
+		t.Run("custom validators", func(t *testing.T) {
+			errNoUser := errors.New("user ID is required")
+			b := newBuilder(stream.WithBuilderPropertiesValidators(func(p *stream.MessageProperties) error {
+				if p.UserID == "" {
+					return errNoUser
+				}
+				return nil
+			}))
+			_, err := b.Build()
+			require.ErrorIs(t, err, errNoUser)
+
+			_, err = b.UserID("userID").Encryption("aes-gcm:256", "").Build()
+			require.NoError(t, err, "validators replace the default ones")
+		})
+
+		t.Run("MustBuild panics", func(t *testing.T) {
+			require.Panics(t, func() { newBuilder().SourceID("").MustBuild() })
+		})
+	})
+}
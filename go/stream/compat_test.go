@@ -0,0 +1,149 @@
+package stream_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-schemas/go/internal/compattest"
+	"github.com/rudderlabs/rudder-schemas/go/stream"
+)
+
+// compatCase is a property map produced by a released version of the library,
+// along with the MessageProperties it must decode to.
+//
+// The corpus lives in testdata/compat/properties, one file per version that changed the shape of the property map.
+// Released files must never be changed: if ToMapProperties changes its output, add the new shapes to head.json,
+// which is renamed after the upcoming version once it is released.
+type compatCase struct {
+	Name       string                   `json:"name"`
+	Properties map[string]string        `json:"properties"`
+	Expected   stream.MessageProperties `json:"expected"`
+}
+
+func TestCompatibility(t *testing.T) {
+	corpus := loadCompatCorpus(t)
+	latest := corpus[len(corpus)-1]
+
+	t.Run("released property maps decode", func(t *testing.T) {
+		for _, c := range corpus {
+			for _, cc := range c.Content {
+				t.Run(c.Version+"/"+cc.Name, func(t *testing.T) {
+					properties, err := stream.FromMapProperties(cc.Properties)
+					require.NoError(t, err)
+					require.Equal(t, cc.Expected, properties)
+				})
+			}
+		}
+	})
+
+	t.Run("released property maps stay valid", func(t *testing.T) {
+		validate := stream.NewMessagePropertiesValidator(stream.WithEncryptionPropertiesValidator())
+		for _, c := range corpus {
+			for _, cc := range c.Content {
+				t.Run(c.Version+"/"+cc.Name, func(t *testing.T) {
+					properties, err := stream.FromMapProperties(cc.Properties)
+					require.NoError(t, err)
+					require.NoError(t, validate(&properties))
+				})
+			}
+		}
+	})
+
+	t.Run("current output is in the corpus", func(t *testing.T) {
+		for _, cc := range latest.Content {
+			t.Run(cc.Name, func(t *testing.T) {
+				require.Equal(t, cc.Properties, stream.ToMapProperties(cc.Expected),
+					"the output of ToMapProperties changed, add the new shape to %s.json, released files must not change", compattest.HeadVersion)
+			})
+		}
+	})
+
+	t.Run("current output is readable by the previous minor version", func(t *testing.T) {
+		for _, cc := range latest.Content {
+			t.Run(cc.Name, func(t *testing.T) {
+				properties, err := previousMinorFromMapProperties(stream.ToMapProperties(cc.Expected))
+				require.NoError(t, err)
+
+				expected := cc.Expected
+				// fields introduced after the previous minor version
+				expected.Signature = ""
+				expected.SignatureKeyID = ""
+				expected.RetryAttempt = 0
+				expected.RetryLastError = ""
+				expected.ReplayJobID = ""
+				expected.ReplayOriginalReceivedAt = time.Time{}
+				expected.TransformerVersion = ""
+				require.Equal(t, expected, properties)
+			})
+		}
+	})
+}
+
+func loadCompatCorpus(t testing.TB) []compattest.Version[[]compatCase] {
+	t.Helper()
+	return compattest.Load[[]compatCase](t, filepath.Join("testdata", "compat", "properties"))
+}
+
+// previousMinorFromMapProperties is a frozen copy of the decoding rules of FromMapProperties in v0.11.
+// Update it when a new minor version is released.
+func previousMinorFromMapProperties(properties map[string]string) (stream.MessageProperties, error) {
+	receivedAt, err := time.Parse(time.RFC3339Nano, properties["receivedAt"])
+	if err != nil {
+		return stream.MessageProperties{}, fmt.Errorf("parsing receivedAt: %w", err)
+	}
+
+	var isBot, botIsInvalidBrowser bool
+	var botName, botURL string
+	var botAction stream.BotAction
+
+	if properties["isBot"] != "" {
+		isBot, err = strconv.ParseBool(properties["isBot"])
+		if err != nil {
+			return stream.MessageProperties{}, fmt.Errorf("parsing isBot: %w", err)
+		}
+	}
+
+	if isBot {
+		botName = properties["botName"]
+		botURL = properties["botURL"]
+		botAction = stream.BotAction(properties["botAction"])
+
+		if properties["botIsInvalidBrowser"] != "" {
+			botIsInvalidBrowser, err = strconv.ParseBool(properties["botIsInvalidBrowser"])
+			if err != nil {
+				return stream.MessageProperties{}, fmt.Errorf("parsing botIsInvalidBrowser: %w", err)
+			}
+		}
+	}
+
+	return stream.MessageProperties{
+		RequestType:          properties["requestType"],
+		RoutingKey:           properties["routingKey"],
+		WorkspaceID:          properties["workspaceID"],
+		RequestIP:            properties["requestIP"],
+		UserID:               properties["userID"],
+		SourceID:             properties["sourceID"],
+		DestinationID:        properties["destinationID"],
+		ReceivedAt:           receivedAt,
+		SourceJobRunID:       properties["sourceJobRunID"],
+		SourceTaskRunID:      properties["sourceTaskRunID"],
+		TraceID:              properties["traceID"],
+		SourceType:           properties["sourceType"],
+		WebhookFailureReason: properties["webhookFailureReason"],
+		Stage:                stream.Stage(properties["stage"]),
+		Compression:          properties["compression"],
+		Encryption:           properties["encryption"],
+		EncryptionKeyID:      properties["encryptionKeyID"],
+		IsBot:                isBot,
+		BotName:              botName,
+		BotURL:               botURL,
+		BotIsInvalidBrowser:  botIsInvalidBrowser,
+		BotAction:            botAction,
+		PartitionID:          properties["partitionID"],
+	}, nil
+}
@@ -0,0 +1,97 @@
+package stream
+
+import (
+	"bytes"
+	"errors"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// DeadLetterStage is the processing stage in which a consumer failed to handle a message.
+type DeadLetterStage string
+
+const (
+	DeadLetterStageValidation DeadLetterStage = "validation" // message failed schema validation
+	DeadLetterStageDecryption DeadLetterStage = "decryption" // payload could not be decrypted
+	DeadLetterStageDownstream DeadLetterStage = "downstream" // a downstream system rejected the message
+)
+
+const (
+	DeadLetterErrorCodeValidation = "validation_failed" // error code used by NewValidationDeadLetter
+)
+
+// DeadLetter wraps a message that a consumer could not process, together with the failure details.
+type DeadLetter struct {
+	Message       Message            `json:"message" validate:"-"`              // original message as received, not validated since it may be the cause of the failure
+	Stage         DeadLetterStage    `json:"stage" validate:"required"`         // stage in which processing failed
+	ErrorCode     string             `json:"errorCode" validate:"required"`     // machine readable error code
+	ErrorMessage  string             `json:"errorMessage"`                      // human readable error message of the last failure
+	InvalidFields []string           `json:"invalidFields,omitempty"`           // fields that failed validation, if any
+	Attempts      int                `json:"attempts" validate:"min=1"`         // number of processing attempts that failed
+	FirstFailedAt time.Time          `json:"firstFailedAt" validate:"required"` // time of the first failure
+	LastFailedAt  time.Time          `json:"lastFailedAt" validate:"required"`  // time of the last failure
+	Consumer      DeadLetterConsumer `json:"consumer" validate:"required"`      // identity of the consumer that failed
+}
+
+// DeadLetterConsumer identifies the service instance that dead-lettered a message.
+type DeadLetterConsumer struct {
+	Service  string `json:"service" validate:"required"` // name of the consuming service
+	Instance string `json:"instance,omitempty"`          // optional instance or pod name
+}
+
+// NewDeadLetter creates a dead-letter envelope for msg after its first failed attempt.
+func NewDeadLetter(msg Message, stage DeadLetterStage, errorCode string, err error, consumer DeadLetterConsumer, failedAt time.Time) *DeadLetter {
+	dl := &DeadLetter{
+		Message:       msg,
+		Stage:         stage,
+		ErrorCode:     errorCode,
+		Attempts:      1,
+		FirstFailedAt: failedAt,
+		LastFailedAt:  failedAt,
+		Consumer:      consumer,
+	}
+	if err != nil {
+		dl.ErrorMessage = err.Error()
+	}
+	return dl
+}
+
+// NewValidationDeadLetter creates a dead-letter envelope for msg from an error returned by
+// NewMessageValidator or NewMessagePropertiesValidator, recording the fields that failed validation.
+func NewValidationDeadLetter(msg Message, err error, consumer DeadLetterConsumer, failedAt time.Time) *DeadLetter {
+	dl := NewDeadLetter(msg, DeadLetterStageValidation, DeadLetterErrorCodeValidation, err, consumer, failedAt)
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		for _, fe := range validationErrors {
+			dl.InvalidFields = append(dl.InvalidFields, fe.Namespace())
+		}
+	}
+	return dl
+}
+
+// RecordFailure records another failed processing attempt of the dead-lettered message.
+func (dl *DeadLetter) RecordFailure(errorCode string, err error, failedAt time.Time) {
+	dl.Attempts++
+	dl.ErrorCode = errorCode
+	dl.ErrorMessage = ""
+	if err != nil {
+		dl.ErrorMessage = err.Error()
+	}
+	dl.LastFailedAt = failedAt
+}
+
+// Unwrap returns a copy of the original message, so that it can be reprocessed.
+func (dl *DeadLetter) Unwrap() Message {
+	return Message{
+		Properties: dl.Message.Properties,
+		Payload:    bytes.Clone(dl.Message.Payload),
+	}
+}
+
+func NewDeadLetterValidator() func(dl *DeadLetter) error {
+	validate := newValidator()
+	return func(dl *DeadLetter) error {
+		return validate.Struct(dl)
+	}
+}
@@ -0,0 +1,111 @@
+package stream_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-schemas/go/stream"
+)
+
+func TestDeadLetter(t *testing.T) {
+	msg := stream.Message{
+		Properties: stream.MessageProperties{
+			RequestType: "requestType",
+			RoutingKey:  "routingKey",
+			WorkspaceID: "",
+			SourceID:    "sourceID",
+			ReceivedAt:  time.Date(2024, 8, 1, 0o2, 30, 50, 200, time.UTC),
+			RequestIP:   "",
+		},
+		Payload: json.RawMessage(`{"key":"value"}`),
+	}
+	consumer := stream.DeadLetterConsumer{Service: "processor", Instance: "processor-0"}
+	firstFailedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("from validation error", func(t *testing.T) {
+		err := stream.NewMessageValidator()(&msg)
+		require.Error(t, err)
+
+		dl := stream.NewValidationDeadLetter(msg, err, consumer, firstFailedAt)
+		require.Equal(t, &stream.DeadLetter{
+			Message:       msg,
+			Stage:         stream.DeadLetterStageValidation,
+			ErrorCode:     stream.DeadLetterErrorCodeValidation,
+			ErrorMessage:  err.Error(),
+			InvalidFields: []string{"Message.Properties.WorkspaceID", "Message.Properties.RequestIP"},
+			Attempts:      1,
+			FirstFailedAt: firstFailedAt,
+			LastFailedAt:  firstFailedAt,
+			Consumer:      consumer,
+		}, dl)
+		require.NoError(t, stream.NewDeadLetterValidator()(dl))
+	})
+
+	t.Run("from unknown enum value", func(t *testing.T) {
+		unknown := msg
+		unknown.Properties.WorkspaceID, unknown.Properties.RequestIP = "workspaceID", "10.29.13.20"
+		unknown.Properties.Stage = "unknown"
+		err := stream.NewMessageValidator()(&unknown)
+		require.ErrorContains(t, err, "failed on the 'enum' tag")
+
+		dl := stream.NewValidationDeadLetter(unknown, err, consumer, firstFailedAt)
+		require.Equal(t, []string{"Message.Properties.Stage"}, dl.InvalidFields)
+		data, err := json.Marshal(dl)
+		require.NoError(t, err, "messages with unknown values can be dead-lettered")
+
+		var unmarshaled stream.DeadLetter
+		require.NoError(t, json.Unmarshal(data, &unmarshaled))
+		require.Equal(t, dl, &unmarshaled)
+	})
+
+	t.Run("from non validation error", func(t *testing.T) {
+		dl := stream.NewValidationDeadLetter(msg, errors.New("encryption key ID is required when encryption is set"), consumer, firstFailedAt)
+		require.Empty(t, dl.InvalidFields)
+		require.Equal(t, "encryption key ID is required when encryption is set", dl.ErrorMessage)
+	})
+
+	t.Run("record failure", func(t *testing.T) {
+		dl := stream.NewDeadLetter(msg, stream.DeadLetterStageDownstream, "http_500", errors.New("internal server error"), consumer, firstFailedAt)
+		lastFailedAt := firstFailedAt.Add(time.Minute)
+		dl.RecordFailure("http_429", errors.New("too many requests"), lastFailedAt)
+
+		require.Equal(t, 2, dl.Attempts)
+		require.Equal(t, "http_429", dl.ErrorCode)
+		require.Equal(t, "too many requests", dl.ErrorMessage)
+		require.Equal(t, firstFailedAt, dl.FirstFailedAt)
+		require.Equal(t, lastFailedAt, dl.LastFailedAt)
+	})
+
+	t.Run("marshal unmarshal", func(t *testing.T) {
+		dl := stream.NewDeadLetter(msg, stream.DeadLetterStageDecryption, "decrypt_failed", errors.New("bad key"), consumer, firstFailedAt)
+		data, err := json.Marshal(dl)
+		require.NoError(t, err)
+
+		var unmarshaled stream.DeadLetter
+		require.NoError(t, json.Unmarshal(data, &unmarshaled))
+		require.Equal(t, dl, &unmarshaled)
+	})
+
+	t.Run("unwrap", func(t *testing.T) {
+		dl := stream.NewDeadLetter(msg, stream.DeadLetterStageDownstream, "rejected", nil, consumer, firstFailedAt)
+		unwrapped := dl.Unwrap()
+		require.Equal(t, msg, unwrapped)
+
+		// Verify that modifying the unwrapped message doesn't affect the envelope
+		unwrapped.Payload[0] = '['
+		require.Equal(t, json.RawMessage(`{"key":"value"}`), dl.Message.Payload)
+	})
+
+	t.Run("validation Err", func(t *testing.T) {
+		dl := stream.NewDeadLetter(msg, "", "", nil, stream.DeadLetterConsumer{Instance: "processor-0"}, firstFailedAt)
+		dl.Attempts = 0
+		err := stream.NewDeadLetterValidator()(dl)
+		require.ErrorContains(t, err, "Key: 'DeadLetter.Stage' Error:Field validation for 'Stage' failed on the 'required' tag")
+		require.ErrorContains(t, err, "Key: 'DeadLetter.Attempts' Error:Field validation for 'Attempts' failed on the 'min' tag")
+		require.ErrorContains(t, err, "Key: 'DeadLetter.Consumer.Service' Error:Field validation for 'Service' failed on the 'required' tag")
+	})
+}
@@ -0,0 +1,185 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ErrInvalidEnumValue is returned when decoding serialized settings with an algorithm or scheme which is not part of its enum.
+var ErrInvalidEnumValue = errors.New("invalid enum value")
+
+// Stage is the pipeline stage that produced a message.
+type Stage string
+
+// Each stage has its own group of properties, which are only present for messages of that stage.
+const (
+	StageWebhook        Stage = "webhook"        // message produced by the webhook source handler, see SourceType and WebhookFailureReason
+	StageRetry          Stage = "retry"          // message redelivered after a failure, see RetryAttempt and RetryLastError
+	StageReplay         Stage = "replay"         // message replayed from an archive, see ReplayJobID and ReplayOriginalReceivedAt
+	StageTransformation Stage = "transformation" // message produced by a transformer, see TransformerVersion
+)
+
+// IsValid returns true if the stage is a known one.
+func (s Stage) IsValid() bool {
+	switch s {
+	case StageWebhook, StageRetry, StageReplay, StageTransformation:
+		return true
+	default:
+		return false
+	}
+}
+
+// MarshalText passes any value through, as do all the enums of this package, so that messages produced by newer
+// versions can still be encoded, decoded and dead-lettered. Unknown values are only rejected by the enum validation.
+func (s Stage) MarshalText() ([]byte, error) { return []byte(s), nil }
+
+func (s *Stage) UnmarshalText(text []byte) error {
+	*s = Stage(text)
+	return nil
+}
+
+// BotAction is the action to take for messages sent by bots.
+type BotAction string
+
+const (
+	BotActionFlag    BotAction = "flag"    // enrich the message with bot details
+	BotActionDisable BotAction = "disable" // drop the message, only reporting metrics
+)
+
+// IsValid returns true if the bot action is a known one.
+func (a BotAction) IsValid() bool {
+	switch a {
+	case BotActionFlag, BotActionDisable:
+		return true
+	default:
+		return false
+	}
+}
+
+func (a BotAction) MarshalText() ([]byte, error) { return []byte(a), nil }
+
+func (a *BotAction) UnmarshalText(text []byte) error {
+	*a = BotAction(text)
+	return nil
+}
+
+// CompressionAlgorithm is the algorithm used for compressing the payload of a message,
+// as part of the serialized compression settings of MessageProperties.Compression.
+type CompressionAlgorithm string
+
+const (
+	CompressionAlgorithmZstd    CompressionAlgorithm = "zstd"     // pure go zstd
+	CompressionAlgorithmZstdCgo CompressionAlgorithm = "zstd-cgo" // cgo zstd
+)
+
+// compressionAlgorithms maps the algorithm identifiers used in serialized compression settings,
+// see rudder-go-kit's compress.SerializeSettings, to compression algorithms.
+var compressionAlgorithms = map[string]CompressionAlgorithm{
+	"1": CompressionAlgorithmZstd,
+	"2": CompressionAlgorithmZstdCgo,
+}
+
+// IsValid returns true if the compression algorithm is a known one.
+func (c CompressionAlgorithm) IsValid() bool {
+	switch c {
+	case CompressionAlgorithmZstd, CompressionAlgorithmZstdCgo:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c CompressionAlgorithm) MarshalText() ([]byte, error) { return []byte(c), nil }
+
+func (c *CompressionAlgorithm) UnmarshalText(text []byte) error {
+	*c = CompressionAlgorithm(text)
+	return nil
+}
+
+// EncryptionScheme is the scheme used for encrypting the payload of a message,
+// as part of the serialized encryption settings of MessageProperties.Encryption.
+type EncryptionScheme string
+
+const (
+	EncryptionSchemeAESGCM EncryptionScheme = "aes-gcm" // AES in Galois/Counter Mode
+)
+
+// IsValid returns true if the encryption scheme is a known one.
+func (e EncryptionScheme) IsValid() bool {
+	switch e {
+	case EncryptionSchemeAESGCM:
+		return true
+	default:
+		return false
+	}
+}
+
+func (e EncryptionScheme) MarshalText() ([]byte, error) { return []byte(e), nil }
+
+func (e *EncryptionScheme) UnmarshalText(text []byte) error {
+	*e = EncryptionScheme(text)
+	return nil
+}
+
+// CompressionAlgorithm returns the algorithm of the serialized compression settings, or an empty one if not compressed.
+// Settings are serialized as "<algorithm>:<level>", see rudder-go-kit's compress.SerializeSettings.
+func (m MessageProperties) CompressionAlgorithm() (CompressionAlgorithm, error) {
+	if m.Compression == "" {
+		return "", nil
+	}
+	algorithm, level, ok := strings.Cut(m.Compression, ":")
+	if !ok || level == "" {
+		return "", fmt.Errorf("invalid compression settings: %q", m.Compression)
+	}
+	c, ok := compressionAlgorithms[algorithm]
+	if !ok {
+		return "", fmt.Errorf("%w for compression algorithm: %q", ErrInvalidEnumValue, algorithm)
+	}
+	return c, nil
+}
+
+// EncryptionScheme returns the scheme of the serialized encryption settings, or an empty one if not encrypted.
+// Settings are serialized as "<scheme>:<level>", see rudder-go-kit's encrypt.SerializeSettings.
+func (m MessageProperties) EncryptionScheme() (EncryptionScheme, error) {
+	if m.Encryption == "" {
+		return "", nil
+	}
+	scheme, level, ok := strings.Cut(m.Encryption, ":")
+	if !ok || level == "" {
+		return "", fmt.Errorf("invalid encryption settings: %q", m.Encryption)
+	}
+	if e := EncryptionScheme(scheme); !e.IsValid() {
+		return "", fmt.Errorf("%w for encryption scheme: %q", ErrInvalidEnumValue, scheme)
+	}
+	return EncryptionScheme(scheme), nil
+}
+
+// newValidator returns a validator supporting the custom tags of the message types:
+//   - enum: the value of an enum type must be valid
+//   - compression: the serialized compression settings must use a known algorithm
+//   - encryption: the serialized encryption settings must use a known scheme
+func newValidator() *validator.Validate {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	mustRegisterValidation(validate, "enum", func(fl validator.FieldLevel) bool {
+		e, ok := fl.Field().Interface().(interface{ IsValid() bool })
+		return ok && e.IsValid()
+	})
+	mustRegisterValidation(validate, "compression", func(fl validator.FieldLevel) bool {
+		_, err := MessageProperties{Compression: fl.Field().String()}.CompressionAlgorithm()
+		return err == nil
+	})
+	mustRegisterValidation(validate, "encryption", func(fl validator.FieldLevel) bool {
+		_, err := MessageProperties{Encryption: fl.Field().String()}.EncryptionScheme()
+		return err == nil
+	})
+	return validate
+}
+
+func mustRegisterValidation(validate *validator.Validate, tag string, fn validator.Func) {
+	if err := validate.RegisterValidation(tag, fn); err != nil {
+		panic(fmt.Errorf("registering %s validation: %w", tag, err))
+	}
+}
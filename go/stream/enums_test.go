@@ -0,0 +1,175 @@
+package stream_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-schemas/go/stream"
+)
+
+func TestEnums(t *testing.T) {
+	t.Run("IsValid", func(t *testing.T) {
+		require.True(t, stream.StageWebhook.IsValid())
+		require.False(t, stream.Stage("").IsValid())
+		require.False(t, stream.Stage("unknown").IsValid())
+
+		require.True(t, stream.BotActionFlag.IsValid())
+		require.True(t, stream.BotActionDisable.IsValid())
+		require.False(t, stream.BotAction("Flag").IsValid())
+
+		require.True(t, stream.CompressionAlgorithmZstd.IsValid())
+		require.True(t, stream.CompressionAlgorithmZstdCgo.IsValid())
+		require.False(t, stream.CompressionAlgorithm("gzip").IsValid())
+
+		require.True(t, stream.EncryptionSchemeAESGCM.IsValid())
+		require.False(t, stream.EncryptionScheme("aes-cbc").IsValid())
+	})
+
+	t.Run("text marshalling", func(t *testing.T) {
+		type enums struct {
+			Stage       stream.Stage                `json:"stage"`
+			BotAction   stream.BotAction            `json:"botAction"`
+			Compression stream.CompressionAlgorithm `json:"compression"`
+			Encryption  stream.EncryptionScheme     `json:"encryption"`
+		}
+		in := enums{
+			Stage:       stream.StageWebhook,
+			BotAction:   stream.BotActionDisable,
+			Compression: stream.CompressionAlgorithmZstdCgo,
+			Encryption:  stream.EncryptionSchemeAESGCM,
+		}
+		data, err := json.Marshal(in)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"stage":"webhook","botAction":"disable","compression":"zstd-cgo","encryption":"aes-gcm"}`, string(data))
+
+		var out enums
+		require.NoError(t, json.Unmarshal(data, &out))
+		require.Equal(t, in, out)
+
+		t.Run("empty values", func(t *testing.T) {
+			data, err := json.Marshal(enums{})
+			require.NoError(t, err)
+			var out enums
+			require.NoError(t, json.Unmarshal(data, &out))
+			require.Equal(t, enums{}, out)
+		})
+
+		t.Run("unknown values pass through", func(t *testing.T) {
+			in := enums{Stage: "unknown", BotAction: "block", Compression: "gzip", Encryption: "aes-cbc"}
+			data, err := json.Marshal(in)
+			require.NoError(t, err)
+			require.JSONEq(t, `{"stage":"unknown","botAction":"block","compression":"gzip","encryption":"aes-cbc"}`, string(data))
+
+			var out enums
+			require.NoError(t, json.Unmarshal(data, &out))
+			require.Equal(t, in, out, "messages of newer versions can be decoded, only the validator rejects them")
+		})
+	})
+
+	t.Run("CompressionAlgorithm", func(t *testing.T) {
+		for settings, expected := range map[string]stream.CompressionAlgorithm{
+			"":    "",
+			"1:1": stream.CompressionAlgorithmZstd,
+			"1:4": stream.CompressionAlgorithmZstd,
+			"2:3": stream.CompressionAlgorithmZstdCgo,
+		} {
+			algorithm, err := stream.MessageProperties{Compression: settings}.CompressionAlgorithm()
+			require.NoError(t, err, settings)
+			require.Equal(t, expected, algorithm, settings)
+		}
+
+		_, err := stream.MessageProperties{Compression: "3:1"}.CompressionAlgorithm()
+		require.ErrorIs(t, err, stream.ErrInvalidEnumValue)
+		_, err = stream.MessageProperties{Compression: "zstd"}.CompressionAlgorithm()
+		require.EqualError(t, err, `invalid compression settings: "zstd"`)
+	})
+
+	t.Run("EncryptionScheme", func(t *testing.T) {
+		scheme, err := stream.MessageProperties{Encryption: "aes-gcm:256"}.EncryptionScheme()
+		require.NoError(t, err)
+		require.Equal(t, stream.EncryptionSchemeAESGCM, scheme)
+
+		scheme, err = stream.MessageProperties{}.EncryptionScheme()
+		require.NoError(t, err)
+		require.Empty(t, scheme)
+
+		_, err = stream.MessageProperties{Encryption: "aes-cbc:256"}.EncryptionScheme()
+		require.ErrorIs(t, err, stream.ErrInvalidEnumValue)
+		_, err = stream.MessageProperties{Encryption: "aes-gcm"}.EncryptionScheme()
+		require.EqualError(t, err, `invalid encryption settings: "aes-gcm"`)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		validate := stream.NewMessagePropertiesValidator()
+		newProperties := func() stream.MessageProperties {
+			return stream.MessageProperties{
+				RequestType: "requestType",
+				RoutingKey:  "routingKey",
+				WorkspaceID: "workspaceID",
+				SourceID:    "sourceID",
+				RequestIP:   "10.29.13.20",
+				ReceivedAt:  time.Date(2024, 8, 1, 0o2, 30, 50, 200, time.UTC),
+			}
+		}
+
+		properties := newProperties()
+		properties.Stage = stream.StageWebhook
+		properties.BotAction = stream.BotActionFlag
+		properties.Compression = "1:2"
+		properties.Encryption = "aes-gcm:128"
+		require.NoError(t, validate(&properties))
+
+		t.Run("invalid values are rejected", func(t *testing.T) {
+			for _, tc := range []struct {
+				name   string
+				modify func(p *stream.MessageProperties)
+				error  string
+			}{
+				{
+					name:   "stage",
+					modify: func(p *stream.MessageProperties) { p.Stage = "unknown" },
+					error:  "Key: 'MessageProperties.Stage' Error:Field validation for 'Stage' failed on the 'enum' tag",
+				},
+				{
+					name:   "bot action",
+					modify: func(p *stream.MessageProperties) { p.BotAction = "block" },
+					error:  "Key: 'MessageProperties.BotAction' Error:Field validation for 'BotAction' failed on the 'enum' tag",
+				},
+				{
+					name:   "compression",
+					modify: func(p *stream.MessageProperties) { p.Compression = "9:1" },
+					error:  "Key: 'MessageProperties.Compression' Error:Field validation for 'Compression' failed on the 'compression' tag",
+				},
+				{
+					name:   "encryption",
+					modify: func(p *stream.MessageProperties) { p.Encryption = "rot13:1" },
+					error:  "Key: 'MessageProperties.Encryption' Error:Field validation for 'Encryption' failed on the 'encryption' tag",
+				},
+			} {
+				t.Run(tc.name, func(t *testing.T) {
+					properties := newProperties()
+					tc.modify(&properties)
+					require.EqualError(t, validate(&properties), tc.error)
+				})
+			}
+		})
+
+		t.Run("from property map", func(t *testing.T) {
+			properties, err := stream.FromMapProperties(map[string]string{
+				"requestType": "requestType",
+				"routingKey":  "routingKey",
+				"workspaceID": "workspaceID",
+				"sourceID":    "sourceID",
+				"requestIP":   "10.29.13.20",
+				"receivedAt":  time.Date(2024, 8, 1, 0o2, 30, 50, 200, time.UTC).Format(time.RFC3339Nano),
+				"stage":       "unknown",
+			})
+			require.NoError(t, err, "property maps are decoded as is")
+			require.Equal(t, stream.Stage("unknown"), properties.Stage)
+			require.Error(t, validate(&properties), "and rejected by the validator")
+		})
+	})
+}
@@ -0,0 +1,71 @@
+package stream
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// fingerprintVersion is part of the fingerprint input, so that a change of the algorithm never produces colliding keys.
+const fingerprintVersion = "v1"
+
+// Fingerprint returns a deterministic, hex encoded SHA-256 key for the message that can be used for deduplication.
+//
+// The fingerprint covers, in this order:
+//   - the workspace ID
+//   - the source ID
+//   - the messageId field of the payload, if any
+//   - receivedAt in UTC, truncated to a multiple of window (no truncation if window is not positive)
+//   - the payload in canonical JSON form: compact, with object keys sorted and numbers kept as written
+//
+// All other properties are ignored, so that the same event produces the same fingerprint regardless of
+// routing, tracing, compression or encryption settings.
+func (m *Message) Fingerprint(window time.Duration) (string, error) {
+	payload, err := canonicalJSON(m.Payload)
+	if err != nil {
+		return "", fmt.Errorf("normalizing payload: %w", err)
+	}
+
+	var messageID string
+	if fields, ok := payload.(map[string]any); ok {
+		if id, ok := fields["messageId"].(string); ok {
+			messageID = id
+		}
+	}
+
+	receivedAt := m.Properties.ReceivedAt.UTC()
+	if window > 0 {
+		receivedAt = receivedAt.Truncate(window)
+	}
+
+	data, err := json.Marshal([]any{
+		fingerprintVersion,
+		m.Properties.WorkspaceID,
+		m.Properties.SourceID,
+		messageID,
+		receivedAt.Format(time.RFC3339Nano),
+		payload,
+	})
+	if err != nil {
+		return "", fmt.Errorf("encoding fingerprint input: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// canonicalJSON decodes data into a value which encoding/json marshals with sorted object keys.
+func canonicalJSON(data json.RawMessage) (any, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	if d.More() {
+		return nil, fmt.Errorf("unexpected data after top-level value")
+	}
+	return v, nil
+}
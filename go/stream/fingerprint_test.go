@@ -0,0 +1,110 @@
+package stream_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-schemas/go/stream"
+)
+
+func TestFingerprint(t *testing.T) {
+	newMessage := func() stream.Message {
+		return stream.Message{
+			Properties: stream.MessageProperties{
+				RequestType: "requestType",
+				RoutingKey:  "routingKey",
+				WorkspaceID: "workspaceID",
+				SourceID:    "sourceID",
+				ReceivedAt:  time.Date(2024, 8, 1, 0o2, 30, 50, 200, time.UTC),
+				RequestIP:   "10.29.13.20",
+			},
+			Payload: json.RawMessage(`{"messageId":"message-1","properties":{"a":1,"b":"two"}}`),
+		}
+	}
+	fingerprint := func(t *testing.T, msg stream.Message, window time.Duration) string {
+		t.Helper()
+		fp, err := msg.Fingerprint(window)
+		require.NoError(t, err)
+		return fp
+	}
+
+	expected := fingerprint(t, newMessage(), time.Minute)
+
+	t.Run("stable", func(t *testing.T) {
+		// changing the expected value breaks every producer and consumer agreeing on idempotency keys
+		require.Equal(t, "ab231185a7fe675045b272ce4c6d19c9064f223aff860fc44bd983899658d36e", expected)
+	})
+
+	t.Run("ignores payload formatting and key order", func(t *testing.T) {
+		msg := newMessage()
+		msg.Payload = json.RawMessage(`{
+			"properties": {"b": "two", "a": 1},
+			"messageId": "message-1"
+		}`)
+		require.Equal(t, expected, fingerprint(t, msg, time.Minute))
+	})
+
+	t.Run("ignores other properties", func(t *testing.T) {
+		msg := newMessage()
+		msg.Properties.RoutingKey = "other"
+		msg.Properties.RequestIP = "10.0.0.1"
+		msg.Properties.TraceID = "traceID"
+		msg.Properties.PartitionID = "workspaceID-1"
+		msg.Properties.Compression = "some-serialized-compression-settings"
+		require.Equal(t, expected, fingerprint(t, msg, time.Minute))
+	})
+
+	t.Run("receivedAt window", func(t *testing.T) {
+		msg := newMessage()
+		msg.Properties.ReceivedAt = time.Date(2024, 8, 1, 0o2, 30, 59, 0, time.UTC)
+		require.Equal(t, expected, fingerprint(t, msg, time.Minute))
+
+		msg.Properties.ReceivedAt = time.Date(2024, 8, 1, 0o4, 30, 50, 200, time.FixedZone("UTC+2", 2*60*60))
+		require.Equal(t, expected, fingerprint(t, msg, time.Minute), "time zone should not matter")
+
+		msg.Properties.ReceivedAt = time.Date(2024, 8, 1, 0o2, 31, 0, 0, time.UTC)
+		require.NotEqual(t, expected, fingerprint(t, msg, time.Minute))
+
+		require.NotEqual(t,
+			fingerprint(t, newMessage(), 0),
+			fingerprint(t, msg, 0),
+		)
+	})
+
+	t.Run("differs by identity", func(t *testing.T) {
+		for name, modify := range map[string]func(msg *stream.Message){
+			"workspaceID": func(msg *stream.Message) { msg.Properties.WorkspaceID = "other" },
+			"sourceID":    func(msg *stream.Message) { msg.Properties.SourceID = "other" },
+			"messageId": func(msg *stream.Message) {
+				msg.Payload = json.RawMessage(`{"messageId":"message-2","properties":{"a":1,"b":"two"}}`)
+			},
+			"payload": func(msg *stream.Message) {
+				msg.Payload = json.RawMessage(`{"messageId":"message-1","properties":{"a":2,"b":"two"}}`)
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				msg := newMessage()
+				modify(&msg)
+				require.NotEqual(t, expected, fingerprint(t, msg, time.Minute))
+			})
+		}
+	})
+
+	t.Run("payload without messageId", func(t *testing.T) {
+		msg := newMessage()
+		msg.Payload = json.RawMessage(`[1,2,3]`)
+		require.NotEmpty(t, fingerprint(t, msg, time.Minute))
+	})
+
+	t.Run("invalid payload", func(t *testing.T) {
+		for _, payload := range []string{`{`, `{} {}`, ``} {
+			msg := newMessage()
+			msg.Payload = json.RawMessage(payload)
+			_, err := msg.Fingerprint(time.Minute)
+			require.ErrorContains(t, err, "normalizing payload")
+		}
+	})
+}
@@ -6,14 +6,10 @@ import (
 	"strconv"
 	"time"
 
-	"github.com/go-playground/validator/v10"
-
 	"github.com/rudderlabs/rudder-go-kit/logger"
 )
 
 const (
-	StageWebhook = "webhook"
-
 	mapKeyRequestType          = "requestType"
 	mapKeyRoutingKey           = "routingKey"
 	mapKeyWorkspaceID          = "workspaceID"
@@ -37,11 +33,22 @@ const (
 	mapKeyBotIsInvalidBrowser  = "botIsInvalidBrowser"
 	mapKeyBotAction            = "botAction"
 	mapKeyPartitionID          = "partitionID"
+	mapKeySignature            = "signature"
+	mapKeySignatureKeyID       = "signatureKeyID"
+
+	mapKeyRetryAttempt             = "retryAttempt"
+	mapKeyRetryLastError           = "retryLastError"
+	mapKeyReplayJobID              = "replayJobID"
+	mapKeyReplayOriginalReceivedAt = "replayOriginalReceivedAt"
+	mapKeyTransformerVersion       = "transformerVersion"
 )
 
 var (
-	messagePropertiesDefaultSize      = len(ToMapProperties(MessageProperties{}))
-	messagePropertiesStageWebhookSize = len(ToMapProperties(MessageProperties{Stage: StageWebhook}))
+	messagePropertiesDefaultSize             = len(ToMapProperties(MessageProperties{}))
+	messagePropertiesStageWebhookSize        = len(ToMapProperties(MessageProperties{Stage: StageWebhook}))
+	messagePropertiesStageRetrySize          = len(ToMapProperties(MessageProperties{Stage: StageRetry}))
+	messagePropertiesStageReplaySize         = len(ToMapProperties(MessageProperties{Stage: StageReplay}))
+	messagePropertiesStageTransformationSize = len(ToMapProperties(MessageProperties{Stage: StageTransformation}))
 )
 
 type Message struct {
@@ -63,9 +70,12 @@ type MessageProperties struct {
 	TraceID              string    `json:"traceID,omitempty"`              // optional
 	SourceType           string    `json:"sourceType,omitempty"`           // optional
 	WebhookFailureReason string    `json:"webhookFailureReason,omitempty"` // optional
-	Stage                string    `json:"stage,omitempty"`                // optional
-	Compression          string    `json:"compression,omitempty"`          // optional
-	Encryption           string    `json:"encryption,omitempty"`           // optional
+	// Stage is the pipeline stage that produced the message
+	Stage Stage `json:"stage,omitempty" validate:"omitempty,enum"` // optional
+	// Compression contains the serialized compression settings of the payload, see CompressionAlgorithm
+	Compression string `json:"compression,omitempty" validate:"omitempty,compression"` // optional
+	// Encryption contains the serialized encryption settings of the payload, see EncryptionScheme
+	Encryption string `json:"encryption,omitempty" validate:"omitempty,encryption"` // optional
 	// if key is rotated EncryptionKeyID should be used to refer to correct key
 	EncryptionKeyID string `json:"encryptionKeyID,omitempty"` // optional
 	IsBot           bool   `json:"isBot,omitempty"`           // optional
@@ -76,19 +86,48 @@ type MessageProperties struct {
 	// BotIsInvalidBrowser is true if event is a bot and the browser is invalid
 	BotIsInvalidBrowser bool `json:"botIsInvalidBrowser,omitempty"` // optional
 	// BotAction defines the action for bot events: "flag" enriches with bot details, "disable" only reports metrics.
-	BotAction   string `json:"botAction,omitempty"`   // optional
-	PartitionID string `json:"partitionID,omitempty"` // optional
+	BotAction   BotAction `json:"botAction,omitempty" validate:"omitempty,enum"` // optional
+	PartitionID string    `json:"partitionID,omitempty"`                         // optional
+	// Signature is the base64 encoded signature of the message, see CanonicalBytes
+	Signature string `json:"signature,omitempty"` // optional
+	// SignatureKeyID refers to the key that was used for producing Signature
+	SignatureKeyID string `json:"signatureKeyID,omitempty"` // optional
+	// RetryAttempt is the attempt number of a message redelivered by the retry stage, starting from 1
+	RetryAttempt int `json:"retryAttempt,omitempty" validate:"required_if=Stage retry,excluded_unless=Stage retry"` // optional, retry stage only
+	// RetryLastError is the error that caused the previous attempt of a message redelivered by the retry stage to fail
+	RetryLastError string `json:"retryLastError,omitempty" validate:"excluded_unless=Stage retry"` // optional, retry stage only
+	// ReplayJobID is the ID of the job that replayed the message
+	ReplayJobID string `json:"replayJobID,omitempty" validate:"required_if=Stage replay,excluded_unless=Stage replay"` // optional, replay stage only
+	// ReplayOriginalReceivedAt is the time at which a replayed message was originally received
+	ReplayOriginalReceivedAt time.Time `json:"replayOriginalReceivedAt,omitzero" validate:"required_if=Stage replay,excluded_unless=Stage replay"` // optional, replay stage only
+	// TransformerVersion is the version of the transformer that produced the message
+	TransformerVersion string `json:"transformerVersion,omitempty" validate:"required_if=Stage transformation,excluded_unless=Stage transformation"` // optional, transformation stage only
 }
 
 func (m MessageProperties) LoggerFields() []logger.Field {
 	var fields []logger.Field
 
-	if m.Stage == StageWebhook {
+	switch m.Stage {
+	case StageWebhook:
 		fields = make([]logger.Field, 0, messagePropertiesStageWebhookSize)
 		fields = append(fields, logger.NewStringField(mapKeySourceType, m.SourceType))
 		fields = append(fields, logger.NewStringField(mapKeyWebhookFailureReason, m.WebhookFailureReason))
-		fields = append(fields, logger.NewStringField(mapKeyStage, m.Stage))
-	} else {
+		fields = append(fields, logger.NewStringField(mapKeyStage, string(m.Stage)))
+	case StageRetry:
+		fields = make([]logger.Field, 0, messagePropertiesStageRetrySize)
+		fields = append(fields, logger.NewIntField(mapKeyRetryAttempt, int64(m.RetryAttempt)))
+		fields = append(fields, logger.NewStringField(mapKeyRetryLastError, m.RetryLastError))
+		fields = append(fields, logger.NewStringField(mapKeyStage, string(m.Stage)))
+	case StageReplay:
+		fields = make([]logger.Field, 0, messagePropertiesStageReplaySize)
+		fields = append(fields, logger.NewStringField(mapKeyReplayJobID, m.ReplayJobID))
+		fields = append(fields, logger.NewStringField(mapKeyReplayOriginalReceivedAt, m.ReplayOriginalReceivedAt.Format(time.RFC3339Nano)))
+		fields = append(fields, logger.NewStringField(mapKeyStage, string(m.Stage)))
+	case StageTransformation:
+		fields = make([]logger.Field, 0, messagePropertiesStageTransformationSize)
+		fields = append(fields, logger.NewStringField(mapKeyTransformerVersion, m.TransformerVersion))
+		fields = append(fields, logger.NewStringField(mapKeyStage, string(m.Stage)))
+	default:
 		fields = make([]logger.Field, 0, messagePropertiesDefaultSize)
 	}
 
@@ -111,21 +150,31 @@ func (m MessageProperties) LoggerFields() []logger.Field {
 		fields = append(fields, logger.NewStringField(mapKeyBotName, m.BotName))
 		fields = append(fields, logger.NewStringField(mapKeyBotURL, m.BotURL))
 		fields = append(fields, logger.NewBoolField(mapKeyBotIsInvalidBrowser, m.BotIsInvalidBrowser))
-		fields = append(fields, logger.NewStringField(mapKeyBotAction, m.BotAction))
+		fields = append(fields, logger.NewStringField(mapKeyBotAction, string(m.BotAction)))
 	}
 	fields = append(fields, logger.NewStringField(mapKeyPartitionID, m.PartitionID))
+	if m.Signature != "" {
+		fields = append(fields, logger.NewStringField(mapKeySignatureKeyID, m.SignatureKeyID))
+	}
 	return fields
 }
 
 // FromMapProperties converts a property map to MessageProperties.
+// Property maps of older schema versions are upgraded to the current one, newer versions are rejected.
 func FromMapProperties(properties map[string]string) (MessageProperties, error) {
+	properties, err := upgradeProperties(properties)
+	if err != nil {
+		return MessageProperties{}, err
+	}
+
 	receivedAt, err := time.Parse(time.RFC3339Nano, properties[mapKeyReceivedAt])
 	if err != nil {
 		return MessageProperties{}, fmt.Errorf("parsing receivedAt: %w", err)
 	}
 
 	var isBot, botIsInvalidBrowser bool
-	var botName, botURL, botAction string
+	var botName, botURL string
+	var botAction BotAction
 
 	if properties[mapKeyIsBot] != "" {
 		isBot, err = strconv.ParseBool(properties[mapKeyIsBot])
@@ -137,7 +186,7 @@ func FromMapProperties(properties map[string]string) (MessageProperties, error)
 	if isBot {
 		botName = properties[mapKeyBotName]
 		botURL = properties[mapKeyBotURL]
-		botAction = properties[mapKeyBotAction]
+		botAction = BotAction(properties[mapKeyBotAction])
 
 		if properties[mapKeyBotIsInvalidBrowser] != "" {
 			botIsInvalidBrowser, err = strconv.ParseBool(properties[mapKeyBotIsInvalidBrowser])
@@ -147,6 +196,38 @@ func FromMapProperties(properties map[string]string) (MessageProperties, error)
 		}
 	}
 
+	var signature, signatureKeyID string
+	if properties[mapKeySignature] != "" {
+		signature = properties[mapKeySignature]
+		signatureKeyID = properties[mapKeySignatureKeyID]
+	}
+
+	var retryAttempt int
+	var retryLastError, replayJobID, transformerVersion string
+	var replayOriginalReceivedAt time.Time
+
+	// missing stage properties are decoded as zero values, the validator enforcing the required ones
+	switch Stage(properties[mapKeyStage]) {
+	case StageRetry:
+		if properties[mapKeyRetryAttempt] != "" {
+			retryAttempt, err = strconv.Atoi(properties[mapKeyRetryAttempt])
+			if err != nil {
+				return MessageProperties{}, fmt.Errorf("parsing retryAttempt: %w", err)
+			}
+		}
+		retryLastError = properties[mapKeyRetryLastError]
+	case StageReplay:
+		replayJobID = properties[mapKeyReplayJobID]
+		if properties[mapKeyReplayOriginalReceivedAt] != "" {
+			replayOriginalReceivedAt, err = time.Parse(time.RFC3339Nano, properties[mapKeyReplayOriginalReceivedAt])
+			if err != nil {
+				return MessageProperties{}, fmt.Errorf("parsing replayOriginalReceivedAt: %w", err)
+			}
+		}
+	case StageTransformation:
+		transformerVersion = properties[mapKeyTransformerVersion]
+	}
+
 	return MessageProperties{
 		RequestType:          properties[mapKeyRequestType],
 		RoutingKey:           properties[mapKeyRoutingKey],
@@ -161,7 +242,7 @@ func FromMapProperties(properties map[string]string) (MessageProperties, error)
 		TraceID:              properties[mapKeyTraceID],
 		SourceType:           properties[mapKeySourceType],
 		WebhookFailureReason: properties[mapKeyWebhookFailureReason],
-		Stage:                properties[mapKeyStage],
+		Stage:                Stage(properties[mapKeyStage]),
 		Compression:          properties[mapKeyCompression],
 		Encryption:           properties[mapKeyEncryption],
 		EncryptionKeyID:      properties[mapKeyEncryptionKeyID],
@@ -171,6 +252,14 @@ func FromMapProperties(properties map[string]string) (MessageProperties, error)
 		BotIsInvalidBrowser:  botIsInvalidBrowser,
 		BotAction:            botAction,
 		PartitionID:          properties[mapKeyPartitionID],
+		Signature:            signature,
+		SignatureKeyID:       signatureKeyID,
+
+		RetryAttempt:             retryAttempt,
+		RetryLastError:           retryLastError,
+		ReplayJobID:              replayJobID,
+		ReplayOriginalReceivedAt: replayOriginalReceivedAt,
+		TransformerVersion:       transformerVersion,
 	}, nil
 }
 
@@ -192,31 +281,48 @@ func ToMapProperties(properties MessageProperties) map[string]string {
 		mapKeyEncryption:      properties.Encryption,
 		mapKeyEncryptionKeyID: properties.EncryptionKeyID,
 		mapKeyPartitionID:     properties.PartitionID,
+		mapKeySchemaVersion:   strconv.Itoa(MessagePropertiesSchemaVersion),
 	}
-	if properties.Stage == StageWebhook {
+	switch properties.Stage {
+	case StageWebhook:
 		m[mapKeySourceType] = properties.SourceType
 		m[mapKeyWebhookFailureReason] = properties.WebhookFailureReason
-		m[mapKeyStage] = properties.Stage
+		m[mapKeyStage] = string(properties.Stage)
+	case StageRetry:
+		m[mapKeyRetryAttempt] = strconv.Itoa(properties.RetryAttempt)
+		m[mapKeyRetryLastError] = properties.RetryLastError
+		m[mapKeyStage] = string(properties.Stage)
+	case StageReplay:
+		m[mapKeyReplayJobID] = properties.ReplayJobID
+		m[mapKeyReplayOriginalReceivedAt] = properties.ReplayOriginalReceivedAt.Format(time.RFC3339Nano)
+		m[mapKeyStage] = string(properties.Stage)
+	case StageTransformation:
+		m[mapKeyTransformerVersion] = properties.TransformerVersion
+		m[mapKeyStage] = string(properties.Stage)
 	}
 	if properties.IsBot {
 		m[mapKeyIsBot] = "true"
 		m[mapKeyBotName] = properties.BotName
 		m[mapKeyBotURL] = properties.BotURL
 		m[mapKeyBotIsInvalidBrowser] = strconv.FormatBool(properties.BotIsInvalidBrowser)
-		m[mapKeyBotAction] = properties.BotAction
+		m[mapKeyBotAction] = string(properties.BotAction)
+	}
+	if properties.Signature != "" {
+		m[mapKeySignature] = properties.Signature
+		m[mapKeySignatureKeyID] = properties.SignatureKeyID
 	}
 	return m
 }
 
 func NewMessageValidator() func(msg *Message) error {
-	validate := validator.New(validator.WithRequiredStructEnabled())
+	validate := newValidator()
 	return func(msg *Message) error {
 		return validate.Struct(msg)
 	}
 }
 
 func NewMessagePropertiesValidator(opt ...func(properties *MessageProperties) error) func(properties *MessageProperties) error {
-	validate := validator.New(validator.WithRequiredStructEnabled())
+	validate := newValidator()
 	return func(properties *MessageProperties) error {
 		for _, o := range opt {
 			if err := o(properties); err != nil {
@@ -2,6 +2,8 @@ package stream_test
 
 import (
 	"encoding/json"
+	"maps"
+	"slices"
 	"testing"
 	"time"
 
@@ -29,6 +31,7 @@ func TestMessage(t *testing.T) {
 			"encryption":      "some-serialized-encryption-settings",
 			"encryptionKeyID": "encryptionKeyID",
 			"partitionID":     "",
+			"schemaVersion":   "2",
 		}
 
 		msg, err := stream.FromMapProperties(input)
@@ -79,11 +82,12 @@ func TestMessage(t *testing.T) {
 			"traceID":              "traceID",
 			"sourceType":           "sourceType",
 			"webhookFailureReason": "webhookFailureReason",
-			"stage":                stream.StageWebhook,
+			"stage":                string(stream.StageWebhook),
 			"compression":          "some-serialized-compression-settings",
 			"encryption":           "some-serialized-encryption-settings",
 			"encryptionKeyID":      "encryptionKeyID",
 			"partitionID":          "workspaceID-0",
+			"schemaVersion":        "2",
 		}
 
 		msg, err := stream.FromMapProperties(input)
@@ -137,6 +141,7 @@ func TestMessage(t *testing.T) {
 				"botIsInvalidBrowser": "true",
 				"botAction":           "flag",
 				"partitionID":         "",
+				"schemaVersion":       "2",
 			}
 
 			msg, err := stream.FromMapProperties(input)
@@ -477,7 +482,7 @@ func TestMessage(t *testing.T) {
 				SourceID:    "sourceID",
 				RequestIP:   "10.29.13.20",
 				ReceivedAt:  time.Date(2024, 8, 1, 0o2, 30, 50, 200, time.UTC),
-				Encryption:  "some-serialized-encryption-settings",
+				Encryption:  "aes-gcm:256",
 			},
 			Payload: json.RawMessage(`{}`),
 		}
@@ -496,7 +501,7 @@ func TestMessage(t *testing.T) {
 				SourceID:    "sourceID",
 				RequestIP:   "10.29.13.20",
 				ReceivedAt:  time.Date(2024, 8, 1, 0o2, 30, 50, 200, time.UTC),
-				Encryption:  "some-serialized-encryption-settings",
+				Encryption:  "aes-gcm:256",
 			},
 			Payload: json.RawMessage(`{}`),
 		}
@@ -516,7 +521,7 @@ func TestMessage(t *testing.T) {
 				SourceID:        "sourceID",
 				RequestIP:       "10.29.13.20",
 				ReceivedAt:      time.Date(2024, 8, 1, 0o2, 30, 50, 200, time.UTC),
-				Encryption:      "some-serialized-encryption-settings",
+				Encryption:      "aes-gcm:256",
 				EncryptionKeyID: "encryption-key-id",
 			},
 			Payload: json.RawMessage(`{}`),
@@ -663,4 +668,270 @@ func TestMessage(t *testing.T) {
 
 		require.ElementsMatch(t, expectedFields, properties.LoggerFields())
 	})
+
+	t.Run("properties to/from: pulsar with stage properties", func(t *testing.T) {
+		base := map[string]string{
+			"requestType":     "requestType",
+			"routingKey":      "routingKey",
+			"workspaceID":     "workspaceID",
+			"userID":          "",
+			"sourceID":        "sourceID",
+			"destinationID":   "",
+			"requestIP":       "10.29.13.20",
+			"receivedAt":      time.Date(2024, 8, 1, 0o2, 30, 50, 200, time.UTC).Format(time.RFC3339Nano),
+			"sourceJobRunID":  "",
+			"sourceTaskRunID": "",
+			"traceID":         "",
+			"compression":     "",
+			"encryption":      "",
+			"encryptionKeyID": "",
+			"partitionID":     "",
+			"schemaVersion":   "2",
+		}
+		baseProperties := stream.MessageProperties{
+			RequestType: "requestType",
+			RoutingKey:  "routingKey",
+			WorkspaceID: "workspaceID",
+			SourceID:    "sourceID",
+			RequestIP:   "10.29.13.20",
+			ReceivedAt:  time.Date(2024, 8, 1, 0o2, 30, 50, 200, time.UTC),
+		}
+
+		for _, tc := range []struct {
+			stage      stream.Stage
+			group      map[string]string
+			properties func(p *stream.MessageProperties)
+		}{
+			{
+				stage: stream.StageRetry,
+				group: map[string]string{"retryAttempt": "3", "retryLastError": "timeout"},
+				properties: func(p *stream.MessageProperties) {
+					p.RetryAttempt = 3
+					p.RetryLastError = "timeout"
+				},
+			},
+			{
+				stage: stream.StageReplay,
+				group: map[string]string{"replayJobID": "replay-1", "replayOriginalReceivedAt": "2024-07-01T02:30:50.0000001Z"},
+				properties: func(p *stream.MessageProperties) {
+					p.ReplayJobID = "replay-1"
+					p.ReplayOriginalReceivedAt = time.Date(2024, 7, 1, 0o2, 30, 50, 100, time.UTC)
+				},
+			},
+			{
+				stage: stream.StageTransformation,
+				group: map[string]string{"transformerVersion": "v1.2.3"},
+				properties: func(p *stream.MessageProperties) {
+					p.TransformerVersion = "v1.2.3"
+				},
+			},
+		} {
+			t.Run(string(tc.stage), func(t *testing.T) {
+				input := maps.Clone(base)
+				maps.Copy(input, tc.group)
+				input["stage"] = string(tc.stage)
+
+				expected := baseProperties
+				expected.Stage = tc.stage
+				tc.properties(&expected)
+
+				properties, err := stream.FromMapProperties(input)
+				require.NoError(t, err)
+				require.Equal(t, expected, properties)
+				require.Equal(t, input, stream.ToMapProperties(properties))
+			})
+
+			t.Run(string(tc.stage)+": group is ignored for other stages", func(t *testing.T) {
+				input := maps.Clone(base)
+				maps.Copy(input, tc.group)
+
+				properties, err := stream.FromMapProperties(input)
+				require.NoError(t, err)
+				require.Equal(t, baseProperties, properties)
+
+				withGroup := baseProperties
+				tc.properties(&withGroup)
+				require.Equal(t, base, stream.ToMapProperties(withGroup))
+			})
+		}
+
+		t.Run("invalid retryAttempt", func(t *testing.T) {
+			input := maps.Clone(base)
+			input["stage"] = string(stream.StageRetry)
+			input["retryAttempt"] = "first"
+			_, err := stream.FromMapProperties(input)
+			require.EqualError(t, err, `parsing retryAttempt: strconv.Atoi: parsing "first": invalid syntax`)
+		})
+
+		t.Run("invalid replayOriginalReceivedAt", func(t *testing.T) {
+			input := maps.Clone(base)
+			input["stage"] = string(stream.StageReplay)
+			input["replayJobID"] = "replay-1"
+			input["replayOriginalReceivedAt"] = "yesterday"
+			_, err := stream.FromMapProperties(input)
+			require.ErrorContains(t, err, "parsing replayOriginalReceivedAt")
+		})
+
+		t.Run("missing stage properties are left to the validator", func(t *testing.T) {
+			validate := stream.NewMessagePropertiesValidator()
+			for stage, field := range map[stream.Stage]string{
+				stream.StageRetry:  "RetryAttempt",
+				stream.StageReplay: "ReplayOriginalReceivedAt",
+			} {
+				input := maps.Clone(base)
+				input["stage"] = string(stage)
+				properties, err := stream.FromMapProperties(input)
+				require.NoError(t, err, stage)
+				require.ErrorContains(t, validate(&properties), "Field validation for '"+field+"' failed on the 'required_if' tag", stage)
+			}
+		})
+	})
+
+	t.Run("logger fields - stage properties", func(t *testing.T) {
+		baseProperties := stream.MessageProperties{
+			RequestType: "requestType",
+			RoutingKey:  "routingKey",
+			WorkspaceID: "workspaceID",
+			SourceID:    "sourceID",
+			ReceivedAt:  time.Date(2024, 8, 1, 2, 30, 50, 200, time.UTC),
+			RequestIP:   "10.29.13.20",
+		}
+		baseFields := []logger.Field{
+			logger.NewStringField("requestType", "requestType"),
+			logger.NewStringField("routingKey", "routingKey"),
+			logger.NewStringField("workspaceID", "workspaceID"),
+			logger.NewStringField("sourceID", "sourceID"),
+			logger.NewStringField("destinationID", ""),
+			logger.NewStringField("requestIP", "10.29.13.20"),
+			logger.NewStringField("receivedAt", "2024-08-01T02:30:50.0000002Z"),
+			logger.NewStringField("userID", ""),
+			logger.NewStringField("sourceJobRunID", ""),
+			logger.NewStringField("sourceTaskRunID", ""),
+			logger.NewStringField("traceID", ""),
+			logger.NewStringField("compression", ""),
+			logger.NewStringField("encryption", ""),
+			logger.NewStringField("encryptionKeyID", ""),
+			logger.NewBoolField("isBot", false),
+			logger.NewStringField("partitionID", ""),
+		}
+
+		retry := baseProperties
+		retry.Stage = stream.StageRetry
+		retry.RetryAttempt = 3
+		retry.RetryLastError = "timeout"
+		require.ElementsMatch(t, append(slices.Clone(baseFields),
+			logger.NewIntField("retryAttempt", 3),
+			logger.NewStringField("retryLastError", "timeout"),
+			logger.NewStringField("stage", "retry"),
+		), retry.LoggerFields())
+
+		replay := baseProperties
+		replay.Stage = stream.StageReplay
+		replay.ReplayJobID = "replay-1"
+		replay.ReplayOriginalReceivedAt = time.Date(2024, 7, 1, 2, 30, 50, 100, time.UTC)
+		require.ElementsMatch(t, append(slices.Clone(baseFields),
+			logger.NewStringField("replayJobID", "replay-1"),
+			logger.NewStringField("replayOriginalReceivedAt", "2024-07-01T02:30:50.0000001Z"),
+			logger.NewStringField("stage", "replay"),
+		), replay.LoggerFields())
+
+		transformation := baseProperties
+		transformation.Stage = stream.StageTransformation
+		transformation.TransformerVersion = "v1.2.3"
+		require.ElementsMatch(t, append(slices.Clone(baseFields),
+			logger.NewStringField("transformerVersion", "v1.2.3"),
+			logger.NewStringField("stage", "transformation"),
+		), transformation.LoggerFields())
+	})
+
+	t.Run("validation: stage properties", func(t *testing.T) {
+		validator := stream.NewMessagePropertiesValidator()
+		newProperties := func(stage stream.Stage) stream.MessageProperties {
+			return stream.MessageProperties{
+				RequestType: "requestType",
+				RoutingKey:  "routingKey",
+				WorkspaceID: "workspaceID",
+				SourceID:    "sourceID",
+				ReceivedAt:  time.Date(2024, 8, 1, 2, 30, 50, 200, time.UTC),
+				RequestIP:   "10.29.13.20",
+				Stage:       stage,
+			}
+		}
+
+		t.Run("group present for its stage", func(t *testing.T) {
+			retry := newProperties(stream.StageRetry)
+			retry.RetryAttempt = 1
+			require.NoError(t, validator(&retry))
+
+			replay := newProperties(stream.StageReplay)
+			replay.ReplayJobID = "replay-1"
+			replay.ReplayOriginalReceivedAt = time.Date(2024, 7, 1, 2, 30, 50, 100, time.UTC)
+			require.NoError(t, validator(&replay))
+
+			transformation := newProperties(stream.StageTransformation)
+			transformation.TransformerVersion = "v1.2.3"
+			require.NoError(t, validator(&transformation))
+		})
+
+		t.Run("group missing for its stage", func(t *testing.T) {
+			retry := newProperties(stream.StageRetry)
+			require.EqualError(t, validator(&retry), "Key: 'MessageProperties.RetryAttempt' Error:Field validation for 'RetryAttempt' failed on the 'required_if' tag")
+
+			replay := newProperties(stream.StageReplay)
+			replay.ReplayJobID = "replay-1"
+			require.EqualError(t, validator(&replay), "Key: 'MessageProperties.ReplayOriginalReceivedAt' Error:Field validation for 'ReplayOriginalReceivedAt' failed on the 'required_if' tag")
+
+			transformation := newProperties(stream.StageTransformation)
+			require.EqualError(t, validator(&transformation), "Key: 'MessageProperties.TransformerVersion' Error:Field validation for 'TransformerVersion' failed on the 'required_if' tag")
+		})
+
+		t.Run("group present for another stage", func(t *testing.T) {
+			webhook := newProperties(stream.StageWebhook)
+			webhook.RetryLastError = "timeout"
+			require.EqualError(t, validator(&webhook), "Key: 'MessageProperties.RetryLastError' Error:Field validation for 'RetryLastError' failed on the 'excluded_unless' tag")
+
+			retry := newProperties(stream.StageRetry)
+			retry.RetryAttempt = 1
+			retry.ReplayOriginalReceivedAt = time.Date(2024, 7, 1, 2, 30, 50, 100, time.UTC)
+			require.EqualError(t, validator(&retry), "Key: 'MessageProperties.ReplayOriginalReceivedAt' Error:Field validation for 'ReplayOriginalReceivedAt' failed on the 'excluded_unless' tag")
+
+			noStage := newProperties("")
+			noStage.TransformerVersion = "v1.2.3"
+			require.EqualError(t, validator(&noStage), "Key: 'MessageProperties.TransformerVersion' Error:Field validation for 'TransformerVersion' failed on the 'excluded_unless' tag")
+		})
+	})
+}
+
+// FuzzFromMapProperties feeds property maps, encoded as JSON objects, to FromMapProperties.
+// Decoding must never panic and, once normalized by a first round trip, property maps must be stable.
+func FuzzFromMapProperties(f *testing.F) {
+	for _, c := range loadCompatCorpus(f) {
+		for _, cc := range c.Content {
+			data, err := json.Marshal(cc.Properties)
+			require.NoError(f, err)
+			f.Add(data)
+		}
+	}
+	f.Add([]byte(`{}`))
+	f.Add([]byte(`{"receivedAt":"2024-08-01T02:30:50.0000002+02:00","isBot":"1","botIsInvalidBrowser":"T"}`))
+	f.Add([]byte(`{"receivedAt":"2024-08-01T02:30:50Z","stage":"retry","retryAttempt":"-0"}`))
+	f.Add([]byte(`{"receivedAt":"2024-08-01T02:30:50Z","schemaVersion":"3"}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var input map[string]string
+		if err := json.Unmarshal(data, &input); err != nil {
+			t.Skip("not a property map")
+		}
+		properties, err := stream.FromMapProperties(input)
+		if err != nil {
+			return
+		}
+		_ = properties.LoggerFields()
+		_ = stream.NewMessagePropertiesValidator(stream.WithEncryptionPropertiesValidator())(&properties)
+
+		normalized := stream.ToMapProperties(properties)
+		decoded, err := stream.FromMapProperties(normalized)
+		require.NoError(t, err, "normalized property maps must decode")
+		require.Equal(t, normalized, stream.ToMapProperties(decoded), "normalized property maps must be stable")
+	})
 }
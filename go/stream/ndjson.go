@@ -0,0 +1,241 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// FileCompression is the compression applied to a whole NDJSON stream of messages.
+type FileCompression string
+
+const (
+	FileCompressionNone FileCompression = ""     // plain NDJSON
+	FileCompressionGzip FileCompression = "gzip" // gzip compressed NDJSON
+	FileCompressionZstd FileCompression = "zstd" // zstd compressed NDJSON
+)
+
+// fileCompressionMagics are the magic numbers starting the streams of every file compression.
+var fileCompressionMagics = map[FileCompression][]byte{
+	FileCompressionGzip: {0x1f, 0x8b},
+	FileCompressionZstd: {0x28, 0xb5, 0x2f, 0xfd},
+}
+
+// LineError is returned by Encoder and Decoder when a specific line of the stream cannot be processed.
+type LineError struct {
+	Line int   // 1-based line number in the stream
+	Err  error // underlying error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// Encoder writes messages as newline-delimited JSON.
+type Encoder struct {
+	w           io.Writer
+	closer      io.Closer
+	compression FileCompression
+	compress    func(w io.Writer) (io.WriteCloser, error)
+	validate    func(msg *Message) error
+	line        int
+}
+
+// NewEncoder returns an Encoder writing to w.
+// If a compression option is provided, Close must be called to flush the compressed stream.
+func NewEncoder(w io.Writer, opt ...func(e *Encoder)) (*Encoder, error) {
+	e := &Encoder{w: w}
+	for _, o := range opt {
+		o(e)
+	}
+
+	switch {
+	case e.compress != nil:
+	case e.compression == FileCompressionNone:
+		return e, nil
+	case e.compression == FileCompressionGzip:
+		e.compress = func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil }
+	default:
+		return nil, fmt.Errorf("unsupported file compression: %q", e.compression)
+	}
+	zw, err := e.compress(w)
+	if err != nil {
+		return nil, fmt.Errorf("creating compressor: %w", err)
+	}
+	e.w, e.closer = zw, zw
+	return e, nil
+}
+
+// WithEncoderValidator validates every message with validate before writing it.
+func WithEncoderValidator(validate func(msg *Message) error) func(e *Encoder) {
+	return func(e *Encoder) {
+		e.validate = validate
+	}
+}
+
+// WithEncoderCompression compresses the output stream using the given compression.
+// Only gzip is supported natively, other compressions require WithEncoderCompressor.
+func WithEncoderCompression(compression FileCompression) func(e *Encoder) {
+	return func(e *Encoder) {
+		e.compression = compression
+	}
+}
+
+// WithEncoderCompressor compresses the output stream with the writer returned by compress, e.g. a zstd encoder,
+// which this package does not import to keep its dependencies small. It takes precedence over WithEncoderCompression.
+func WithEncoderCompressor(compress func(w io.Writer) (io.WriteCloser, error)) func(e *Encoder) {
+	return func(e *Encoder) {
+		e.compress = compress
+	}
+}
+
+// Encode writes msg as a single line.
+func (e *Encoder) Encode(msg *Message) error {
+	line := e.line + 1
+	if e.validate != nil {
+		if err := e.validate(msg); err != nil {
+			return &LineError{Line: line, Err: err}
+		}
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return &LineError{Line: line, Err: fmt.Errorf("marshalling message: %w", err)}
+	}
+	data = append(data, '\n')
+	if _, err := e.w.Write(data); err != nil {
+		return &LineError{Line: line, Err: fmt.Errorf("writing message: %w", err)}
+	}
+	e.line = line
+	return nil
+}
+
+// Close flushes and closes the compressor, if any. It does not close the underlying writer.
+func (e *Encoder) Close() error {
+	if e.closer == nil {
+		return nil
+	}
+	return e.closer.Close()
+}
+
+// Decoder reads messages from newline-delimited JSON.
+// Compressed input is detected automatically: gzip is supported natively, other compressions require WithDecoderDecompressor.
+type Decoder struct {
+	br            *bufio.Reader
+	closer        io.Closer
+	decompressors map[FileCompression]func(r io.Reader) (io.ReadCloser, error)
+	validate      func(msg *Message) error
+	onCorrupt     func(line int, data []byte, err error)
+	line          int
+}
+
+// NewDecoder returns a Decoder reading from r.
+func NewDecoder(r io.Reader, opt ...func(d *Decoder)) (*Decoder, error) {
+	d := &Decoder{decompressors: map[FileCompression]func(r io.Reader) (io.ReadCloser, error){
+		FileCompressionGzip: func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+	}}
+	for _, o := range opt {
+		o(d)
+	}
+
+	br := bufio.NewReader(r)
+	header, err := br.Peek(len(fileCompressionMagics[FileCompressionZstd]))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	compression := FileCompressionNone
+	for c, magic := range fileCompressionMagics {
+		if bytes.HasPrefix(header, magic) {
+			compression = c
+		}
+	}
+	if compression == FileCompressionNone {
+		d.br = br
+		return d, nil
+	}
+	decompress, ok := d.decompressors[compression]
+	if !ok {
+		return nil, fmt.Errorf("unsupported file compression: %q", compression)
+	}
+	zr, err := decompress(br)
+	if err != nil {
+		return nil, fmt.Errorf("creating %s reader: %w", compression, err)
+	}
+	d.br, d.closer = bufio.NewReader(zr), zr
+	return d, nil
+}
+
+// WithDecoderDecompressor decompresses input detected as compressed with compression using the reader returned by decompress,
+// e.g. a zstd decoder, which this package does not import to keep its dependencies small.
+func WithDecoderDecompressor(compression FileCompression, decompress func(r io.Reader) (io.ReadCloser, error)) func(d *Decoder) {
+	return func(d *Decoder) {
+		d.decompressors[compression] = decompress
+	}
+}
+
+// WithDecoderValidator validates every decoded message with validate.
+func WithDecoderValidator(validate func(msg *Message) error) func(d *Decoder) {
+	return func(d *Decoder) {
+		d.validate = validate
+	}
+}
+
+// WithDecoderSkipCorrupt skips lines that cannot be decoded or fail validation instead of returning an error.
+// onCorrupt is called for every skipped line with its line number, raw content and the error.
+func WithDecoderSkipCorrupt(onCorrupt func(line int, data []byte, err error)) func(d *Decoder) {
+	return func(d *Decoder) {
+		d.onCorrupt = onCorrupt
+	}
+}
+
+// Decode reads the next message into msg. Empty lines are ignored.
+// It returns io.EOF when there are no more messages.
+func (d *Decoder) Decode(msg *Message) error {
+	for {
+		data, err := d.br.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return &LineError{Line: d.line + 1, Err: fmt.Errorf("reading line: %w", err)}
+		}
+		if len(data) == 0 && errors.Is(err, io.EOF) {
+			return io.EOF
+		}
+		d.line++
+
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 {
+			continue
+		}
+
+		var m Message
+		decodeErr := json.Unmarshal(data, &m)
+		if decodeErr != nil {
+			decodeErr = fmt.Errorf("unmarshalling message: %w", decodeErr)
+		} else if d.validate != nil {
+			decodeErr = d.validate(&m)
+		}
+		if decodeErr != nil {
+			if d.onCorrupt != nil {
+				d.onCorrupt(d.line, data, decodeErr)
+				continue
+			}
+			return &LineError{Line: d.line, Err: decodeErr}
+		}
+		*msg = m
+		return nil
+	}
+}
+
+// Close releases the decompressor, if any. It does not close the underlying reader.
+func (d *Decoder) Close() error {
+	if d.closer == nil {
+		return nil
+	}
+	return d.closer.Close()
+}
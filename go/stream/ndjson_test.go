@@ -0,0 +1,203 @@
+package stream_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-schemas/go/stream"
+)
+
+func TestNDJSON(t *testing.T) {
+	newMessage := func(workspaceID string) stream.Message {
+		return stream.Message{
+			Properties: stream.MessageProperties{
+				RequestType: "requestType",
+				RoutingKey:  "routingKey",
+				WorkspaceID: workspaceID,
+				SourceID:    "sourceID",
+				ReceivedAt:  time.Date(2024, 8, 1, 0o2, 30, 50, 200, time.UTC),
+				RequestIP:   "10.29.13.20",
+			},
+			Payload: json.RawMessage(`{"key":"value"}`),
+		}
+	}
+
+	readAll := func(t *testing.T, d *stream.Decoder) []stream.Message {
+		t.Helper()
+		var msgs []stream.Message
+		for {
+			var msg stream.Message
+			err := d.Decode(&msg)
+			if errors.Is(err, io.EOF) {
+				return msgs
+			}
+			require.NoError(t, err)
+			msgs = append(msgs, msg)
+		}
+	}
+
+	zstdCompressor := stream.WithEncoderCompressor(func(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w) })
+	zstdDecompressor := stream.WithDecoderDecompressor(stream.FileCompressionZstd, func(r io.Reader) (io.ReadCloser, error) {
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	})
+
+	for name, encoderOpt := range map[string]func(e *stream.Encoder){
+		"none": stream.WithEncoderCompression(stream.FileCompressionNone),
+		"gzip": stream.WithEncoderCompression(stream.FileCompressionGzip),
+		"zstd": zstdCompressor,
+	} {
+		t.Run("round trip: "+name, func(t *testing.T) {
+			msgs := []stream.Message{newMessage("ws-1"), newMessage("ws-2"), newMessage("ws-3")}
+
+			var buf bytes.Buffer
+			e, err := stream.NewEncoder(&buf, encoderOpt)
+			require.NoError(t, err)
+			for i := range msgs {
+				require.NoError(t, e.Encode(&msgs[i]))
+			}
+			require.NoError(t, e.Close())
+
+			d, err := stream.NewDecoder(&buf, zstdDecompressor)
+			require.NoError(t, err)
+			defer func() { require.NoError(t, d.Close()) }()
+			require.Equal(t, msgs, readAll(t, d))
+		})
+	}
+
+	t.Run("unsupported compression", func(t *testing.T) {
+		_, err := stream.NewEncoder(io.Discard, stream.WithEncoderCompression("lz4"))
+		require.EqualError(t, err, `unsupported file compression: "lz4"`)
+
+		_, err = stream.NewEncoder(io.Discard, stream.WithEncoderCompression(stream.FileCompressionZstd))
+		require.EqualError(t, err, `unsupported file compression: "zstd"`)
+
+		var buf bytes.Buffer
+		e, err := stream.NewEncoder(&buf, zstdCompressor)
+		require.NoError(t, err)
+		msg := newMessage("ws-1")
+		require.NoError(t, e.Encode(&msg))
+		require.NoError(t, e.Close())
+		_, err = stream.NewDecoder(&buf)
+		require.EqualError(t, err, `unsupported file compression: "zstd"`)
+	})
+
+	t.Run("compressor error", func(t *testing.T) {
+		_, err := stream.NewEncoder(io.Discard, stream.WithEncoderCompressor(func(io.Writer) (io.WriteCloser, error) {
+			return nil, errors.New("boom")
+		}))
+		require.EqualError(t, err, "creating compressor: boom")
+	})
+
+	t.Run("empty input", func(t *testing.T) {
+		d, err := stream.NewDecoder(bytes.NewReader(nil))
+		require.NoError(t, err)
+		var msg stream.Message
+		require.ErrorIs(t, d.Decode(&msg), io.EOF)
+	})
+
+	t.Run("encoder validation error reports line", func(t *testing.T) {
+		var buf bytes.Buffer
+		e, err := stream.NewEncoder(&buf, stream.WithEncoderValidator(stream.NewMessageValidator()))
+		require.NoError(t, err)
+
+		valid := newMessage("ws-1")
+		invalid := newMessage("")
+		require.NoError(t, e.Encode(&valid))
+		err = e.Encode(&invalid)
+		require.EqualError(t, err, "line 2: Key: 'Message.Properties.WorkspaceID' Error:Field validation for 'WorkspaceID' failed on the 'required' tag")
+		var lineErr *stream.LineError
+		require.ErrorAs(t, err, &lineErr)
+		require.Equal(t, 2, lineErr.Line)
+
+		// the invalid message is not written, so the next one takes its line
+		require.NoError(t, e.Encode(&valid))
+		require.Equal(t, 2, bytes.Count(buf.Bytes(), []byte("\n")))
+	})
+
+	t.Run("unknown enum values round trip", func(t *testing.T) {
+		var buf bytes.Buffer
+		e, err := stream.NewEncoder(&buf)
+		require.NoError(t, err)
+		msg := newMessage("ws-1")
+		msg.Properties.Stage = "unknown"
+		require.NoError(t, e.Encode(&msg))
+		require.NoError(t, e.Close())
+
+		d, err := stream.NewDecoder(&buf)
+		require.NoError(t, err)
+		require.Equal(t, []stream.Message{msg}, readAll(t, d))
+	})
+
+	input := func() string {
+		m1, _ := json.Marshal(newMessage("ws-1"))
+		m2, _ := json.Marshal(newMessage(""))
+		m3, _ := json.Marshal(newMessage("ws-3"))
+		return string(m1) + "\n\n" + "{not json\n" + string(m2) + "\n" + string(m3)
+	}()
+
+	t.Run("decoder error reports line", func(t *testing.T) {
+		d, err := stream.NewDecoder(bytes.NewBufferString(input))
+		require.NoError(t, err)
+
+		var msg stream.Message
+		require.NoError(t, d.Decode(&msg))
+		require.Equal(t, "ws-1", msg.Properties.WorkspaceID)
+
+		err = d.Decode(&msg)
+		var lineErr *stream.LineError
+		require.ErrorAs(t, err, &lineErr)
+		require.Equal(t, 3, lineErr.Line)
+		require.ErrorContains(t, err, "line 3: unmarshalling message")
+	})
+
+	t.Run("decoder validation", func(t *testing.T) {
+		d, err := stream.NewDecoder(bytes.NewBufferString(input), stream.WithDecoderValidator(stream.NewMessageValidator()))
+		require.NoError(t, err)
+
+		var msg stream.Message
+		require.NoError(t, d.Decode(&msg))
+		require.Error(t, d.Decode(&msg)) // line 3: not json
+
+		err = d.Decode(&msg)
+		require.EqualError(t, err, "line 4: Key: 'Message.Properties.WorkspaceID' Error:Field validation for 'WorkspaceID' failed on the 'required' tag")
+
+		require.NoError(t, d.Decode(&msg))
+		require.Equal(t, "ws-3", msg.Properties.WorkspaceID)
+		require.ErrorIs(t, d.Decode(&msg), io.EOF)
+	})
+
+	t.Run("decoder skip corrupt", func(t *testing.T) {
+		type corrupt struct {
+			line int
+			data string
+		}
+		var skipped []corrupt
+		d, err := stream.NewDecoder(bytes.NewBufferString(input),
+			stream.WithDecoderValidator(stream.NewMessageValidator()),
+			stream.WithDecoderSkipCorrupt(func(line int, data []byte, err error) {
+				require.Error(t, err)
+				skipped = append(skipped, corrupt{line: line, data: string(data)})
+			}),
+		)
+		require.NoError(t, err)
+
+		msgs := readAll(t, d)
+		require.Len(t, msgs, 2)
+		require.Equal(t, "ws-1", msgs[0].Properties.WorkspaceID)
+		require.Equal(t, "ws-3", msgs[1].Properties.WorkspaceID)
+		require.Len(t, skipped, 2)
+		require.Equal(t, corrupt{line: 3, data: "{not json"}, skipped[0])
+		require.Equal(t, 4, skipped[1].line)
+	})
+}
@@ -0,0 +1,237 @@
+// Package parquet writes and reads stream messages as parquet files, keeping its dependencies out of the stream package.
+package parquet
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	parquetgo "github.com/parquet-go/parquet-go"
+
+	"github.com/rudderlabs/rudder-schemas/go/stream"
+)
+
+// DefaultRowGroupSize is the default maximum number of messages of a parquet row group.
+const DefaultRowGroupSize = 10_000
+
+// Writer writes messages to a parquet file, with one column per stream.MessageProperties field
+// and the payload as a JSON column. Row groups are flushed as soon as they are full,
+// so that only a single row group is buffered in memory.
+type Writer struct {
+	w            *parquetgo.GenericWriter[message]
+	rowGroupSize int64
+	buf          []message
+}
+
+// NewWriter returns a Writer writing to w. Close must be called to write the file footer.
+func NewWriter(w io.Writer, opt ...func(pw *Writer)) *Writer {
+	pw := &Writer{rowGroupSize: DefaultRowGroupSize}
+	for _, o := range opt {
+		o(pw)
+	}
+	pw.w = parquetgo.NewGenericWriter[message](w,
+		parquetgo.MaxRowsPerRowGroup(pw.rowGroupSize),
+		parquetgo.Compression(&parquetgo.Zstd),
+	)
+	return pw
+}
+
+// WithWriterRowGroupSize sets the maximum number of messages of a row group.
+func WithWriterRowGroupSize(rowGroupSize int) func(pw *Writer) {
+	return func(pw *Writer) {
+		pw.rowGroupSize = int64(rowGroupSize)
+	}
+}
+
+// Write writes messages to the file.
+func (pw *Writer) Write(msgs ...stream.Message) error {
+	pw.buf = pw.buf[:0]
+	for i := range msgs {
+		pw.buf = append(pw.buf, fromMessage(&msgs[i]))
+	}
+	if _, err := pw.w.Write(pw.buf); err != nil {
+		return fmt.Errorf("writing parquet rows: %w", err)
+	}
+	return nil
+}
+
+// Close flushes the last row group and writes the file footer. It does not close the underlying writer.
+func (pw *Writer) Close() error {
+	if err := pw.w.Close(); err != nil {
+		return fmt.Errorf("closing parquet writer: %w", err)
+	}
+	return nil
+}
+
+// Reader reads messages from a parquet file written by Writer.
+type Reader struct {
+	r   *parquetgo.GenericReader[message]
+	buf []message
+}
+
+// NewReader returns a Reader reading the parquet file of the given size from r.
+func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+	f, err := parquetgo.OpenFile(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("opening parquet file: %w", err)
+	}
+	return &Reader{r: parquetgo.NewGenericReader[message](f)}, nil
+}
+
+// NumRows returns the number of messages in the file.
+func (pr *Reader) NumRows() int64 {
+	return pr.r.NumRows()
+}
+
+// Read reads up to len(msgs) messages into msgs and returns the number of messages read.
+// It returns io.EOF when there are no more messages.
+func (pr *Reader) Read(msgs []stream.Message) (int, error) {
+	if cap(pr.buf) < len(msgs) {
+		pr.buf = make([]message, len(msgs))
+	}
+	pr.buf = pr.buf[:len(msgs)]
+	n, err := pr.r.Read(pr.buf)
+	for i := range n {
+		msgs[i] = pr.buf[i].toMessage()
+	}
+	if err != nil && err != io.EOF {
+		return n, fmt.Errorf("reading parquet rows: %w", err)
+	}
+	return n, err
+}
+
+// Close releases the resources of the reader. It does not close the underlying reader.
+func (pr *Reader) Close() error {
+	return pr.r.Close()
+}
+
+// message is the row of a parquet file of messages.
+type message struct {
+	RequestType          string `parquet:"requestType,dict"`
+	RoutingKey           string `parquet:"routingKey"`
+	WorkspaceID          string `parquet:"workspaceID,dict"`
+	SourceID             string `parquet:"sourceID,dict"`
+	ReceivedAt           int64  `parquet:"receivedAt,timestamp(nanosecond)"`
+	RequestIP            string `parquet:"requestIP"`
+	DestinationID        string `parquet:"destinationID,dict"`
+	UserID               string `parquet:"userID"`
+	SourceJobRunID       string `parquet:"sourceJobRunID,dict"`
+	SourceTaskRunID      string `parquet:"sourceTaskRunID,dict"`
+	TraceID              string `parquet:"traceID"`
+	SourceType           string `parquet:"sourceType,dict"`
+	WebhookFailureReason string `parquet:"webhookFailureReason,dict"`
+	Stage                string `parquet:"stage,dict"`
+	Compression          string `parquet:"compression,dict"`
+	Encryption           string `parquet:"encryption,dict"`
+	EncryptionKeyID      string `parquet:"encryptionKeyID,dict"`
+	IsBot                bool   `parquet:"isBot"`
+	BotName              string `parquet:"botName,dict"`
+	BotURL               string `parquet:"botURL,dict"`
+	BotIsInvalidBrowser  bool   `parquet:"botIsInvalidBrowser"`
+	BotAction            string `parquet:"botAction,dict"`
+	PartitionID          string `parquet:"partitionID,dict"`
+	Signature            string `parquet:"signature"`
+	SignatureKeyID       string `parquet:"signatureKeyID,dict"`
+
+	RetryAttempt             int64  `parquet:"retryAttempt,optional"`
+	RetryLastError           string `parquet:"retryLastError,optional"`
+	ReplayJobID              string `parquet:"replayJobID,optional,dict"`
+	ReplayOriginalReceivedAt int64  `parquet:"replayOriginalReceivedAt,optional,timestamp(nanosecond)"`
+	TransformerVersion       string `parquet:"transformerVersion,optional,dict"`
+
+	Payload []byte `parquet:"payload,json"`
+}
+
+func fromMessage(msg *stream.Message) message {
+	p := msg.Properties
+	return message{
+		RequestType:          p.RequestType,
+		RoutingKey:           p.RoutingKey,
+		WorkspaceID:          p.WorkspaceID,
+		SourceID:             p.SourceID,
+		ReceivedAt:           p.ReceivedAt.UnixNano(),
+		RequestIP:            p.RequestIP,
+		DestinationID:        p.DestinationID,
+		UserID:               p.UserID,
+		SourceJobRunID:       p.SourceJobRunID,
+		SourceTaskRunID:      p.SourceTaskRunID,
+		TraceID:              p.TraceID,
+		SourceType:           p.SourceType,
+		WebhookFailureReason: p.WebhookFailureReason,
+		Stage:                string(p.Stage),
+		Compression:          p.Compression,
+		Encryption:           p.Encryption,
+		EncryptionKeyID:      p.EncryptionKeyID,
+		IsBot:                p.IsBot,
+		BotName:              p.BotName,
+		BotURL:               p.BotURL,
+		BotIsInvalidBrowser:  p.BotIsInvalidBrowser,
+		BotAction:            string(p.BotAction),
+		PartitionID:          p.PartitionID,
+		Signature:            p.Signature,
+		SignatureKeyID:       p.SignatureKeyID,
+
+		RetryAttempt:             int64(p.RetryAttempt),
+		RetryLastError:           p.RetryLastError,
+		ReplayJobID:              p.ReplayJobID,
+		ReplayOriginalReceivedAt: optionalTime(p.ReplayOriginalReceivedAt),
+		TransformerVersion:       p.TransformerVersion,
+
+		Payload: msg.Payload,
+	}
+}
+
+func (pm *message) toMessage() stream.Message {
+	return stream.Message{
+		Properties: stream.MessageProperties{
+			RequestType:          pm.RequestType,
+			RoutingKey:           pm.RoutingKey,
+			WorkspaceID:          pm.WorkspaceID,
+			SourceID:             pm.SourceID,
+			ReceivedAt:           time.Unix(0, pm.ReceivedAt).UTC(),
+			RequestIP:            pm.RequestIP,
+			DestinationID:        pm.DestinationID,
+			UserID:               pm.UserID,
+			SourceJobRunID:       pm.SourceJobRunID,
+			SourceTaskRunID:      pm.SourceTaskRunID,
+			TraceID:              pm.TraceID,
+			SourceType:           pm.SourceType,
+			WebhookFailureReason: pm.WebhookFailureReason,
+			Stage:                stream.Stage(pm.Stage),
+			Compression:          pm.Compression,
+			Encryption:           pm.Encryption,
+			EncryptionKeyID:      pm.EncryptionKeyID,
+			IsBot:                pm.IsBot,
+			BotName:              pm.BotName,
+			BotURL:               pm.BotURL,
+			BotIsInvalidBrowser:  pm.BotIsInvalidBrowser,
+			BotAction:            stream.BotAction(pm.BotAction),
+			PartitionID:          pm.PartitionID,
+			Signature:            pm.Signature,
+			SignatureKeyID:       pm.SignatureKeyID,
+
+			RetryAttempt:             int(pm.RetryAttempt),
+			RetryLastError:           pm.RetryLastError,
+			ReplayJobID:              pm.ReplayJobID,
+			ReplayOriginalReceivedAt: timeValue(pm.ReplayOriginalReceivedAt),
+			TransformerVersion:       pm.TransformerVersion,
+		},
+		Payload: pm.Payload,
+	}
+}
+
+// optionalTime returns 0 for the zero time, which is written as null in optional columns.
+func optionalTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// timeValue returns the zero time for 0, otherwise the time in UTC.
+func timeValue(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos).UTC()
+}
@@ -0,0 +1,171 @@
+package parquet_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	parquetgo "github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-schemas/go/stream"
+	"github.com/rudderlabs/rudder-schemas/go/stream/parquet"
+)
+
+func TestParquet(t *testing.T) {
+	newMessage := func(i int) stream.Message {
+		return stream.Message{
+			Properties: stream.MessageProperties{
+				RequestType:          "requestType",
+				RoutingKey:           "routingKey",
+				WorkspaceID:          "workspaceID",
+				UserID:               fmt.Sprintf("user-%d", i),
+				SourceID:             "sourceID",
+				DestinationID:        "destinationID",
+				RequestIP:            "10.29.13.20",
+				ReceivedAt:           time.Date(2024, 8, 1, 0o2, 30, 50, 200+i, time.UTC),
+				SourceJobRunID:       "sourceJobRunID",
+				SourceTaskRunID:      "sourceTaskRunID",
+				TraceID:              "traceID",
+				SourceType:           "sourceType",
+				WebhookFailureReason: "webhookFailureReason",
+				Stage:                stream.StageWebhook,
+				Compression:          "some-serialized-compression-settings",
+				Encryption:           "some-serialized-encryption-settings",
+				EncryptionKeyID:      "encryptionKeyID",
+				IsBot:                i%2 == 0,
+				BotName:              "TestBot",
+				BotURL:               "https://testbot.com",
+				BotIsInvalidBrowser:  i%3 == 0,
+				BotAction:            "flag",
+				PartitionID:          "workspaceID-0",
+				Signature:            "c2lnbmF0dXJl",
+				SignatureKeyID:       "key-1",
+			},
+			Payload: json.RawMessage(fmt.Sprintf(`{"index":%d}`, i)),
+		}
+	}
+
+	readAll := func(t *testing.T, data []byte) []stream.Message {
+		t.Helper()
+		r, err := parquet.NewReader(bytes.NewReader(data), int64(len(data)))
+		require.NoError(t, err)
+		defer func() { require.NoError(t, r.Close()) }()
+
+		var msgs []stream.Message
+		buf := make([]stream.Message, 7)
+		for {
+			n, err := r.Read(buf)
+			msgs = append(msgs, buf[:n]...)
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+		}
+		require.EqualValues(t, len(msgs), r.NumRows())
+		return msgs
+	}
+
+	t.Run("write read", func(t *testing.T) {
+		var expected []stream.Message
+		var buf bytes.Buffer
+		w := parquet.NewWriter(&buf, parquet.WithWriterRowGroupSize(10))
+		for i := range 25 {
+			msg := newMessage(i)
+			require.NoError(t, w.Write(msg))
+			expected = append(expected, msg)
+		}
+		require.NoError(t, w.Close())
+
+		require.Equal(t, expected, readAll(t, buf.Bytes()))
+
+		f, err := parquetgo.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+		require.Len(t, f.RowGroups(), 3, "row groups are flushed when full")
+	})
+
+	t.Run("stage properties", func(t *testing.T) {
+		retry := newMessage(0)
+		retry.Properties.Stage = stream.StageRetry
+		retry.Properties.RetryAttempt = 2
+		retry.Properties.RetryLastError = "timeout"
+
+		replay := newMessage(1)
+		replay.Properties.Stage = stream.StageReplay
+		replay.Properties.ReplayJobID = "replay-1"
+		replay.Properties.ReplayOriginalReceivedAt = time.Date(2024, 7, 1, 0o2, 30, 50, 100, time.UTC)
+
+		transformation := newMessage(2)
+		transformation.Properties.Stage = stream.StageTransformation
+		transformation.Properties.TransformerVersion = "v1.2.3"
+
+		var buf bytes.Buffer
+		w := parquet.NewWriter(&buf)
+		require.NoError(t, w.Write(retry, replay, transformation))
+		require.NoError(t, w.Close())
+		require.Equal(t, []stream.Message{retry, replay, transformation}, readAll(t, buf.Bytes()))
+	})
+
+	t.Run("local timestamps are read as UTC", func(t *testing.T) {
+		msg := newMessage(0)
+		msg.Properties.ReceivedAt = time.Date(2024, 8, 1, 0o4, 30, 50, 200, time.FixedZone("UTC+2", 2*60*60))
+
+		var buf bytes.Buffer
+		w := parquet.NewWriter(&buf)
+		require.NoError(t, w.Write(msg))
+		require.NoError(t, w.Close())
+
+		msgs := readAll(t, buf.Bytes())
+		require.Len(t, msgs, 1)
+		require.True(t, msg.Properties.ReceivedAt.Equal(msgs[0].Properties.ReceivedAt))
+		require.Equal(t, time.UTC, msgs[0].Properties.ReceivedAt.Location())
+	})
+
+	t.Run("empty file", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, parquet.NewWriter(&buf).Close())
+		require.Empty(t, readAll(t, buf.Bytes()))
+	})
+
+	t.Run("invalid file", func(t *testing.T) {
+		_, err := parquet.NewReader(strings.NewReader("not parquet"), int64(len("not parquet")))
+		require.ErrorContains(t, err, "opening parquet file")
+	})
+
+	t.Run("schema", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, parquet.NewWriter(&buf).Close())
+		f, err := parquetgo.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+
+		columns := map[string]parquetgo.Field{}
+		for _, field := range f.Schema().Fields() {
+			columns[field.Name()] = field
+		}
+
+		t.Run("one column per message property", func(t *testing.T) {
+			rt := reflect.TypeFor[stream.MessageProperties]()
+			for i := range rt.NumField() {
+				name, _, _ := strings.Cut(rt.Field(i).Tag.Get("json"), ",")
+				require.Contains(t, columns, name, "field %s of MessageProperties is missing from the parquet schema", name)
+			}
+			require.Contains(t, columns, "payload")
+			require.Len(t, columns, rt.NumField()+1)
+		})
+
+		t.Run("column types", func(t *testing.T) {
+			receivedAt := columns["receivedAt"].Type()
+			require.Equal(t, parquetgo.Int64Type.Kind(), receivedAt.Kind())
+			require.Equal(t, "TIMESTAMP(isAdjustedToUTC=true,unit=NANOS)", receivedAt.LogicalType().String())
+
+			require.Equal(t, parquetgo.BooleanType.Kind(), columns["isBot"].Type().Kind())
+			require.Equal(t, parquetgo.BooleanType.Kind(), columns["botIsInvalidBrowser"].Type().Kind())
+			require.Equal(t, "JSON", columns["payload"].Type().LogicalType().String())
+		})
+	})
+}
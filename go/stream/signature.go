@@ -0,0 +1,140 @@
+package stream
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrSignatureMissing    = errors.New("message is not signed")
+	ErrSignatureUnknownKey = errors.New("unknown signature key ID")
+	ErrSignatureInvalid    = errors.New("invalid message signature")
+)
+
+// CanonicalBytes returns the canonical serialization of a message that is used for signing it.
+//
+// It consists of the properties of version 1 of the canonical serialization, see canonicalPropertiesV1, encoded as
+// a JSON object with sorted keys, followed by a newline and the compacted JSON payload.
+func CanonicalBytes(msg *Message) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(canonicalPropertiesV1(msg.Properties)); err != nil { // json.Encoder terminates the object with a newline
+		return nil, fmt.Errorf("encoding properties: %w", err)
+	}
+	if err := json.Compact(&buf, msg.Payload); err != nil {
+		return nil, fmt.Errorf("compacting payload: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// canonicalKeysV1 are the keys of the property map, see ToMapProperties, signed by version 1 of the canonical
+// serialization. It leaves out the schema version and the signature, and must never change, so that signatures made
+// by earlier versions of the library can still be verified: signing other properties requires a new version of the
+// canonical serialization.
+var canonicalKeysV1 = []string{
+	mapKeyRequestType, mapKeyRoutingKey, mapKeyWorkspaceID, mapKeyUserID, mapKeySourceID, mapKeyDestinationID,
+	mapKeyRequestIP, mapKeyReceivedAt, mapKeySourceJobRunID, mapKeySourceTaskRunID, mapKeyTraceID,
+	mapKeyCompression, mapKeyEncryption, mapKeyEncryptionKeyID, mapKeyPartitionID,
+	mapKeyStage, mapKeySourceType, mapKeyWebhookFailureReason, mapKeyRetryAttempt, mapKeyRetryLastError,
+	mapKeyReplayJobID, mapKeyReplayOriginalReceivedAt, mapKeyTransformerVersion,
+	mapKeyIsBot, mapKeyBotName, mapKeyBotURL, mapKeyBotIsInvalidBrowser, mapKeyBotAction,
+}
+
+// canonicalPropertiesV1 returns the signed properties of a message: the ones of canonicalKeysV1 that ToMapProperties
+// emits, so that signatures survive a property map round trip, and the signature key ID.
+func canonicalPropertiesV1(p MessageProperties) map[string]string {
+	properties := ToMapProperties(p)
+	m := make(map[string]string, len(canonicalKeysV1)+1)
+	for _, key := range canonicalKeysV1 {
+		if v, ok := properties[key]; ok {
+			m[key] = v
+		}
+	}
+	m[mapKeySignatureKeyID] = p.SignatureKeyID
+	return m
+}
+
+// NewHMACSigner returns a function that signs messages with HMAC-SHA256, setting Signature and SignatureKeyID.
+func NewHMACSigner(keyID string, key []byte) func(msg *Message) error {
+	return newSigner(keyID, func(data []byte) []byte {
+		return hmacSHA256(key, data)
+	})
+}
+
+// NewHMACVerifier returns a function that verifies HMAC-SHA256 message signatures against the keys, indexed by key ID.
+func NewHMACVerifier(keys map[string][]byte) func(msg *Message) error {
+	return newVerifier(func(keyID string, data, signature []byte) (bool, error) {
+		key, ok := keys[keyID]
+		if !ok {
+			return false, ErrSignatureUnknownKey
+		}
+		return hmac.Equal(signature, hmacSHA256(key, data)), nil
+	})
+}
+
+// NewEd25519Signer returns a function that signs messages with Ed25519, setting Signature and SignatureKeyID.
+func NewEd25519Signer(keyID string, key ed25519.PrivateKey) func(msg *Message) error {
+	return newSigner(keyID, func(data []byte) []byte {
+		return ed25519.Sign(key, data)
+	})
+}
+
+// NewEd25519Verifier returns a function that verifies Ed25519 message signatures against the public keys, indexed by key ID.
+func NewEd25519Verifier(keys map[string]ed25519.PublicKey) func(msg *Message) error {
+	return newVerifier(func(keyID string, data, signature []byte) (bool, error) {
+		key, ok := keys[keyID]
+		if !ok {
+			return false, ErrSignatureUnknownKey
+		}
+		return ed25519.Verify(key, data, signature), nil
+	})
+}
+
+func newSigner(keyID string, sign func(data []byte) []byte) func(msg *Message) error {
+	return func(msg *Message) error {
+		signed := *msg
+		signed.Properties.SignatureKeyID = keyID
+		data, err := CanonicalBytes(&signed)
+		if err != nil {
+			return fmt.Errorf("signing message: %w", err)
+		}
+		msg.Properties.SignatureKeyID = keyID
+		msg.Properties.Signature = base64.StdEncoding.EncodeToString(sign(data))
+		return nil
+	}
+}
+
+func newVerifier(verify func(keyID string, data, signature []byte) (bool, error)) func(msg *Message) error {
+	return func(msg *Message) error {
+		if msg.Properties.Signature == "" {
+			return ErrSignatureMissing
+		}
+		signature, err := base64.StdEncoding.DecodeString(msg.Properties.Signature)
+		if err != nil {
+			return fmt.Errorf("%w: decoding signature: %w", ErrSignatureInvalid, err)
+		}
+		data, err := CanonicalBytes(msg)
+		if err != nil {
+			return fmt.Errorf("verifying message: %w", err)
+		}
+		ok, err := verify(msg.Properties.SignatureKeyID, data, signature)
+		if err != nil {
+			return fmt.Errorf("%w: %q", err, msg.Properties.SignatureKeyID)
+		}
+		if !ok {
+			return ErrSignatureInvalid
+		}
+		return nil
+	}
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	return h.Sum(nil)
+}
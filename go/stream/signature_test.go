@@ -0,0 +1,217 @@
+package stream_test
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-schemas/go/stream"
+)
+
+func TestSignature(t *testing.T) {
+	newMessage := func() stream.Message {
+		return stream.Message{
+			Properties: stream.MessageProperties{
+				RequestType: "requestType",
+				RoutingKey:  "routingKey",
+				WorkspaceID: "workspaceID",
+				SourceID:    "sourceID",
+				ReceivedAt:  time.Date(2024, 8, 1, 0o2, 30, 50, 200, time.UTC),
+				RequestIP:   "10.29.13.20",
+				IsBot:       true,
+				BotName:     "TestBot",
+			},
+			Payload: json.RawMessage(`{"key": "value", "key2": [1, 2]}`),
+		}
+	}
+
+	t.Run("canonical bytes", func(t *testing.T) {
+		msg := newMessage()
+		msg.Properties.SignatureKeyID = "key-1"
+		msg.Properties.Signature = "ignored"
+
+		data, err := stream.CanonicalBytes(&msg)
+		require.NoError(t, err)
+		require.Equal(t, `{"botAction":"","botIsInvalidBrowser":"false","botName":"TestBot","botURL":"","compression":"","destinationID":"","encryption":"","encryptionKeyID":"","isBot":"true","partitionID":"","receivedAt":"2024-08-01T02:30:50.0000002Z","requestIP":"10.29.13.20","requestType":"requestType","routingKey":"routingKey","signatureKeyID":"key-1","sourceID":"sourceID","sourceJobRunID":"","sourceTaskRunID":"","traceID":"","userID":"","workspaceID":"workspaceID"}
+{"key":"value","key2":[1,2]}`, string(data))
+
+		t.Run("invalid payload", func(t *testing.T) {
+			msg := newMessage()
+			msg.Payload = json.RawMessage(`{`)
+			_, err := stream.CanonicalBytes(&msg)
+			require.ErrorContains(t, err, "compacting payload")
+		})
+	})
+
+	t.Run("hmac", func(t *testing.T) {
+		sign := stream.NewHMACSigner("key-1", []byte("secret"))
+		verify := stream.NewHMACVerifier(map[string][]byte{"key-1": []byte("secret"), "key-2": []byte("other")})
+
+		msg := newMessage()
+		require.ErrorIs(t, verify(&msg), stream.ErrSignatureMissing)
+
+		require.NoError(t, sign(&msg))
+		require.Equal(t, "key-1", msg.Properties.SignatureKeyID)
+		require.NotEmpty(t, msg.Properties.Signature)
+		require.NoError(t, verify(&msg))
+
+		t.Run("survives property map and JSON round trip", func(t *testing.T) {
+			properties, err := stream.FromMapProperties(stream.ToMapProperties(msg.Properties))
+			require.NoError(t, err)
+			data, err := json.Marshal(stream.Message{Properties: properties, Payload: msg.Payload})
+			require.NoError(t, err)
+
+			var received stream.Message
+			require.NoError(t, json.Unmarshal(data, &received))
+			require.NoError(t, verify(&received))
+		})
+
+		t.Run("survives property map round trip of every stage", func(t *testing.T) {
+			for _, stage := range []stream.Stage{"", stream.StageWebhook, stream.StageRetry, stream.StageReplay, stream.StageTransformation, "unknown"} {
+				msg := newMessage()
+				msg.Properties.Stage = stage
+				msg.Properties.SourceType, msg.Properties.WebhookFailureReason = "shopify", "reason"
+				msg.Properties.RetryAttempt, msg.Properties.RetryLastError = 2, "timeout"
+				msg.Properties.ReplayJobID, msg.Properties.ReplayOriginalReceivedAt = "job", time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+				msg.Properties.TransformerVersion = "v1.2.3"
+				require.NoError(t, sign(&msg))
+
+				properties, err := stream.FromMapProperties(stream.ToMapProperties(msg.Properties))
+				require.NoError(t, err, stage)
+				require.NoError(t, verify(&stream.Message{Properties: properties, Payload: msg.Payload}), stage)
+			}
+		})
+
+		t.Run("failed signing leaves the message unchanged", func(t *testing.T) {
+			msg := newMessage()
+			msg.Payload = json.RawMessage(`{`)
+			require.ErrorContains(t, sign(&msg), "signing message")
+			require.Equal(t, newMessage().Properties, msg.Properties)
+		})
+
+		t.Run("signatures of earlier versions", func(t *testing.T) {
+			// signatures made by the first version of the library supporting signatures, before schema versions
+			for signature, msg := range map[string]stream.Message{
+				"7iTZig+/BwTYe6k8KMDVdLG5jfTtfWAqwTGTqYWejwc=": {
+					Properties: stream.MessageProperties{
+						RequestType: "track", RoutingKey: "routingKey", WorkspaceID: "workspaceID", SourceID: "sourceID",
+						ReceivedAt: time.Date(2024, 8, 1, 2, 30, 50, 200, time.UTC), RequestIP: "10.29.13.20",
+						IsBot: true, BotName: "TestBot", BotAction: stream.BotActionFlag,
+						Stage: stream.StageWebhook, SourceType: "webhook-source",
+					},
+					Payload: json.RawMessage(`{"key": "value"}`),
+				},
+				"1BReqwXdjMf9ZzMFPelbsIQ+adEj3rJjwCvoqLMcooU=": {
+					Properties: stream.MessageProperties{
+						RequestType: "track", RoutingKey: "routingKey", WorkspaceID: "workspaceID", SourceID: "sourceID",
+						ReceivedAt: time.Date(2024, 8, 1, 2, 30, 50, 200, time.UTC), RequestIP: "10.29.13.20", PartitionID: "p-1",
+					},
+					Payload: json.RawMessage(`{"key": "value"}`),
+				},
+			} {
+				msg.Properties.Signature, msg.Properties.SignatureKeyID = signature, "key-1"
+				require.NoError(t, verify(&msg))
+
+				properties, err := stream.FromMapProperties(stream.ToMapProperties(msg.Properties))
+				require.NoError(t, err)
+				require.NoError(t, verify(&stream.Message{Properties: properties, Payload: msg.Payload}), "schema versions are not signed")
+			}
+		})
+
+		t.Run("tampered stage properties", func(t *testing.T) {
+			retry := newMessage()
+			retry.Properties.Stage, retry.Properties.RetryAttempt = stream.StageRetry, 1
+			require.NoError(t, sign(&retry))
+			require.NoError(t, verify(&retry))
+			retry.Properties.RetryAttempt = 2
+			require.ErrorIs(t, verify(&retry), stream.ErrSignatureInvalid)
+		})
+
+		t.Run("tampered properties", func(t *testing.T) {
+			tampered := msg
+			tampered.Properties.WorkspaceID = "other"
+			require.ErrorIs(t, verify(&tampered), stream.ErrSignatureInvalid)
+		})
+
+		t.Run("tampered payload", func(t *testing.T) {
+			tampered := msg
+			tampered.Payload = json.RawMessage(`{"key":"other","key2":[1,2]}`)
+			require.ErrorIs(t, verify(&tampered), stream.ErrSignatureInvalid)
+		})
+
+		t.Run("tampered key ID", func(t *testing.T) {
+			tampered := msg
+			tampered.Properties.SignatureKeyID = "key-2"
+			require.ErrorIs(t, verify(&tampered), stream.ErrSignatureInvalid)
+		})
+
+		t.Run("unknown key ID", func(t *testing.T) {
+			tampered := msg
+			tampered.Properties.SignatureKeyID = "key-3"
+			err := verify(&tampered)
+			require.ErrorIs(t, err, stream.ErrSignatureUnknownKey)
+			require.EqualError(t, err, `unknown signature key ID: "key-3"`)
+		})
+
+		t.Run("malformed signature", func(t *testing.T) {
+			tampered := msg
+			tampered.Properties.Signature = "not base64!"
+			require.ErrorIs(t, verify(&tampered), stream.ErrSignatureInvalid)
+		})
+	})
+
+	t.Run("ed25519", func(t *testing.T) {
+		publicKey, privateKey, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		otherPublicKey, _, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+
+		msg := newMessage()
+		require.NoError(t, stream.NewEd25519Signer("gw-key", privateKey)(&msg))
+		require.NoError(t, stream.NewEd25519Verifier(map[string]ed25519.PublicKey{"gw-key": publicKey})(&msg))
+		require.ErrorIs(t, stream.NewEd25519Verifier(map[string]ed25519.PublicKey{"gw-key": otherPublicKey})(&msg), stream.ErrSignatureInvalid)
+		require.ErrorIs(t, stream.NewEd25519Verifier(nil)(&msg), stream.ErrSignatureUnknownKey)
+	})
+
+	t.Run("properties to/from: pulsar", func(t *testing.T) {
+		input := map[string]string{
+			"requestType":     "requestType",
+			"routingKey":      "routingKey",
+			"workspaceID":     "workspaceID",
+			"userID":          "",
+			"sourceID":        "sourceID",
+			"destinationID":   "",
+			"requestIP":       "10.29.13.20",
+			"receivedAt":      time.Date(2024, 8, 1, 0o2, 30, 50, 200, time.UTC).Format(time.RFC3339Nano),
+			"sourceJobRunID":  "",
+			"sourceTaskRunID": "",
+			"traceID":         "",
+			"compression":     "",
+			"encryption":      "",
+			"encryptionKeyID": "",
+			"partitionID":     "",
+			"schemaVersion":   "2",
+			"signature":       "c2lnbmF0dXJl",
+			"signatureKeyID":  "key-1",
+		}
+		properties, err := stream.FromMapProperties(input)
+		require.NoError(t, err)
+		require.Equal(t, "c2lnbmF0dXJl", properties.Signature)
+		require.Equal(t, "key-1", properties.SignatureKeyID)
+		require.Equal(t, input, stream.ToMapProperties(properties))
+
+		t.Run("unsigned", func(t *testing.T) {
+			properties, err := stream.FromMapProperties(map[string]string{
+				"receivedAt":     time.Date(2024, 8, 1, 0o2, 30, 50, 200, time.UTC).Format(time.RFC3339Nano),
+				"signatureKeyID": "key-1",
+			})
+			require.NoError(t, err)
+			require.Empty(t, properties.SignatureKeyID)
+			require.NotContains(t, stream.ToMapProperties(properties), "signature")
+			require.NotContains(t, stream.ToMapProperties(properties), "signatureKeyID")
+		})
+	})
+}
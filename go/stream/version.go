@@ -0,0 +1,70 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strconv"
+)
+
+// MessagePropertiesSchemaVersion is the version of the property map shape produced by ToMapProperties.
+// It must be increased, and a migration registered in propertiesMigrations, whenever
+// the meaning or the encoding of an existing property map key changes.
+const MessagePropertiesSchemaVersion = 2
+
+const mapKeySchemaVersion = "schemaVersion"
+
+// ErrUnsupportedSchemaVersion is returned when a property map was produced by a newer version of the library.
+var ErrUnsupportedSchemaVersion = errors.New("unsupported schema version")
+
+// propertiesMigrations contains, for every schema version older than MessagePropertiesSchemaVersion,
+// the function that upgrades a property map from that version to the next one.
+var propertiesMigrations = map[int]func(properties map[string]string) error{
+	// v1 property maps carry no schema version. The keys added since then (bot fields, partitionID)
+	// are optional and decode as empty values, so no conversion is needed.
+	1: func(properties map[string]string) error { return nil },
+}
+
+// schemaVersion returns the schema version of a property map, v1 if it has none.
+func schemaVersion(properties map[string]string) (int, error) {
+	v, ok := properties[mapKeySchemaVersion]
+	if !ok || v == "" {
+		return 1, nil
+	}
+	version, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("parsing schemaVersion: %w", err)
+	}
+	if version < 1 {
+		return 0, fmt.Errorf("parsing schemaVersion: invalid version %d", version)
+	}
+	return version, nil
+}
+
+// upgradeProperties returns the property map upgraded to MessagePropertiesSchemaVersion.
+// The input map is never modified.
+func upgradeProperties(properties map[string]string) (map[string]string, error) {
+	version, err := schemaVersion(properties)
+	if err != nil {
+		return nil, err
+	}
+	if version > MessagePropertiesSchemaVersion {
+		return nil, fmt.Errorf("%w: %d, latest supported version is %d", ErrUnsupportedSchemaVersion, version, MessagePropertiesSchemaVersion)
+	}
+	if version == MessagePropertiesSchemaVersion {
+		return properties, nil
+	}
+
+	properties = maps.Clone(properties)
+	for ; version < MessagePropertiesSchemaVersion; version++ {
+		migrate, ok := propertiesMigrations[version]
+		if !ok {
+			return nil, fmt.Errorf("no migration registered for schema version %d", version)
+		}
+		if err := migrate(properties); err != nil {
+			return nil, fmt.Errorf("migrating properties from schema version %d: %w", version, err)
+		}
+	}
+	properties[mapKeySchemaVersion] = strconv.Itoa(MessagePropertiesSchemaVersion)
+	return properties, nil
+}
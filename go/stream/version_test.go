@@ -0,0 +1,78 @@
+package stream_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rudderlabs/rudder-schemas/go/stream"
+)
+
+func TestSchemaVersion(t *testing.T) {
+	receivedAt := time.Date(2024, 8, 1, 0o2, 30, 50, 200, time.UTC)
+
+	t.Run("current version is written", func(t *testing.T) {
+		properties := stream.ToMapProperties(stream.MessageProperties{ReceivedAt: receivedAt})
+		require.Equal(t, strconv.Itoa(stream.MessagePropertiesSchemaVersion), properties["schemaVersion"])
+	})
+
+	t.Run("v1 without version marker is upgraded", func(t *testing.T) {
+		input := map[string]string{
+			"requestType": "requestType",
+			"routingKey":  "routingKey",
+			"workspaceID": "workspaceID",
+			"sourceID":    "sourceID",
+			"requestIP":   "10.29.13.20",
+			"receivedAt":  receivedAt.Format(time.RFC3339Nano),
+		}
+
+		properties, err := stream.FromMapProperties(input)
+		require.NoError(t, err)
+		require.Equal(t, stream.MessageProperties{
+			RequestType: "requestType",
+			RoutingKey:  "routingKey",
+			WorkspaceID: "workspaceID",
+			SourceID:    "sourceID",
+			RequestIP:   "10.29.13.20",
+			ReceivedAt:  receivedAt,
+		}, properties)
+		require.NotContains(t, input, "schemaVersion", "input map should not be modified")
+		require.Equal(t, "2", stream.ToMapProperties(properties)["schemaVersion"])
+	})
+
+	t.Run("explicit v1 is upgraded", func(t *testing.T) {
+		properties, err := stream.FromMapProperties(map[string]string{
+			"workspaceID":   "workspaceID",
+			"receivedAt":    receivedAt.Format(time.RFC3339Nano),
+			"schemaVersion": "1",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "workspaceID", properties.WorkspaceID)
+	})
+
+	t.Run("newer version is rejected", func(t *testing.T) {
+		properties, err := stream.FromMapProperties(map[string]string{
+			"receivedAt":    receivedAt.Format(time.RFC3339Nano),
+			"schemaVersion": strconv.Itoa(stream.MessagePropertiesSchemaVersion + 1),
+		})
+		require.Empty(t, properties)
+		require.ErrorIs(t, err, stream.ErrUnsupportedSchemaVersion)
+		require.EqualError(t, err, "unsupported schema version: 3, latest supported version is 2")
+	})
+
+	t.Run("invalid version", func(t *testing.T) {
+		_, err := stream.FromMapProperties(map[string]string{
+			"receivedAt":    receivedAt.Format(time.RFC3339Nano),
+			"schemaVersion": "two",
+		})
+		require.EqualError(t, err, `parsing schemaVersion: strconv.Atoi: parsing "two": invalid syntax`)
+
+		_, err = stream.FromMapProperties(map[string]string{
+			"receivedAt":    receivedAt.Format(time.RFC3339Nano),
+			"schemaVersion": "0",
+		})
+		require.EqualError(t, err, "parsing schemaVersion: invalid version 0")
+	})
+}
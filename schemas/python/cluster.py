@@ -0,0 +1,245 @@
+# Code generated by schemagen. DO NOT EDIT.
+from __future__ import annotations
+
+from datetime import datetime
+from enum import Enum
+from typing import List, Literal, Optional, Union
+
+from pydantic import BaseModel, ConfigDict, Field
+
+
+class PartitionMigrationStatus(str, Enum):
+    NEW = "new"
+    """initial state"""
+    RELOADING_GW = "reloading-gw"
+    """reloading gateway nodes"""
+    RELOADING_SRC_ROUTER = "reloading-srcrouter"
+    """reloading source routers"""
+    MIGRATING = "migrating"
+    """migrating partitions"""
+    COMPLETED = "completed"
+    """migration completed"""
+
+
+class PartitionMigrationJobStatus(str, Enum):
+    NEW = "new"
+    """initial state"""
+    MOVED = "moved"
+    """partitions have been moved"""
+    COMPLETED = "completed"
+    """migration job completed"""
+
+
+class PartitionMigrationJobHeader(BaseModel):
+    """PartitionMigrationJobHeader contains the basic information about a partition migration job."""
+
+    model_config = ConfigDict(populate_by_name=True)
+
+    job_id: str = Field(alias="jobId")
+    """unique identifier for the migration job"""
+    source_node: int = Field(alias="sourceNode")
+    """Index of the source node"""
+    target_node: int = Field(alias="targetNode")
+    """Index of the target node"""
+    partitions: Optional[List[str]] = Field(alias="partitions")
+    """List of partition IDs being migrated"""
+
+
+class PartitionMigration(BaseModel):
+    """PartitionMigration represents the overall migration process for a set of partitions."""
+
+    model_config = ConfigDict(populate_by_name=True)
+
+    id: str = Field(alias="id")
+    """unique identifier for the migration"""
+    status: Union[PartitionMigrationStatus, Literal[""]] = Field(alias="status")
+    """current status of the migration"""
+    previous_status: Union[PartitionMigrationStatus, Literal[""]] = Field(alias="previousStatus")
+    """previous status of the migration"""
+    jobs: Optional[List[PartitionMigrationJobHeader]] = Field(alias="jobs")
+    """list of migration jobs"""
+    start_time: datetime = Field(alias="startTime")
+    """time when the migration was started"""
+    ack_key_prefix: str = Field(alias="ackKeyPrefix")
+    """the key prefix to use for acknowledging the migration initialization"""
+
+
+class PartitionMigrationAck(BaseModel):
+    """PartitionMigrationAck represents an acknowledgment from a node regarding the migration."""
+
+    model_config = ConfigDict(populate_by_name=True)
+
+    node_index: int = Field(alias="nodeIndex")
+    """Index of the node acknowledging"""
+    node_name: str = Field(alias="nodeName")
+    """Name of the node acknowledging"""
+
+
+class ReloadGatewayCommand(BaseModel):
+    """ReloadGatewayCommand represents a command to reload the gateway nodes during migration."""
+
+    model_config = ConfigDict(populate_by_name=True)
+
+    nodes: Optional[List[int]] = Field(alias="nodes")
+    """list of gateway node indices to reload"""
+    ack_key_prefix: str = Field(alias="ackKeyPrefix")
+    """the key prefix to use for acknowledging the reload"""
+
+
+class ReloadGatewayAck(BaseModel):
+    """ReloadGatewayAck represents an acknowledgment from a gateway node after reloading."""
+
+    model_config = ConfigDict(populate_by_name=True)
+
+    node_index: int = Field(alias="nodeIndex")
+    """Index of the node acknowledging"""
+    node_name: str = Field(alias="nodeName")
+    """Name of the node acknowledging"""
+
+
+class ReloadSrcRouterCommand(BaseModel):
+    """ReloadSrcRouterCommand represents a command to reload the source routers during migration."""
+
+    model_config = ConfigDict(populate_by_name=True)
+
+    ack_key_prefix: str = Field(alias="ackKeyPrefix")
+    """the key prefix to use for acknowledging the reload"""
+
+
+class ReloadSrcRouterAck(BaseModel):
+    """ReloadSrcRouterAck represents an acknowledgment from the srcrouter after reloading."""
+
+    model_config = ConfigDict(populate_by_name=True)
+
+    node_name: str = Field(alias="nodeName")
+    """Name of the node acknowledging"""
+
+
+class PartitionMigrationJob(BaseModel):
+    """PartitionMigrationJob represents a specific migration job for a set of partitions."""
+
+    model_config = ConfigDict(populate_by_name=True)
+
+    job_id: str = Field(alias="jobId")
+    """unique identifier for the migration job"""
+    source_node: int = Field(alias="sourceNode")
+    """Index of the source node"""
+    target_node: int = Field(alias="targetNode")
+    """Index of the target node"""
+    partitions: Optional[List[str]] = Field(alias="partitions")
+    """List of partition IDs being migrated"""
+    migration_id: str = Field(alias="migrationId")
+    status: Union[PartitionMigrationJobStatus, Literal[""]] = Field(alias="status")
+    start_time: datetime = Field(alias="startTime")
+    """time when the migration job was started"""
+
+
+class PartitionMigrationInfo(BaseModel):
+    """PartitionMigrationInfo represents the information about an ongoing partition migration, including job details."""
+
+    model_config = ConfigDict(populate_by_name=True)
+
+    id: str = Field(alias="id")
+    """unique identifier for the migration"""
+    status: Union[PartitionMigrationStatus, Literal[""]] = Field(alias="status")
+    """current status of the migration"""
+    previous_status: Union[PartitionMigrationStatus, Literal[""]] = Field(alias="previousStatus")
+    """previous status of the migration"""
+    jobs: Optional[List[PartitionMigrationJob]] = Field(alias="jobs")
+    """list of migration jobs"""
+    start_time: datetime = Field(alias="startTime")
+    """time when the migration was started"""
+    ack_key_prefix: str = Field(alias="ackKeyPrefix")
+    """the key prefix to use for acknowledging the migration initialization"""
+
+
+class PartitionMigrationLimits(BaseModel):
+    """PartitionMigrationLimits throttles the waves of a PartitionMigrationPlan. Zero values mean no limit."""
+
+    model_config = ConfigDict(populate_by_name=True)
+
+    max_partitions_per_job: int = Field(alias="maxPartitionsPerJob")
+    """maximum number of partitions moved by a job"""
+    max_jobs_per_node: int = Field(alias="maxJobsPerNode")
+    """maximum number of jobs of a wave having the same source, or the same target node"""
+    max_bytes_per_wave: int = Field(alias="maxBytesPerWave")
+    """maximum size of the partitions moved by a wave, if partition sizes are known"""
+
+
+class PartitionMigrationWave(BaseModel):
+    """PartitionMigrationWave is a set of migration jobs running concurrently."""
+
+    model_config = ConfigDict(populate_by_name=True)
+
+    jobs: Optional[List[PartitionMigrationJobHeader]] = Field(alias="jobs")
+    """list of migration jobs"""
+    bytes: int = Field(alias="bytes")
+    """size of the partitions moved by the wave, 0 if unknown"""
+
+
+class PartitionMigrationPlan(BaseModel):
+    """PartitionMigrationPlan is a migration split into ordered waves within limits, every wave being migrated as a PartitionMigration once the previous one is completed, so that nodes are not overloaded."""
+
+    model_config = ConfigDict(populate_by_name=True)
+
+    id: str = Field(alias="id")
+    """unique identifier for the plan"""
+    limits: PartitionMigrationLimits = Field(alias="limits")
+    """limits of the waves"""
+    waves: Optional[List[PartitionMigrationWave]] = Field(alias="waves")
+    """ordered list of waves"""
+
+
+class PartitionMigrationEventType(str, Enum):
+    """PartitionMigrationEventType is the type of a PartitionMigrationEvent."""
+
+    STATUS_CHANGED = "status-changed"
+    """the migration moved to a new status"""
+    JOB_STATUS_CHANGED = "job-status-changed"
+    """a migration job moved to a new status"""
+    ACK_RECEIVED = "ack-received"
+    """a node acknowledged a command"""
+    COMMAND_ISSUED = "command-issued"
+    """the coordinator issued a command to nodes"""
+    FAILURE = "failure"
+    """an actor failed, without changing the migration"""
+
+
+class PartitionMigrationCommand(str, Enum):
+    """PartitionMigrationCommand is a command acknowledged by nodes during a migration."""
+
+    MIGRATION = "migration"
+    """the migration itself, issued when it is created"""
+    RELOAD_GATEWAY = "reload-gw"
+    """see ReloadGatewayCommand"""
+    RELOAD_SRC_ROUTER = "reload-srcrouter"
+    """see ReloadSrcRouterCommand"""
+
+
+class PartitionMigrationEvent(BaseModel):
+    """PartitionMigrationEvent is an entry of the append-only audit log of a migration, keeping the history that PartitionMigration doesn't. The first event of a migration is its status change to new, carrying the migration."""
+
+    model_config = ConfigDict(populate_by_name=True)
+
+    type: Union[PartitionMigrationEventType, Literal[""]] = Field(alias="type")
+    """type of the event"""
+    migration_id: str = Field(alias="migrationId")
+    """identifier of the migration"""
+    time: datetime = Field(alias="time")
+    """time when the event occurred"""
+    actor: str = Field(alias="actor")
+    """name of the coordinator or node causing the event, e.g. the node acknowledging a command"""
+    status: Optional[PartitionMigrationStatus] = Field(default=None, alias="status")
+    """new status of the migration, for status-changed events"""
+    migration: Optional[PartitionMigration] = Field(default=None, alias="migration")
+    """migration created, for the status-changed event to new"""
+    job_id: Optional[str] = Field(default=None, alias="jobId")
+    """identifier of the job, for job-status-changed events"""
+    job_status: Optional[PartitionMigrationJobStatus] = Field(default=None, alias="jobStatus")
+    """new status of the job, for job-status-changed events"""
+    command: Optional[PartitionMigrationCommand] = Field(default=None, alias="command")
+    """command issued or acknowledged, for command-issued and ack-received events"""
+    nodes: Optional[List[str]] = Field(default=None, alias="nodes")
+    """names of the nodes expected to acknowledge the command, for command-issued events"""
+    error: Optional[str] = Field(default=None, alias="error")
+    """error message, for failure events"""
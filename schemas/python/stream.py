@@ -0,0 +1,197 @@
+# Code generated by schemagen. DO NOT EDIT.
+from __future__ import annotations
+
+from datetime import datetime
+from enum import Enum
+from typing import Any, List, Optional
+
+from pydantic import BaseModel, ConfigDict, Field
+
+
+class FileCompression(str, Enum):
+    """FileCompression is the compression applied to a whole NDJSON stream of messages."""
+
+    NONE = ""
+    """plain NDJSON"""
+    GZIP = "gzip"
+    """gzip compressed NDJSON"""
+    ZSTD = "zstd"
+    """zstd compressed NDJSON"""
+
+
+class Stage(str, Enum):
+    """Stage is the pipeline stage that produced a message."""
+
+    WEBHOOK = "webhook"
+    """message produced by the webhook source handler, see SourceType and WebhookFailureReason"""
+    RETRY = "retry"
+    """message redelivered after a failure, see RetryAttempt and RetryLastError"""
+    REPLAY = "replay"
+    """message replayed from an archive, see ReplayJobID and ReplayOriginalReceivedAt"""
+    TRANSFORMATION = "transformation"
+    """message produced by a transformer, see TransformerVersion"""
+
+
+class BotAction(str, Enum):
+    """BotAction is the action to take for messages sent by bots."""
+
+    FLAG = "flag"
+    """enrich the message with bot details"""
+    DISABLE = "disable"
+    """drop the message, only reporting metrics"""
+
+
+class CompressionAlgorithm(str, Enum):
+    """CompressionAlgorithm is the algorithm used for compressing the payload of a message, as part of the serialized compression settings of MessageProperties.Compression."""
+
+    ZSTD = "zstd"
+    """pure go zstd"""
+    ZSTD_CGO = "zstd-cgo"
+    """cgo zstd"""
+
+
+class EncryptionScheme(str, Enum):
+    """EncryptionScheme is the scheme used for encrypting the payload of a message, as part of the serialized encryption settings of MessageProperties.Encryption."""
+
+    AESGCM = "aes-gcm"
+    """AES in Galois/Counter Mode"""
+
+
+class MessageProperties(BaseModel):
+    model_config = ConfigDict(populate_by_name=True)
+
+    request_type: str = Field(alias="requestType")
+    routing_key: str = Field(alias="routingKey")
+    workspace_id: str = Field(alias="workspaceID")
+    source_id: str = Field(alias="sourceID")
+    received_at: datetime = Field(alias="receivedAt")
+    request_ip: str = Field(alias="requestIP")
+    destination_id: Optional[str] = Field(default=None, alias="destinationID")
+    user_id: Optional[str] = Field(default=None, alias="userID")
+    source_job_run_id: Optional[str] = Field(default=None, alias="sourceJobRunID")
+    source_task_run_id: Optional[str] = Field(default=None, alias="sourceTaskRunID")
+    trace_id: Optional[str] = Field(default=None, alias="traceID")
+    source_type: Optional[str] = Field(default=None, alias="sourceType")
+    webhook_failure_reason: Optional[str] = Field(default=None, alias="webhookFailureReason")
+    stage: Optional[Stage] = Field(default=None, alias="stage")
+    """Stage is the pipeline stage that produced the message"""
+    compression: Optional[str] = Field(default=None, alias="compression")
+    """Compression contains the serialized compression settings of the payload, see CompressionAlgorithm"""
+    encryption: Optional[str] = Field(default=None, alias="encryption")
+    """Encryption contains the serialized encryption settings of the payload, see EncryptionScheme"""
+    encryption_key_id: Optional[str] = Field(default=None, alias="encryptionKeyID")
+    """if key is rotated EncryptionKeyID should be used to refer to correct key"""
+    is_bot: Optional[bool] = Field(default=None, alias="isBot")
+    bot_name: Optional[str] = Field(default=None, alias="botName")
+    """BotName is the name of the bot that sent the event"""
+    bot_url: Optional[str] = Field(default=None, alias="botURL")
+    """BotURL contains the source URL or reference that explains why the user agent was identified as a bot"""
+    bot_is_invalid_browser: Optional[bool] = Field(default=None, alias="botIsInvalidBrowser")
+    """BotIsInvalidBrowser is true if event is a bot and the browser is invalid"""
+    bot_action: Optional[BotAction] = Field(default=None, alias="botAction")
+    """BotAction defines the action for bot events: "flag" enriches with bot details, "disable" only reports metrics."""
+    partition_id: Optional[str] = Field(default=None, alias="partitionID")
+    signature: Optional[str] = Field(default=None, alias="signature")
+    """Signature is the base64 encoded signature of the message, see CanonicalBytes"""
+    signature_key_id: Optional[str] = Field(default=None, alias="signatureKeyID")
+    """SignatureKeyID refers to the key that was used for producing Signature"""
+    retry_attempt: Optional[int] = Field(default=None, alias="retryAttempt")
+    """RetryAttempt is the attempt number of a message redelivered by the retry stage, starting from 1"""
+    retry_last_error: Optional[str] = Field(default=None, alias="retryLastError")
+    """RetryLastError is the error that caused the previous attempt of a message redelivered by the retry stage to fail"""
+    replay_job_id: Optional[str] = Field(default=None, alias="replayJobID")
+    """ReplayJobID is the ID of the job that replayed the message"""
+    replay_original_received_at: Optional[datetime] = Field(default=None, alias="replayOriginalReceivedAt")
+    """ReplayOriginalReceivedAt is the time at which a replayed message was originally received"""
+    transformer_version: Optional[str] = Field(default=None, alias="transformerVersion")
+    """TransformerVersion is the version of the transformer that produced the message"""
+
+
+class Message(BaseModel):
+    model_config = ConfigDict(populate_by_name=True)
+
+    properties: MessageProperties = Field(alias="properties")
+    payload: Any = Field(alias="payload")
+
+
+class DeadLetterStage(str, Enum):
+    """DeadLetterStage is the processing stage in which a consumer failed to handle a message."""
+
+    VALIDATION = "validation"
+    """message failed schema validation"""
+    DECRYPTION = "decryption"
+    """payload could not be decrypted"""
+    DOWNSTREAM = "downstream"
+    """a downstream system rejected the message"""
+
+
+class DeadLetterConsumer(BaseModel):
+    """DeadLetterConsumer identifies the service instance that dead-lettered a message."""
+
+    model_config = ConfigDict(populate_by_name=True)
+
+    service: str = Field(alias="service")
+    """name of the consuming service"""
+    instance: Optional[str] = Field(default=None, alias="instance")
+    """optional instance or pod name"""
+
+
+class DeadLetter(BaseModel):
+    """DeadLetter wraps a message that a consumer could not process, together with the failure details."""
+
+    model_config = ConfigDict(populate_by_name=True)
+
+    message: Message = Field(alias="message")
+    """original message as received, not validated since it may be the cause of the failure"""
+    stage: DeadLetterStage = Field(alias="stage")
+    """stage in which processing failed"""
+    error_code: str = Field(alias="errorCode")
+    """machine readable error code"""
+    error_message: str = Field(alias="errorMessage")
+    """human readable error message of the last failure"""
+    invalid_fields: Optional[List[str]] = Field(default=None, alias="invalidFields")
+    """fields that failed validation, if any"""
+    attempts: int = Field(alias="attempts")
+    """number of processing attempts that failed"""
+    first_failed_at: datetime = Field(alias="firstFailedAt")
+    """time of the first failure"""
+    last_failed_at: datetime = Field(alias="lastFailedAt")
+    """time of the last failure"""
+    consumer: DeadLetterConsumer = Field(alias="consumer")
+    """identity of the consumer that failed"""
+
+
+class MessagePropertiesMapKey(str, Enum):
+    """Keys of the property map encoding of MessageProperties."""
+
+    REQUEST_TYPE = "requestType"
+    ROUTING_KEY = "routingKey"
+    WORKSPACE_ID = "workspaceID"
+    SOURCE_ID = "sourceID"
+    DESTINATION_ID = "destinationID"
+    REQUEST_IP = "requestIP"
+    RECEIVED_AT = "receivedAt"
+    USER_ID = "userID"
+    SOURCE_JOB_RUN_ID = "sourceJobRunID"
+    SOURCE_TASK_RUN_ID = "sourceTaskRunID"
+    TRACE_ID = "traceID"
+    SOURCE_TYPE = "sourceType"
+    WEBHOOK_FAILURE_REASON = "webhookFailureReason"
+    STAGE = "stage"
+    COMPRESSION = "compression"
+    ENCRYPTION = "encryption"
+    ENCRYPTION_KEY_ID = "encryptionKeyID"
+    IS_BOT = "isBot"
+    BOT_NAME = "botName"
+    BOT_URL = "botURL"
+    BOT_IS_INVALID_BROWSER = "botIsInvalidBrowser"
+    BOT_ACTION = "botAction"
+    PARTITION_ID = "partitionID"
+    SIGNATURE = "signature"
+    SIGNATURE_KEY_ID = "signatureKeyID"
+    RETRY_ATTEMPT = "retryAttempt"
+    RETRY_LAST_ERROR = "retryLastError"
+    REPLAY_JOB_ID = "replayJobID"
+    REPLAY_ORIGINAL_RECEIVED_AT = "replayOriginalReceivedAt"
+    TRANSFORMER_VERSION = "transformerVersion"
+    SCHEMA_VERSION = "schemaVersion"
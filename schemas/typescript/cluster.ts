@@ -0,0 +1,201 @@
+// Code generated by schemagen. DO NOT EDIT.
+
+export const PartitionMigrationStatus = {
+  /** initial state */
+  New: "new",
+  /** reloading gateway nodes */
+  ReloadingGW: "reloading-gw",
+  /** reloading source routers */
+  ReloadingSrcRouter: "reloading-srcrouter",
+  /** migrating partitions */
+  Migrating: "migrating",
+  /** migration completed */
+  Completed: "completed",
+} as const;
+export type PartitionMigrationStatus = (typeof PartitionMigrationStatus)[keyof typeof PartitionMigrationStatus];
+
+export const PartitionMigrationJobStatus = {
+  /** initial state */
+  New: "new",
+  /** partitions have been moved */
+  Moved: "moved",
+  /** migration job completed */
+  Completed: "completed",
+} as const;
+export type PartitionMigrationJobStatus = (typeof PartitionMigrationJobStatus)[keyof typeof PartitionMigrationJobStatus];
+
+/** PartitionMigrationJobHeader contains the basic information about a partition migration job. */
+export interface PartitionMigrationJobHeader {
+  /** unique identifier for the migration job */
+  jobId: string;
+  /** Index of the source node */
+  sourceNode: number;
+  /** Index of the target node */
+  targetNode: number;
+  /** List of partition IDs being migrated */
+  partitions: string[] | null;
+}
+
+/** PartitionMigration represents the overall migration process for a set of partitions. */
+export interface PartitionMigration {
+  /** unique identifier for the migration */
+  id: string;
+  /** current status of the migration */
+  status: PartitionMigrationStatus | "";
+  /** previous status of the migration */
+  previousStatus: PartitionMigrationStatus | "";
+  /** list of migration jobs */
+  jobs: PartitionMigrationJobHeader[] | null;
+  /** time when the migration was started */
+  startTime: string;
+  /** the key prefix to use for acknowledging the migration initialization */
+  ackKeyPrefix: string;
+}
+
+/** PartitionMigrationAck represents an acknowledgment from a node regarding the migration. */
+export interface PartitionMigrationAck {
+  /** Index of the node acknowledging */
+  nodeIndex: number;
+  /** Name of the node acknowledging */
+  nodeName: string;
+}
+
+/** ReloadGatewayCommand represents a command to reload the gateway nodes during migration. */
+export interface ReloadGatewayCommand {
+  /** list of gateway node indices to reload */
+  nodes: number[] | null;
+  /** the key prefix to use for acknowledging the reload */
+  ackKeyPrefix: string;
+}
+
+/** ReloadGatewayAck represents an acknowledgment from a gateway node after reloading. */
+export interface ReloadGatewayAck {
+  /** Index of the node acknowledging */
+  nodeIndex: number;
+  /** Name of the node acknowledging */
+  nodeName: string;
+}
+
+/** ReloadSrcRouterCommand represents a command to reload the source routers during migration. */
+export interface ReloadSrcRouterCommand {
+  /** the key prefix to use for acknowledging the reload */
+  ackKeyPrefix: string;
+}
+
+/** ReloadSrcRouterAck represents an acknowledgment from the srcrouter after reloading. */
+export interface ReloadSrcRouterAck {
+  /** Name of the node acknowledging */
+  nodeName: string;
+}
+
+/** PartitionMigrationJob represents a specific migration job for a set of partitions. */
+export interface PartitionMigrationJob {
+  /** unique identifier for the migration job */
+  jobId: string;
+  /** Index of the source node */
+  sourceNode: number;
+  /** Index of the target node */
+  targetNode: number;
+  /** List of partition IDs being migrated */
+  partitions: string[] | null;
+  migrationId: string;
+  status: PartitionMigrationJobStatus | "";
+  /** time when the migration job was started */
+  startTime: string;
+}
+
+/** PartitionMigrationInfo represents the information about an ongoing partition migration, including job details. */
+export interface PartitionMigrationInfo {
+  /** unique identifier for the migration */
+  id: string;
+  /** current status of the migration */
+  status: PartitionMigrationStatus | "";
+  /** previous status of the migration */
+  previousStatus: PartitionMigrationStatus | "";
+  /** list of migration jobs */
+  jobs: PartitionMigrationJob[] | null;
+  /** time when the migration was started */
+  startTime: string;
+  /** the key prefix to use for acknowledging the migration initialization */
+  ackKeyPrefix: string;
+}
+
+/** PartitionMigrationLimits throttles the waves of a PartitionMigrationPlan. Zero values mean no limit. */
+export interface PartitionMigrationLimits {
+  /** maximum number of partitions moved by a job */
+  maxPartitionsPerJob: number;
+  /** maximum number of jobs of a wave having the same source, or the same target node */
+  maxJobsPerNode: number;
+  /** maximum size of the partitions moved by a wave, if partition sizes are known */
+  maxBytesPerWave: number;
+}
+
+/** PartitionMigrationWave is a set of migration jobs running concurrently. */
+export interface PartitionMigrationWave {
+  /** list of migration jobs */
+  jobs: PartitionMigrationJobHeader[] | null;
+  /** size of the partitions moved by the wave, 0 if unknown */
+  bytes: number;
+}
+
+/** PartitionMigrationPlan is a migration split into ordered waves within limits, every wave being migrated as a PartitionMigration once the previous one is completed, so that nodes are not overloaded. */
+export interface PartitionMigrationPlan {
+  /** unique identifier for the plan */
+  id: string;
+  /** limits of the waves */
+  limits: PartitionMigrationLimits;
+  /** ordered list of waves */
+  waves: PartitionMigrationWave[] | null;
+}
+
+/** PartitionMigrationEventType is the type of a PartitionMigrationEvent. */
+export const PartitionMigrationEventType = {
+  /** the migration moved to a new status */
+  StatusChanged: "status-changed",
+  /** a migration job moved to a new status */
+  JobStatusChanged: "job-status-changed",
+  /** a node acknowledged a command */
+  AckReceived: "ack-received",
+  /** the coordinator issued a command to nodes */
+  CommandIssued: "command-issued",
+  /** an actor failed, without changing the migration */
+  Failure: "failure",
+} as const;
+export type PartitionMigrationEventType = (typeof PartitionMigrationEventType)[keyof typeof PartitionMigrationEventType];
+
+/** PartitionMigrationCommand is a command acknowledged by nodes during a migration. */
+export const PartitionMigrationCommand = {
+  /** the migration itself, issued when it is created */
+  Migration: "migration",
+  /** see ReloadGatewayCommand */
+  ReloadGateway: "reload-gw",
+  /** see ReloadSrcRouterCommand */
+  ReloadSrcRouter: "reload-srcrouter",
+} as const;
+export type PartitionMigrationCommand = (typeof PartitionMigrationCommand)[keyof typeof PartitionMigrationCommand];
+
+/** PartitionMigrationEvent is an entry of the append-only audit log of a migration, keeping the history that PartitionMigration doesn't. The first event of a migration is its status change to new, carrying the migration. */
+export interface PartitionMigrationEvent {
+  /** type of the event */
+  type: PartitionMigrationEventType | "";
+  /** identifier of the migration */
+  migrationId: string;
+  /** time when the event occurred */
+  time: string;
+  /** name of the coordinator or node causing the event, e.g. the node acknowledging a command */
+  actor: string;
+  /** new status of the migration, for status-changed events */
+  status?: PartitionMigrationStatus;
+  /** migration created, for the status-changed event to new */
+  migration?: PartitionMigration;
+  /** identifier of the job, for job-status-changed events */
+  jobId?: string;
+  /** new status of the job, for job-status-changed events */
+  jobStatus?: PartitionMigrationJobStatus;
+  /** command issued or acknowledged, for command-issued and ack-received events */
+  command?: PartitionMigrationCommand;
+  /** names of the nodes expected to acknowledge the command, for command-issued events */
+  nodes?: string[];
+  /** error message, for failure events */
+  error?: string;
+}
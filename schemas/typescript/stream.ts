@@ -0,0 +1,180 @@
+// Code generated by schemagen. DO NOT EDIT.
+
+/** FileCompression is the compression applied to a whole NDJSON stream of messages. */
+export const FileCompression = {
+  /** plain NDJSON */
+  None: "",
+  /** gzip compressed NDJSON */
+  Gzip: "gzip",
+  /** zstd compressed NDJSON */
+  Zstd: "zstd",
+} as const;
+export type FileCompression = (typeof FileCompression)[keyof typeof FileCompression];
+
+/** Stage is the pipeline stage that produced a message. */
+export const Stage = {
+  /** message produced by the webhook source handler, see SourceType and WebhookFailureReason */
+  Webhook: "webhook",
+  /** message redelivered after a failure, see RetryAttempt and RetryLastError */
+  Retry: "retry",
+  /** message replayed from an archive, see ReplayJobID and ReplayOriginalReceivedAt */
+  Replay: "replay",
+  /** message produced by a transformer, see TransformerVersion */
+  Transformation: "transformation",
+} as const;
+export type Stage = (typeof Stage)[keyof typeof Stage];
+
+/** BotAction is the action to take for messages sent by bots. */
+export const BotAction = {
+  /** enrich the message with bot details */
+  Flag: "flag",
+  /** drop the message, only reporting metrics */
+  Disable: "disable",
+} as const;
+export type BotAction = (typeof BotAction)[keyof typeof BotAction];
+
+/** CompressionAlgorithm is the algorithm used for compressing the payload of a message, as part of the serialized compression settings of MessageProperties.Compression. */
+export const CompressionAlgorithm = {
+  /** pure go zstd */
+  Zstd: "zstd",
+  /** cgo zstd */
+  ZstdCgo: "zstd-cgo",
+} as const;
+export type CompressionAlgorithm = (typeof CompressionAlgorithm)[keyof typeof CompressionAlgorithm];
+
+/** EncryptionScheme is the scheme used for encrypting the payload of a message, as part of the serialized encryption settings of MessageProperties.Encryption. */
+export const EncryptionScheme = {
+  /** AES in Galois/Counter Mode */
+  AESGCM: "aes-gcm",
+} as const;
+export type EncryptionScheme = (typeof EncryptionScheme)[keyof typeof EncryptionScheme];
+
+export interface MessageProperties {
+  requestType: string;
+  routingKey: string;
+  workspaceID: string;
+  sourceID: string;
+  receivedAt: string;
+  requestIP: string;
+  destinationID?: string;
+  userID?: string;
+  sourceJobRunID?: string;
+  sourceTaskRunID?: string;
+  traceID?: string;
+  sourceType?: string;
+  webhookFailureReason?: string;
+  /** Stage is the pipeline stage that produced the message */
+  stage?: Stage;
+  /** Compression contains the serialized compression settings of the payload, see CompressionAlgorithm */
+  compression?: string;
+  /** Encryption contains the serialized encryption settings of the payload, see EncryptionScheme */
+  encryption?: string;
+  /** if key is rotated EncryptionKeyID should be used to refer to correct key */
+  encryptionKeyID?: string;
+  isBot?: boolean;
+  /** BotName is the name of the bot that sent the event */
+  botName?: string;
+  /** BotURL contains the source URL or reference that explains why the user agent was identified as a bot */
+  botURL?: string;
+  /** BotIsInvalidBrowser is true if event is a bot and the browser is invalid */
+  botIsInvalidBrowser?: boolean;
+  /** BotAction defines the action for bot events: "flag" enriches with bot details, "disable" only reports metrics. */
+  botAction?: BotAction;
+  partitionID?: string;
+  /** Signature is the base64 encoded signature of the message, see CanonicalBytes */
+  signature?: string;
+  /** SignatureKeyID refers to the key that was used for producing Signature */
+  signatureKeyID?: string;
+  /** RetryAttempt is the attempt number of a message redelivered by the retry stage, starting from 1 */
+  retryAttempt?: number;
+  /** RetryLastError is the error that caused the previous attempt of a message redelivered by the retry stage to fail */
+  retryLastError?: string;
+  /** ReplayJobID is the ID of the job that replayed the message */
+  replayJobID?: string;
+  /** ReplayOriginalReceivedAt is the time at which a replayed message was originally received */
+  replayOriginalReceivedAt?: string;
+  /** TransformerVersion is the version of the transformer that produced the message */
+  transformerVersion?: string;
+}
+
+export interface Message {
+  properties: MessageProperties;
+  payload: unknown;
+}
+
+/** DeadLetterStage is the processing stage in which a consumer failed to handle a message. */
+export const DeadLetterStage = {
+  /** message failed schema validation */
+  Validation: "validation",
+  /** payload could not be decrypted */
+  Decryption: "decryption",
+  /** a downstream system rejected the message */
+  Downstream: "downstream",
+} as const;
+export type DeadLetterStage = (typeof DeadLetterStage)[keyof typeof DeadLetterStage];
+
+/** DeadLetterConsumer identifies the service instance that dead-lettered a message. */
+export interface DeadLetterConsumer {
+  /** name of the consuming service */
+  service: string;
+  /** optional instance or pod name */
+  instance?: string;
+}
+
+/** DeadLetter wraps a message that a consumer could not process, together with the failure details. */
+export interface DeadLetter {
+  /** original message as received, not validated since it may be the cause of the failure */
+  message: Message;
+  /** stage in which processing failed */
+  stage: DeadLetterStage;
+  /** machine readable error code */
+  errorCode: string;
+  /** human readable error message of the last failure */
+  errorMessage: string;
+  /** fields that failed validation, if any */
+  invalidFields?: string[];
+  /** number of processing attempts that failed */
+  attempts: number;
+  /** time of the first failure */
+  firstFailedAt: string;
+  /** time of the last failure */
+  lastFailedAt: string;
+  /** identity of the consumer that failed */
+  consumer: DeadLetterConsumer;
+}
+
+/** Keys of the property map encoding of MessageProperties. */
+export const MessagePropertiesMapKey = {
+  RequestType: "requestType",
+  RoutingKey: "routingKey",
+  WorkspaceID: "workspaceID",
+  SourceID: "sourceID",
+  DestinationID: "destinationID",
+  RequestIP: "requestIP",
+  ReceivedAt: "receivedAt",
+  UserID: "userID",
+  SourceJobRunID: "sourceJobRunID",
+  SourceTaskRunID: "sourceTaskRunID",
+  TraceID: "traceID",
+  SourceType: "sourceType",
+  WebhookFailureReason: "webhookFailureReason",
+  Stage: "stage",
+  Compression: "compression",
+  Encryption: "encryption",
+  EncryptionKeyID: "encryptionKeyID",
+  IsBot: "isBot",
+  BotName: "botName",
+  BotURL: "botURL",
+  BotIsInvalidBrowser: "botIsInvalidBrowser",
+  BotAction: "botAction",
+  PartitionID: "partitionID",
+  Signature: "signature",
+  SignatureKeyID: "signatureKeyID",
+  RetryAttempt: "retryAttempt",
+  RetryLastError: "retryLastError",
+  ReplayJobID: "replayJobID",
+  ReplayOriginalReceivedAt: "replayOriginalReceivedAt",
+  TransformerVersion: "transformerVersion",
+  SchemaVersion: "schemaVersion",
+} as const;
+export type MessagePropertiesMapKey = (typeof MessagePropertiesMapKey)[keyof typeof MessagePropertiesMapKey];